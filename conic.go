@@ -0,0 +1,146 @@
+package geo
+
+import (
+	"math"
+)
+
+// LambertConformalConic is the ellipsoidal Lambert Conformal Conic projection with two standard parallels Lat1 and Lat2, which are equal for the one standard parallel variant. Lon0 and Lat0 are the longitude and latitude of origin, and X0 and Y0 are the false easting and northing.
+type LambertConformalConic struct {
+	Ellipsoid
+	Lon0, Lat0 float64
+	Lat1, Lat2 float64
+	X0, Y0     float64
+
+	e, n, f, rho0 float64
+}
+
+// NewLambertConformalConic returns a Lambert Conformal Conic projection with origin (lon0,lat0) and standard parallels lat1 and lat2.
+func NewLambertConformalConic(ellipsoid Ellipsoid, lon0, lat0, lat1, lat2 float64) *LambertConformalConic {
+	p := &LambertConformalConic{
+		Ellipsoid: ellipsoid,
+		Lon0:      lon0,
+		Lat0:      lat0,
+		Lat1:      lat1,
+		Lat2:      lat2,
+	}
+	p.e = ellipsoid.E()
+	e2 := ellipsoid.E2()
+	phi1, phi2 := radians(lat1), radians(lat2)
+	m1, t1 := scaleM(phi1, e2), conformalT(phi1, p.e)
+	if lat1 == lat2 {
+		p.n = math.Sin(phi1)
+	} else {
+		m2, t2 := scaleM(phi2, e2), conformalT(phi2, p.e)
+		p.n = (math.Log(m1) - math.Log(m2)) / (math.Log(t1) - math.Log(t2))
+	}
+	p.f = m1 / (p.n * math.Pow(t1, p.n))
+	p.rho0 = p.rho(radians(lat0))
+	return p
+}
+
+func (p *LambertConformalConic) rho(lat float64) float64 {
+	if math.Abs(lat) == math.Pi/2.0 && 0.0 < lat*p.n {
+		return 0.0
+	}
+	return p.A * p.f * math.Pow(conformalT(lat, p.e), p.n)
+}
+
+func (p *LambertConformalConic) Forward(lon, lat float64) (float64, float64) {
+	rho := p.rho(radians(lat))
+	theta := p.n * normalizeLongitude(radians(lon-p.Lon0))
+	return p.X0 + rho*math.Sin(theta), p.Y0 + p.rho0 - rho*math.Cos(theta)
+}
+
+func (p *LambertConformalConic) Inverse(x, y float64) (float64, float64) {
+	x, y = x-p.X0, p.rho0-(y-p.Y0)
+	rho := math.Copysign(math.Hypot(x, y), p.n)
+	if p.n < 0.0 {
+		x, y = -x, -y
+	}
+	theta := math.Atan2(x, y)
+	lat := math.Copysign(math.Pi/2.0, p.n)
+	if rho != 0.0 {
+		lat = conformalLatitude(math.Pow(rho/(p.A*p.f), 1.0/p.n), p.e)
+	}
+	return p.Lon0 + degrees(theta/p.n), degrees(lat)
+}
+
+// AlbersEqualArea is the ellipsoidal Albers Equal Area Conic projection with two standard parallels Lat1 and Lat2. Lon0 and Lat0 are the longitude and latitude of origin, and X0 and Y0 are the false easting and northing.
+type AlbersEqualArea struct {
+	Ellipsoid
+	Lon0, Lat0 float64
+	Lat1, Lat2 float64
+	X0, Y0     float64
+
+	e, e2, n, c, rho0 float64
+}
+
+// NewAlbersEqualArea returns an Albers Equal Area projection with origin (lon0,lat0) and standard parallels lat1 and lat2.
+func NewAlbersEqualArea(ellipsoid Ellipsoid, lon0, lat0, lat1, lat2 float64) *AlbersEqualArea {
+	p := &AlbersEqualArea{
+		Ellipsoid: ellipsoid,
+		Lon0:      lon0,
+		Lat0:      lat0,
+		Lat1:      lat1,
+		Lat2:      lat2,
+	}
+	p.e, p.e2 = ellipsoid.E(), ellipsoid.E2()
+	phi1, phi2 := radians(lat1), radians(lat2)
+	m1, q1 := scaleM(phi1, p.e2), p.q(phi1)
+	if lat1 == lat2 {
+		p.n = math.Sin(phi1)
+	} else {
+		m2, q2 := scaleM(phi2, p.e2), p.q(phi2)
+		p.n = (m1*m1 - m2*m2) / (q2 - q1)
+	}
+	p.c = m1*m1 + p.n*q1
+	p.rho0 = p.rho(radians(lat0))
+	return p
+}
+
+// q returns Snyder's q function of the latitude, which is proportional to the area between the equator and the latitude.
+func (p *AlbersEqualArea) q(lat float64) float64 {
+	sin := math.Sin(lat)
+	if p.e == 0.0 {
+		return 2.0 * sin
+	}
+	es := p.e * sin
+	return (1.0 - p.e2) * (sin/(1.0-es*es) - math.Log((1.0-es)/(1.0+es))/(2.0*p.e))
+}
+
+func (p *AlbersEqualArea) rho(lat float64) float64 {
+	return p.A * math.Sqrt(math.Max(0.0, p.c-p.n*p.q(lat))) / p.n
+}
+
+func (p *AlbersEqualArea) Forward(lon, lat float64) (float64, float64) {
+	rho := p.rho(radians(lat))
+	theta := p.n * normalizeLongitude(radians(lon-p.Lon0))
+	return p.X0 + rho*math.Sin(theta), p.Y0 + p.rho0 - rho*math.Cos(theta)
+}
+
+func (p *AlbersEqualArea) Inverse(x, y float64) (float64, float64) {
+	x, y = x-p.X0, p.rho0-(y-p.Y0)
+	rho := math.Hypot(x, y)
+	if p.n < 0.0 {
+		x, y = -x, -y
+	}
+	theta := math.Atan2(x, y)
+	q := (p.c - rho*rho*p.n*p.n/(p.A*p.A)) / p.n
+
+	// iterate for the latitude, unless at the poles
+	lat := math.Asin(math.Max(-1.0, math.Min(q/2.0, 1.0)))
+	if qPole := p.q(math.Pi / 2.0); p.e != 0.0 && math.Abs(q) < qPole-1e-12 {
+		for i := 0; i < 15; i++ {
+			sin := math.Sin(lat)
+			es := p.e * sin
+			d := (1.0 - es*es) * (1.0 - es*es) / (2.0 * math.Cos(lat)) * (q/(1.0-p.e2) - sin/(1.0-es*es) + math.Log((1.0-es)/(1.0+es))/(2.0*p.e))
+			lat += d
+			if math.Abs(d) < 1e-14 {
+				break
+			}
+		}
+	} else if p.e != 0.0 {
+		lat = math.Copysign(math.Pi/2.0, q)
+	}
+	return p.Lon0 + degrees(theta/p.n), degrees(lat)
+}
@@ -29,8 +29,8 @@ const (
 )
 
 var Bounds = osm.Bounds{
-	{6.5651050153515484, 53.16260493850089},
-	{6.574056630521028, 53.1677857404529},
+	{X: 6.5651050153515484, Y: 53.16260493850089},
+	{X: 6.574056630521028, Y: 53.1677857404529},
 }
 
 func progress(ctx context.Context, z *osm.Parser, total int64) {
@@ -62,16 +62,36 @@ func boundsPath(bounds osm.Bounds, projector geo.Projector) *canvas.Path {
 	return p
 }
 
-func polygonPath(polygons []osm.Polygon, projector geo.Projector) *canvas.Path {
+func ringPath(p *canvas.Path, coords []osm.Coord, projector geo.Projector) {
+	x, y := projector(coords[0].X, coords[0].Y)
+	p.MoveTo(x, y)
+	for _, coord := range coords[1:] {
+		x, y := projector(coord.X, coord.Y)
+		p.LineTo(x, y)
+	}
+	p.Close()
+}
+
+func polygonPath(polygons []osm.PolygonWithHoles, projector geo.Projector) *canvas.Path {
 	p := &canvas.Path{}
 	for _, polygon := range polygons {
-		x, y := projector(polygon.Coords[0].X, polygon.Coords[0].Y)
+		ringPath(p, polygon.Outer, projector)
+		for _, hole := range polygon.Holes {
+			ringPath(p, hole, projector)
+		}
+	}
+	return p
+}
+
+func lineStringsPath(lineStrings [][]osm.Coord, projector geo.Projector) *canvas.Path {
+	p := &canvas.Path{}
+	for _, coords := range lineStrings {
+		x, y := projector(coords[0].X, coords[0].Y)
 		p.MoveTo(x, y)
-		for _, coord := range polygon.Coords[1:] {
+		for _, coord := range coords[1:] {
 			x, y := projector(coord.X, coord.Y)
 			p.LineTo(x, y)
 		}
-		p.Close()
 	}
 	return p
 }
@@ -138,8 +158,6 @@ func main() {
 			return Wetland
 		} else if tags.Find("natural") == "beach" {
 			return Beach
-		} else if tags.Find("type") == "coastline" {
-			return Land
 		}
 		return 0
 	}
@@ -147,6 +165,27 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	coastline, err := z.ExtractCoastline(ctx0, Bounds.ExpandByFactor(margin), 0.001)
+	if err != nil {
+		panic(err)
+	}
+	for _, problem := range coastline.Problems {
+		fmt.Println("WARNING:", problem)
+	}
+
+	// contour lines every meter if SRTM or GeoTIFF elevation tiles are available
+	var contours []osm.Geometry
+	if dem, err := osm.OpenDEM("dem"); err == nil {
+		grid, err := dem.Grid(Bounds.ExpandByFactor(margin), 1.0/3600.0)
+		if err != nil {
+			panic(err)
+		}
+		contours = grid.Contours(&osm.ContourOptions{
+			Interval: 1.0,
+			Smooth:   2,
+			Region:   Bounds.ExpandByFactor(margin),
+		})
+	}
 	fmt.Println("Time:", time.Since(t))
 
 	proj := geo.TransverseMercatorLambert(Bounds.Centre().X, 0.9996)
@@ -179,6 +218,9 @@ func main() {
 	ctx.SetStrokeColor(canvas.Transparent)
 	ctx.DrawPath(0.0, 0.0, boundsPath(Bounds.ExpandByFactor(margin), projector))
 
+	ctx.SetFillColor(colors[Water])
+	ctx.DrawPath(0.0, 0.0, polygonPath(coastline.Water, projector))
+
 	classes := []osm.Class{Water, Residential, Wetland, Forest, Grass, Beach}
 	for _, class := range classes {
 		color := colors[class]
@@ -192,6 +234,12 @@ func main() {
 	}
 
 	ctx.SetFillColor(canvas.Transparent)
+	ctx.SetStrokeColor(canvas.Hex("a0522d"))
+	ctx.SetStrokeWidth(0.5)
+	for _, geom := range contours {
+		ctx.DrawPath(0.0, 0.0, lineStringsPath(geom.LineStrings, projector))
+	}
+
 	ctx.SetStrokeColor(canvas.Red)
 	ctx.SetStrokeWidth(1.5)
 	ctx.SetDashes(0.0, canvas.Dashed...)
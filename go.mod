@@ -18,6 +18,7 @@ require (
 	github.com/tdewolff/test v1.0.11
 	github.com/thomersch/gosmparse v1.1.0
 	google.golang.org/protobuf v1.36.10
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
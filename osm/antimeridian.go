@@ -0,0 +1,127 @@
+package osm
+
+import (
+	"math"
+)
+
+// wrapLongitude returns the longitude within [-180,180].
+func wrapLongitude(x float64) float64 {
+	if x < -180.0 || 180.0 < x {
+		x = math.Mod(x+180.0, 360.0)
+		if x < 0.0 {
+			x += 360.0
+		}
+		x -= 180.0
+	}
+	return x
+}
+
+// crossesAntimeridian returns true if a segment crosses the antimeridian, which is when the longitude of consecutive coordinates differs by more than 180 degrees.
+func crossesAntimeridian(coords []Coord) bool {
+	for i := 1; i < len(coords); i++ {
+		if dx := coords[i].X - coords[i-1].X; dx < -180.0 || 180.0 < dx {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLineStringAntimeridian splits a line string where it crosses the antimeridian, so that each part has longitudes within [-180,180] without jumps.
+func splitLineStringAntimeridian(coords []Coord) [][]Coord {
+	if !crossesAntimeridian(coords) {
+		return [][]Coord{coords}
+	}
+
+	var lines [][]Coord
+	line := []Coord{coords[0]}
+	for i := 1; i < len(coords); i++ {
+		a, b := coords[i-1], coords[i]
+		if dx := b.X - a.X; dx < -180.0 || 180.0 < dx {
+			edge, bx := 180.0, b.X+360.0 // eastwards
+			if 0.0 < dx {
+				edge, bx = -180.0, b.X-360.0 // westwards
+			}
+			y := a.Y + (edge-a.X)/(bx-a.X)*(b.Y-a.Y)
+			if a.X != edge {
+				line = append(line, Coord{edge, y})
+			}
+			if 1 < len(line) {
+				lines = append(lines, line)
+			}
+			line = []Coord{}
+			if b.X != -edge {
+				line = append(line, Coord{-edge, y})
+			}
+		}
+		line = append(line, b)
+	}
+	if 1 < len(line) {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitRingAntimeridian splits a closed ring where it crosses the antimeridian into closed rings with longitudes within [-180,180], keeping the orientation. A ring that crosses the antimeridian an odd number of times encloses a pole, and is closed along the north pole if it runs eastwards and along the south pole otherwise.
+func splitRingAntimeridian(ring []Coord) [][]Coord {
+	if !crossesAntimeridian(ring) {
+		return [][]Coord{ring}
+	}
+
+	// make longitudes continuous, remembering the original coordinates to restore them exactly
+	coords := make([]Coord, len(ring))
+	original := map[Coord]Coord{}
+	offset := 0.0
+	coords[0] = ring[0]
+	for i := 1; i < len(ring); i++ {
+		if dx := ring[i].X - ring[i-1].X; dx < -180.0 {
+			offset += 360.0
+		} else if 180.0 < dx {
+			offset -= 360.0
+		}
+		coords[i] = Coord{ring[i].X + offset, ring[i].Y}
+		if offset != 0.0 {
+			original[coords[i]] = ring[i]
+		}
+	}
+	if first, last := coords[0], coords[len(coords)-1]; first.X != last.X {
+		pole := 90.0
+		if last.X < first.X {
+			pole = -90.0
+		}
+		coords = append(coords, Coord{last.X, pole}, Coord{first.X, pole}, first)
+	}
+
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for _, c := range coords {
+		minX = math.Min(minX, c.X)
+		maxX = math.Max(maxX, c.X)
+	}
+
+	// clip to each copy of the world and shift back
+	var rings [][]Coord
+	for k := math.Floor((minX + 180.0) / 360.0); 360.0*k-180.0 < maxX; k++ {
+		offset := 360.0 * k
+		strip := Bounds{{offset - 180.0, math.Inf(-1)}, {offset + 180.0, math.Inf(1)}}
+		clipped := closeRing(clipBounds(strip, coords))
+		if len(clipped) < 4 || ringArea(clipped) == 0.0 {
+			continue
+		}
+		for i, c := range clipped {
+			if orig, ok := original[c]; ok {
+				clipped[i] = orig
+			} else {
+				clipped[i].X -= offset
+			}
+		}
+		rings = append(rings, clipped)
+	}
+	return rings
+}
+
+// wrappedParts returns the two parts of wrapped bounds on either side of the antimeridian.
+func (b Bounds) wrappedParts() [2]Bounds {
+	return [2]Bounds{
+		{{b[0].X, b[0].Y}, {180.0, b[1].Y}},
+		{{-180.0, b[0].Y}, {b[1].X, b[1].Y}},
+	}
+}
@@ -0,0 +1,122 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestWrappedBounds(t *testing.T) {
+	b := Bounds{{170.0, -20.0}, {-170.0, -10.0}}
+	if !b.IsWrapped() || b.W() != 20.0 {
+		t.Errorf("expected wrapped bounds of width 20, got %v", b.W())
+	}
+	if c := b.Centre(); c != (Coord{180.0, -15.0}) {
+		t.Errorf("wrong centre %v", c)
+	}
+	for _, c := range []Coord{{175.0, -15.0}, {-175.0, -15.0}, {180.0, -15.0}} {
+		if !b.Contains(c) {
+			t.Errorf("expected %v to be contained", c)
+		}
+	}
+	for _, c := range []Coord{{0.0, -15.0}, {175.0, 0.0}} {
+		if b.Contains(c) {
+			t.Errorf("expected %v not to be contained", c)
+		}
+	}
+	if e := b.Expand(5.0, 1.0); e != (Bounds{{165.0, -21.0}, {-165.0, -9.0}}) {
+		t.Errorf("wrong expanded bounds %v", e)
+	}
+	if e := b.Expand(200.0, 0.0); e != (Bounds{{-180.0, -20.0}, {180.0, -10.0}}) {
+		t.Errorf("wrong expanded bounds %v", e)
+	}
+	if p := b.Project(func(x, y float64) (float64, float64) { return x, y }); p != (Bounds{{170.0, -20.0}, {190.0, -10.0}}) {
+		t.Errorf("wrong projected bounds %v", p)
+	}
+}
+
+func TestSplitLineStringAntimeridian(t *testing.T) {
+	lines := splitLineStringAntimeridian([]Coord{{170.0, 0.0}, {-170.0, 10.0}, {-160.0, 10.0}})
+	expected := [][]Coord{{{170.0, 0.0}, {180.0, 5.0}}, {{-180.0, 5.0}, {-170.0, 10.0}, {-160.0, 10.0}}}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %v, got %v", expected, lines)
+	}
+}
+
+func TestSplitRingAntimeridian(t *testing.T) {
+	ring := []Coord{{170.0, 0.0}, {-170.0, 0.0}, {-170.0, 10.0}, {170.0, 10.0}, {170.0, 0.0}}
+	rings := splitRingAntimeridian(ring)
+	if len(rings) != 2 {
+		t.Fatalf("expected two rings, got %v", rings)
+	}
+	for _, ring := range rings {
+		if area := ringArea(ring); area != 100.0 {
+			t.Errorf("expected CCW ring with area 100, got %v: %v", area, ring)
+		}
+	}
+
+	// ring around the north pole
+	ring = []Coord{{0.0, 80.0}, {120.0, 80.0}, {-120.0, 80.0}, {0.0, 80.0}}
+	rings = splitRingAntimeridian(ring)
+	area := 0.0
+	for _, ring := range rings {
+		area += ringArea(ring)
+	}
+	if area != 360.0*10.0 {
+		t.Errorf("expected band around the pole, got %v", rings)
+	}
+}
+
+func TestExtractAntimeridian(t *testing.T) {
+	nodes := []Node{
+		{ID: 1, Lon: 175.0, Lat: -15.0},
+		{ID: 2, Lon: -175.0, Lat: -15.0},
+		{ID: 3, Lon: -175.0, Lat: -17.0},
+		{ID: 4, Lon: 175.0, Lat: -17.0},
+		{ID: 5, Lon: 0.0, Lat: -15.0},
+	}
+	ways := []Way{
+		{ID: 10, Refs: []uint64{1, 2}, Tags: Tags{{"highway", "primary"}}},
+		{ID: 11, Refs: []uint64{4, 3, 2, 1, 4}, Tags: Tags{{"landuse", "forest"}}},
+		{ID: 12, Refs: []uint64{1, 5}, Tags: Tags{{"highway", "primary"}}},
+	}
+	b := writeTestPBF(t, nodes, ways, nil)
+
+	z := NewParser(bytes.NewReader(b))
+	geometries, err := z.Extract(context.Background(), Bounds{{170.0, -20.0}, {-170.0, -10.0}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points, lines, areas := 0, map[uint64][][]Coord{}, map[uint64][]PolygonWithHoles{}
+	for _, geom := range geometries[0] {
+		points += len(geom.Points)
+		if 0 < len(geom.LineStrings) {
+			lines[geom.ID] = geom.LineStrings
+		}
+		if 0 < len(geom.Polygons) {
+			areas[geom.ID] = geom.Polygons
+		}
+		for _, line := range geom.LineStrings {
+			for i := 1; i < len(line); i++ {
+				if 180.0 < math.Abs(line[i].X-line[i-1].X) {
+					t.Errorf("line string %v crosses the antimeridian: %v", geom.ID, line)
+				}
+			}
+		}
+	}
+	if points != 4 {
+		t.Errorf("expected 4 points, got %v", points)
+	}
+	if len(lines[10]) != 2 {
+		t.Errorf("expected way 10 to be split in two, got %v", lines[10])
+	}
+	if len(lines[12]) != 1 || lines[12][0][len(lines[12][0])-1].X != 170.0 {
+		t.Errorf("expected way 12 to be clipped at 170, got %v", lines[12])
+	}
+	if len(areas[11]) != 2 || math.Abs(polygonsArea(areas[11])-20.0) > 1e-9 {
+		t.Errorf("expected way 11 to be split in two polygons, got %v", areas[11])
+	}
+}
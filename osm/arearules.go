@@ -0,0 +1,108 @@
+package osm
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AreaRule decides which values of a key make a closed way an area. If Values is set, only those values make it an area, otherwise all values except those in Except make it an area.
+type AreaRule struct {
+	Values []string `json:"values,omitempty" yaml:"values,omitempty"`
+	Except []string `json:"except,omitempty" yaml:"except,omitempty"`
+}
+
+// IsArea returns true if the value makes a closed way an area.
+func (rule AreaRule) IsArea(val string) bool {
+	if rule.Values != nil {
+		return slices.Contains(rule.Values, val)
+	}
+	return !slices.Contains(rule.Except, val)
+}
+
+// UnmarshalYAML decodes a rule either as a mapping with values and/or except lists, or as a mapping of values that are not areas as used by the areaKeys of iD, such as {"picnic_table": true}.
+func (rule *AreaRule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %v: area rule must be a mapping", node.Line)
+	}
+	lists := 0
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i].Value; (key == "values" || key == "except") && node.Content[i+1].Kind == yaml.SequenceNode {
+			lists++
+		}
+	}
+	if 0 < lists && lists == len(node.Content)/2 {
+		type plain AreaRule // avoid recursion
+		return node.Decode((*plain)(rule))
+	}
+
+	// iD format
+	rule.Values = nil
+	rule.Except = []string{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		rule.Except = append(rule.Except, node.Content[i].Value)
+	}
+	return nil
+}
+
+// AreaRules decides whether a closed way or relation is an area by its tags, using a rule per key. The tags area=yes and area=no always take precedence.
+type AreaRules map[string]AreaRule
+
+// DefaultAreaRules are the area rules used by default.
+var DefaultAreaRules = AreaRules{
+	"building":      {},
+	"building:part": {},
+	"landuse":       {},
+	"amenity":       {},
+	"shop":          {},
+	"boundary":      {},
+	"historic":      {},
+	"place":         {},
+	"area:highway":  {},
+	"waterway":      {Values: []string{"riverbank"}},
+	"highway":       {Values: []string{"rest_area", "services", "platform"}},
+	"railway":       {Values: []string{"platform"}},
+	"natural":       {Values: []string{"water", "wood", "scrub", "wetland", "grassland", "heath", "rock", "bare_rock", "sand", "beach", "scree", "bay", "glacier", "shingle", "fell", "reef", "stone", "mud", "landslide", "sinkhole", "crevasse", "desert", "coastline"}},
+	"leisure":       {Except: []string{"picnic_table", "slipway", "firepit"}},
+	"aeroway":       {Values: []string{"aerodrome"}},
+}
+
+// IsArea returns true if the way or relation is considered an enclosed area (in contrast to a open path).
+func (rules AreaRules) IsArea(tags Tags) bool {
+	area := false
+	for _, tag := range tags {
+		if tag.Key == "area" && tag.Val == "yes" {
+			return true
+		} else if tag.Key == "area" && tag.Val == "no" {
+			return false
+		} else if rule, ok := rules[tag.Key]; ok && rule.IsArea(tag.Val) {
+			area = true
+		}
+	}
+	return area
+}
+
+// ParseAreaRules parses area rules from JSON or YAML. Each key maps to a rule with either a values list of values that are areas, or an except list of values that are not areas. The areaKeys format of iD, where each key maps to an object with values that are not areas, is supported as well, optionally wrapped in an object with an areaKeys field.
+func ParseAreaRules(r io.Reader) (AreaRules, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	} else if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("area rules must be a mapping")
+	}
+
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "areaKeys" {
+			root = root.Content[i+1]
+			break
+		}
+	}
+	rules := AreaRules{}
+	if err := root.Decode(&rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,53 @@
+package osm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAreaRules(t *testing.T) {
+	tests := []struct {
+		tags Tags
+		area bool
+	}{
+		{Tags{{"building", "yes"}}, true},
+		{Tags{{"highway", "residential"}}, false},
+		{Tags{{"highway", "platform"}}, true},
+		{Tags{{"leisure", "park"}}, true},
+		{Tags{{"leisure", "slipway"}}, false},
+		{Tags{{"natural", "coastline"}}, true},
+		{Tags{{"natural", "tree_row"}}, false},
+		{Tags{{"building", "yes"}, {"area", "no"}}, false},
+		{Tags{{"area", "yes"}, {"highway", "pedestrian"}}, true},
+	}
+	for _, tt := range tests {
+		if area := tt.tags.IsArea(); area != tt.area {
+			t.Errorf("IsArea(%v) = %v, expected %v", tt.tags, area, tt.area)
+		}
+	}
+}
+
+func TestParseAreaRules(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"yaml", "building: {}\nnatural:\n  values: [water]\nleisure:\n  except: [track]\n"},
+		{"json", `{"building": {}, "natural": {"values": ["water"]}, "leisure": {"except": ["track"]}}`},
+		{"iD", `{"areaKeys": {"building": {}, "natural": {"coastline": true, "tree_row": true}, "leisure": {"track": true}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseAreaRules(strings.NewReader(tt.s))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !rules.IsArea(Tags{{"building", "house"}}) || !rules.IsArea(Tags{{"natural", "water"}}) || !rules.IsArea(Tags{{"leisure", "park"}}) {
+				t.Errorf("expected areas: %v", rules)
+			}
+			if rules.IsArea(Tags{{"leisure", "track"}}) || rules.IsArea(Tags{{"natural", "tree_row"}}) || rules.IsArea(Tags{{"highway", "primary"}}) {
+				t.Errorf("expected no areas: %v", rules)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package osm
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+)
+
+// Coastline is the result of processing the coastline within the bounds. Land and water polygons together cover the bounds without overlap.
+type Coastline struct {
+	Land     []PolygonWithHoles
+	Water    []PolygonWithHoles
+	Problems []Problem
+}
+
+// ExtractCoastline extracts all natural=coastline ways, joins them into rings, and returns land and water polygons within the bounds, similar to osmcoastline. This function requires parsing the file twice.
+// - Coastlines have land on their left side and water on their right side. Ways are joined from end to start, ways that are reversed with respect to their neighbours are reversed and reported. Closed rings that are CW oriented are reversed and reported as well.
+// - Gaps between the end of one coastline and the start of another (or the same) coastline of at most maxGap degrees, measured as the planar distance in longitude and latitude, are closed by a straight line and reported. Coastlines that remain open with an end within the bounds are reported as unclosed rings and are skipped.
+// - Coastlines that leave the bounds are closed along the bounds, so that the land and water polygons are clipped to the bounds. If no coastline passes through the bounds, the bounds are either land or water depending on the nearest coastline, or land if there are no coastlines at all.
+// - Land and water polygons are CCW oriented and holes are CW oriented. Islands are returned as land polygons and as holes in the water polygons.
+func (z *Parser) ExtractCoastline(ctx context.Context, bounds Bounds, maxGap float64) (Coastline, error) {
+	var mu sync.Mutex
+	var coastlines []Way
+	refs := NewUint64Set(8, 0.6)
+	wayFunc := func(way Way) {
+		if 1 < len(way.Refs) && way.Tags.Find("natural") == "coastline" {
+			way.Own()
+			way.Tags = nil
+			mu.Lock()
+			coastlines = append(coastlines, way)
+			for _, ref := range way.Refs {
+				refs.Add(ref)
+			}
+			mu.Unlock()
+		}
+	}
+	if err := z.Parse(ctx, nil, wayFunc, nil); err != nil {
+		return Coastline{}, err
+	}
+
+	nodes := map[uint64]Coord{}
+	nodeFunc := func(node Node) {
+		mu.Lock()
+		if refs.Has(node.ID) {
+			nodes[node.ID] = Coord{node.Lon, node.Lat}
+		}
+		mu.Unlock()
+	}
+	if err := z.Parse(ctx, nodeFunc, nil, nil); err != nil {
+		return Coastline{}, err
+	}
+	refs = nil
+
+	ways := make([]memberWay, 0, len(coastlines))
+	for _, way := range coastlines {
+		coords := make([]Coord, 0, len(way.Refs))
+		for _, ref := range way.Refs {
+			if coord, ok := nodes[ref]; ok {
+				// node exists
+				coords = append(coords, coord)
+			}
+		}
+		if 1 < len(coords) {
+			ways = append(ways, memberWay{relationWay: relationWay{
+				ID:     way.ID,
+				Coords: coords,
+				First:  way.Refs[0],
+				Last:   way.Refs[len(way.Refs)-1],
+			}})
+		}
+	}
+	return processCoastline(bounds, ways, maxGap), nil
+}
+
+// processCoastline joins coastline ways into rings, fixes reversed coastlines and gaps, and returns the land and water polygons within the bounds.
+func processCoastline(bounds Bounds, ways []memberWay, maxGap float64) Coastline {
+	var problems []Problem
+	chains := make([]wayChain, 0, len(ways))
+	for i, way := range ways {
+		chains = append(chains, wayChain{
+			Coords: way.Coords[:len(way.Coords):len(way.Coords)],
+			First:  way.First,
+			Last:   way.Last,
+			Ways:   []int{i},
+		})
+	}
+	chains = joinCoastlines(chains)
+
+	// fix open coastlines that are reversed with respect to their neighbours
+	for range chains {
+		i, node := reversedCoastline(chains)
+		if i == -1 {
+			break
+		}
+		coord := chains[i].Coords[0]
+		if chains[i].Last == node {
+			coord = chains[i].Coords[len(chains[i].Coords)-1]
+		}
+		for _, w := range chains[i].Ways {
+			problems = append(problems, Problem{Type: ReversedCoastline, ObjectType: WayType, ID: ways[w].ID, Coord: coord})
+		}
+		chains[i].reverse()
+		chains = joinCoastlines(chains)
+	}
+
+	// close gaps between open coastlines
+	var gaps []Problem
+	chains, gaps = closeCoastlineGaps(chains, ways, maxGap)
+	problems = append(problems, gaps...)
+
+	// fix closed coastlines that are reversed
+	for i, chain := range chains {
+		if chain.closed() && !isCCW(chain.Coords) {
+			problems = append(problems, Problem{Type: ReversedCoastline, ObjectType: WayType, ID: ways[chain.Ways[0]].ID, Coord: chain.Coords[0]})
+			chains[i].reverse()
+		}
+	}
+
+	// find parts of the coastline within the bounds
+	rect := NewPolygonRegion([][]Coord{{bounds[0], {bounds[1].X, bounds[0].Y}, bounds[1], {bounds[0].X, bounds[1].Y}}}, nil)
+	var lines, islands [][]Coord
+	for _, chain := range chains {
+		if chain.closed() {
+			if len(chain.Coords) < 4 {
+				continue
+			} else if len(rect.crossings(chain.Coords)) == 0 {
+				if rect.Contains(chain.Coords[0]) {
+					islands = append(islands, chain.Coords)
+				}
+			} else {
+				lines = append(lines, rect.clipRingLineStrings(chain.Coords)...)
+			}
+			continue
+		}
+
+		first, last := chain.Coords[0], chain.Coords[len(chain.Coords)-1]
+		if cohenSutherlandOutcode(bounds, first) == 0b0000 || cohenSutherlandOutcode(bounds, last) == 0b0000 {
+			// open coastline that ends within the bounds, it cannot be closed
+			if cohenSutherlandOutcode(bounds, first) == 0b0000 {
+				problems = append(problems, Problem{Type: UnclosedRing, ObjectType: WayType, ID: ways[chain.Ways[0]].ID, Coord: first})
+			}
+			if cohenSutherlandOutcode(bounds, last) == 0b0000 {
+				problems = append(problems, Problem{Type: UnclosedRing, ObjectType: WayType, ID: ways[chain.Ways[len(chain.Ways)-1]].ID, Coord: last})
+			}
+			continue
+		}
+		lines = append(lines, rect.clipLineString(chain.Coords)...)
+	}
+
+	// close coastlines along the bounds, land is on the left and water is on the right of coastlines
+	var land, water [][]Coord
+	if len(lines) == 0 {
+		if coastlineIsLand(chains, bounds[0]) {
+			land = append(land, rect.Rings[0])
+		} else {
+			water = append(water, rect.Rings[0])
+		}
+	} else {
+		land = rect.closeLineStrings(lines)
+		for i := range lines {
+			lines[i] = reverseOrientation(slices.Clone(lines[i]))
+		}
+		water = rect.closeLineStrings(lines)
+	}
+	for _, island := range islands {
+		land = append(land, island)
+		water = append(water, reverseOrientation(slices.Clone(island)))
+	}
+
+	coastline := Coastline{Problems: problems}
+	coastline.Land, _ = nestRings(land)
+	coastline.Water, _ = nestRings(water)
+	return coastline
+}
+
+// joinCoastlines connects chains from end to start, without reversing chains. Chains that start at the same node as another chain are not joined.
+func joinCoastlines(chains []wayChain) []wayChain {
+	starts := map[uint64]int{} // open chains by first node ID
+	for i, c := range chains {
+		if _, ok := starts[c.First]; !ok && !c.closed() {
+			starts[c.First] = i
+		}
+	}
+	for i := range chains {
+		c := &chains[i]
+		if c.Coords == nil {
+			continue
+		}
+		for !c.closed() {
+			j, ok := starts[c.Last]
+			if !ok {
+				break
+			}
+			d := chains[j]
+			delete(starts, d.First)
+			c.Coords = joinCoords(c.Coords, d.Coords)
+			c.Last = d.Last
+			c.Ways = append(c.Ways, d.Ways...)
+			chains[j].Coords = nil // mark as consumed
+		}
+		if c.closed() && starts[c.First] == i {
+			delete(starts, c.First)
+		}
+	}
+
+	// remove consumed chains
+	j := 0
+	for _, c := range chains {
+		if c.Coords != nil {
+			chains[j] = c
+			j++
+		}
+	}
+	return chains[:j]
+}
+
+// reversedCoastline returns the index of an open chain that is reversed with respect to another chain, which is when both chains end or start at the same node. It prefers chains that conflict at both ends, and then chains with fewer ways. It returns -1 if there are no reversed chains.
+func reversedCoastline(chains []wayChain) (int, uint64) {
+	firsts, lasts := map[uint64]int{}, map[uint64]int{}
+	for _, c := range chains {
+		if !c.closed() {
+			firsts[c.First]++
+			lasts[c.Last]++
+		}
+	}
+	conflicts := func(c wayChain) int {
+		n := 0
+		if 1 < firsts[c.First] {
+			n++
+		}
+		if 1 < lasts[c.Last] {
+			n++
+		}
+		return n
+	}
+
+	best, node := -1, uint64(0)
+	for i, c := range chains {
+		if c.closed() || conflicts(c) == 0 {
+			continue
+		} else if best == -1 || conflicts(chains[best]) < conflicts(c) || conflicts(chains[best]) == conflicts(c) && len(c.Ways) < len(chains[best].Ways) {
+			best, node = i, c.First
+			if 1 < lasts[c.Last] {
+				node = c.Last
+			}
+		}
+	}
+	return best, node
+}
+
+// closeCoastlineGaps joins the ends of open chains to the nearest starts of open chains within a distance of maxGap, shortest gaps first. It returns the new chains and the gaps that were closed.
+func closeCoastlineGaps(chains []wayChain, ways []memberWay, maxGap float64) ([]wayChain, []Problem) {
+	var open []int
+	for i, c := range chains {
+		if !c.closed() {
+			open = append(open, i)
+		}
+	}
+
+	type gap struct {
+		i, j int
+		dist float64
+	}
+	var gaps []gap
+	for _, i := range open {
+		end := chains[i].Coords[len(chains[i].Coords)-1]
+		for _, j := range open {
+			start := chains[j].Coords[0]
+			if dist := math.Hypot(start.X-end.X, start.Y-end.Y); dist <= maxGap {
+				gaps = append(gaps, gap{i, j, dist})
+			}
+		}
+	}
+	if len(gaps) == 0 {
+		return chains, nil
+	}
+	slices.SortStableFunc(gaps, func(a, b gap) int {
+		if a.dist < b.dist {
+			return -1
+		} else if b.dist < a.dist {
+			return 1
+		}
+		return 0
+	})
+
+	next, prev := map[int]int{}, map[int]int{}
+	for _, g := range gaps {
+		if _, ok := next[g.i]; ok {
+			continue
+		} else if _, ok := prev[g.j]; ok {
+			continue
+		}
+		next[g.i] = g.j
+		prev[g.j] = g.i
+	}
+
+	var problems []Problem
+	joined := make([]wayChain, 0, len(chains))
+	visited := make([]bool, len(chains))
+	follow := func(i int) wayChain {
+		c := chains[i]
+		c.Coords = slices.Clone(c.Coords)
+		c.Ways = slices.Clone(c.Ways)
+		visited[i] = true
+		for {
+			j, ok := next[i]
+			if !ok {
+				break
+			}
+			end := c.Coords[len(c.Coords)-1]
+			problems = append(problems, Problem{Type: CoastlineGap, ObjectType: WayType, ID: ways[c.Ways[len(c.Ways)-1]].ID, Coord: end})
+			if visited[j] {
+				// closed ring
+				c.Coords = append(c.Coords, c.Coords[0])
+				c.Last = c.First
+				break
+			}
+			d := chains[j]
+			visited[j] = true
+			c.Coords = joinCoords(c.Coords, d.Coords)
+			c.Last = d.Last
+			c.Ways = append(c.Ways, d.Ways...)
+			i = j
+		}
+		return c
+	}
+	for i, c := range chains {
+		if c.closed() {
+			joined = append(joined, c)
+			visited[i] = true
+		}
+	}
+	for _, i := range open {
+		if _, ok := prev[i]; !ok {
+			joined = append(joined, follow(i))
+		}
+	}
+	for _, i := range open {
+		if !visited[i] {
+			// part of a ring of chains
+			joined = append(joined, follow(i))
+		}
+	}
+	return joined, problems
+}
+
+// coastlineIsLand returns true if the coordinate is on the land side of the nearest coastline segment, or if there are no coastlines.
+func coastlineIsLand(chains []wayChain, c Coord) bool {
+	land, dist := true, math.Inf(1)
+	for _, chain := range chains {
+		for i := 1; i < len(chain.Coords); i++ {
+			a, b := chain.Coords[i-1], chain.Coords[i]
+			ab, ac := b.Sub(a), c.Sub(a)
+			t := 0.0
+			if l := ab.X*ab.X + ab.Y*ab.Y; l != 0.0 {
+				t = math.Max(0.0, math.Min(1.0, (ac.X*ab.X+ac.Y*ab.Y)/l))
+			}
+			if d := math.Hypot(ac.X-t*ab.X, ac.Y-t*ab.Y); d < dist {
+				land, dist = 0.0 <= ab.X*ac.Y-ab.Y*ac.X, d
+			}
+		}
+	}
+	return land
+}
@@ -0,0 +1,66 @@
+package osm
+
+import (
+	"math"
+	"testing"
+)
+
+func polygonsArea(polygons []PolygonWithHoles) float64 {
+	area := 0.0
+	for _, polygon := range polygons {
+		area += ringArea(polygon.Outer)
+		for _, hole := range polygon.Holes {
+			area += ringArea(hole)
+		}
+	}
+	return area
+}
+
+func TestProcessCoastline(t *testing.T) {
+	bounds := Bounds{{0.0, 0.0}, {10.0, 10.0}}
+	ways := []memberWay{
+		// coastline from west to east with land to the north, the second way is reversed
+		testWay(1, "", 1, 2, Coord{-5, 5}, Coord{3, 5}),
+		testWay(2, "", 3, 2, Coord{6, 5}, Coord{3, 5}),
+		testWay(3, "", 3, 4, Coord{6, 5}, Coord{15, 5}),
+		// island
+		testWay(4, "", 5, 5, Coord{2, 1}, Coord{4, 1}, Coord{4, 3}, Coord{2, 3}, Coord{2, 1}),
+	}
+	coastline := processCoastline(bounds, ways, 0.0)
+	if area := polygonsArea(coastline.Land); math.Abs(area-54.0) > 1e-9 {
+		t.Errorf("expected land area 54, got %v: %v", area, coastline.Land)
+	}
+	if area := polygonsArea(coastline.Water); math.Abs(area-46.0) > 1e-9 {
+		t.Errorf("expected water area 46, got %v: %v", area, coastline.Water)
+	}
+	if len(coastline.Water) != 1 || len(coastline.Water[0].Holes) != 1 {
+		t.Errorf("expected water polygon with one hole: %v", coastline.Water)
+	}
+	if len(coastline.Problems) != 1 || coastline.Problems[0].Type != ReversedCoastline || coastline.Problems[0].ID != 2 {
+		t.Errorf("expected reversed coastline: %v", coastline.Problems)
+	}
+}
+
+func TestProcessCoastlineGap(t *testing.T) {
+	bounds := Bounds{{0.0, 0.0}, {10.0, 10.0}}
+	ways := []memberWay{
+		testWay(1, "", 1, 2, Coord{-5, 5}, Coord{4, 5}),
+		testWay(2, "", 3, 4, Coord{4.5, 5}, Coord{15, 5}),
+	}
+	coastline := processCoastline(bounds, ways, 1.0)
+	if area := polygonsArea(coastline.Land); math.Abs(area-50.0) > 1e-9 {
+		t.Errorf("expected land area 50, got %v: %v", area, coastline.Land)
+	}
+	if len(coastline.Problems) != 1 || coastline.Problems[0].Type != CoastlineGap {
+		t.Errorf("expected coastline gap: %v", coastline.Problems)
+	}
+
+	// gap too large
+	coastline = processCoastline(bounds, ways, 0.1)
+	if len(coastline.Land) != 0 || len(coastline.Water) != 1 {
+		t.Errorf("expected only water: %v %v", coastline.Land, coastline.Water)
+	}
+	if len(coastline.Problems) != 2 || coastline.Problems[0].Type != UnclosedRing {
+		t.Errorf("expected unclosed rings: %v", coastline.Problems)
+	}
+}
@@ -0,0 +1,243 @@
+package osm
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// ContourOptions are options for Contours.
+type ContourOptions struct {
+	// Interval is the elevation in meters between contour lines, 10 meters if zero. Contour lines are at multiples of the interval.
+	Interval float64
+
+	// Levels are the elevations of the contour lines, which overrides Interval if not empty.
+	Levels []float64
+
+	// Smooth is the number of iterations of Chaikin's corner cutting to smooth the contour lines, where each iteration doubles the number of coordinates. Smoothing may cause contour lines of adjacent levels to touch in steep terrain.
+	Smooth int
+
+	// Region clips the contour lines if not nil.
+	Region Region
+}
+
+// Contours returns the contour lines of the tile using marching squares, with one geometry per elevation that has the line strings and the tags contour=elevation and ele=<meters> without a type or ID. Contour lines have higher ground on their right, and are open where they leave the tile or reach pixels without data. Levels without contour lines are omitted.
+func (t *DEMTile) Contours(opts *ContourOptions) []Geometry {
+	if opts == nil {
+		opts = &ContourOptions{}
+	}
+	values := make([]float64, len(t.Data))
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i, v := range t.Data {
+		values[i] = float64(v)
+		if !math.IsNaN(values[i]) {
+			lo, hi = min(lo, values[i]), max(hi, values[i])
+		}
+	}
+
+	levels := opts.Levels
+	if len(levels) == 0 && lo <= hi {
+		interval := opts.Interval
+		if interval <= 0.0 {
+			interval = 10.0
+		}
+		for k := math.Ceil(lo / interval); k*interval <= hi; k++ {
+			levels = append(levels, k*interval)
+		}
+	}
+
+	geoms := []Geometry{}
+	for _, level := range levels {
+		var lineStrings [][]Coord
+		for _, line := range isoLines(values, t.Width, t.Height, level) {
+			line = smoothLineString(line, opts.Smooth)
+			slices.Reverse(line) // rows increase southwards which mirrors the lines, so that the area below level would be on the right
+			for i, p := range line {
+				line[i] = Coord{t.Origin.X + p.X*t.Step.X, t.Origin.Y - p.Y*t.Step.Y}
+			}
+			if opts.Region != nil {
+				lineStrings = append(lineStrings, clipLineString(opts.Region.Bounds(), opts.Region, line)...)
+			} else {
+				lineStrings = append(lineStrings, line)
+			}
+		}
+		if 0 < len(lineStrings) {
+			geoms = append(geoms, Geometry{
+				LineStrings: lineStrings,
+				Tags:        Tags{{"contour", "elevation"}, {"ele", strconv.FormatFloat(level, 'f', -1, 64)}},
+			})
+		}
+	}
+	return geoms
+}
+
+// smoothLineString smooths a line string using Chaikin's corner cutting, which replaces each segment by two coordinates at a quarter and three quarters of its length. The end points of open line strings are kept, and closed line strings stay closed.
+func smoothLineString(coords []Coord, iterations int) []Coord {
+	for k := 0; k < iterations && 2 < len(coords); k++ {
+		closed := coords[0] == coords[len(coords)-1]
+		smooth := make([]Coord, 0, 2*len(coords))
+		if !closed {
+			smooth = append(smooth, coords[0])
+		}
+		for i := 1; i < len(coords); i++ {
+			a, b := coords[i-1], coords[i]
+			smooth = append(smooth, Coord{0.75*a.X + 0.25*b.X, 0.75*a.Y + 0.25*b.Y}, Coord{0.25*a.X + 0.75*b.X, 0.25*a.Y + 0.75*b.Y})
+		}
+		if closed {
+			smooth = append(smooth, smooth[0])
+		} else {
+			smooth = append(smooth, coords[len(coords)-1])
+		}
+		coords = smooth
+	}
+	return coords
+}
+
+// isoRings returns the closed rings around the grid points with a value below level using marching squares, where the grid has nx columns and ny rows with the value of column i and row j at values[j*nx+i]. Points outside the grid and NaN values are considered above level. The crossing of a contour between two grid points is interpolated linearly, or placed halfway if a value is infinite or NaN. Rings are in grid coordinates and have the area below level on their left, so that outer rings are CCW and holes are CW.
+func isoRings(values []float64, nx, ny int, level float64) [][]Coord {
+	rings := [][]Coord{}
+	for _, ring := range isoContours(values, nx, ny, level, true) {
+		if 4 <= len(ring) {
+			rings = append(rings, ring)
+		}
+	}
+	return rings
+}
+
+// isoLines returns the contour lines at level using marching squares, see isoRings. Unlike isoRings, contour lines end at the border of the grid and at cells with NaN values, so that they may be open. Closed lines have equal first and last coordinates. The area below level is on their left.
+func isoLines(values []float64, nx, ny int, level float64) [][]Coord {
+	lines := [][]Coord{}
+	for _, line := range isoContours(values, nx, ny, level, false) {
+		if 2 <= len(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// isoContours returns the contours at level using marching squares. If closed is set, the grid is padded with values above level so that all contours are closed, otherwise cells along the border and cells with NaN values are skipped.
+func isoContours(values []float64, nx, ny int, level float64, closed bool) [][]Coord {
+	value := func(i, j int) float64 {
+		if i < 0 || nx <= i || j < 0 || ny <= j {
+			return math.Inf(1)
+		}
+		return values[j*nx+i]
+	}
+
+	// crossings are identified by the edge between two grid points, including the padding around the grid
+	type crossing struct {
+		key   int
+		coord Coord
+	}
+	edgeCrossing := func(i0, j0, i1, j1 int) crossing {
+		v0, v1 := value(i0, j0), value(i1, j1)
+		t := 0.5
+		if d := v1 - v0; d != 0.0 && !math.IsNaN(d) && !math.IsInf(d, 0) {
+			t = math.Max(0.0, math.Min(1.0, (level-v0)/d))
+		}
+		key := 2 * ((min(j0, j1)+1)*(nx+2) + min(i0, i1) + 1)
+		if i0 == i1 {
+			key++ // vertical edge
+		}
+		return crossing{key, Coord{float64(i0) + t*float64(i1-i0), float64(j0) + t*float64(j1-j0)}}
+	}
+
+	first := -1
+	if !closed {
+		first = 0
+	}
+	next := map[int]int{}
+	coords := map[int]Coord{}
+	for j := first; j < ny-1-first; j++ {
+	cells:
+		for i := first; i < nx-1-first; i++ {
+			// corners in CCW order
+			corners := [4][2]int{{i, j}, {i + 1, j}, {i + 1, j + 1}, {i, j + 1}}
+			var inside [4]bool
+			n := 0
+			for k, corner := range corners {
+				v := value(corner[0], corner[1])
+				if !closed && math.IsNaN(v) {
+					continue cells
+				} else if inside[k] = v < level; inside[k] {
+					n++
+				}
+			}
+			if n == 0 || n == 4 {
+				continue
+			}
+
+			// crossings along the cell boundary in CCW order, where the contour exits the area below level at exits
+			var crossings []crossing
+			var exits []bool
+			for k := range corners {
+				a, b := corners[k], corners[(k+1)%4]
+				if inside[k] != inside[(k+1)%4] {
+					crossings = append(crossings, edgeCrossing(a[0], a[1], b[0], b[1]))
+					exits = append(exits, inside[k])
+				}
+			}
+
+			// for saddles, connect the areas below level if the centre is below level
+			step := 1
+			if len(crossings) == 4 {
+				centre := (value(i, j) + value(i+1, j) + value(i+1, j+1) + value(i, j+1)) / 4.0
+				if !(centre < level) {
+					step = 3
+				}
+			}
+			for k, c := range crossings {
+				if exits[k] {
+					entry := crossings[(k+step)%len(crossings)]
+					next[c.key] = entry.key
+					coords[c.key] = c.coord
+					coords[entry.key] = entry.coord
+				}
+			}
+		}
+	}
+
+	// link segments into lines, starting with the open lines that begin at crossings which are not the end of another segment
+	ends := map[int]bool{}
+	for _, key := range next {
+		ends[key] = true
+	}
+	starts := slices.Sorted(maps.Keys(next))
+	slices.SortStableFunc(starts, func(a, b int) int {
+		if !ends[a] && ends[b] {
+			return -1
+		} else if ends[a] && !ends[b] {
+			return 1
+		}
+		return 0
+	})
+
+	lines := [][]Coord{}
+	for _, start := range starts {
+		if _, ok := next[start]; !ok {
+			continue // part of a previous line
+		}
+		line := []Coord{}
+		isClosed := false
+		for key := start; ; {
+			if c := coords[key]; len(line) == 0 || line[len(line)-1] != c {
+				line = append(line, c)
+			}
+			k, ok := next[key]
+			if !ok {
+				break
+			}
+			delete(next, key)
+			if key = k; key == start {
+				isClosed = true
+				break
+			}
+		}
+		if isClosed && line[0] != line[len(line)-1] {
+			line = append(line, line[0])
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,79 @@
+package osm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestContours(t *testing.T) {
+	// slope rising eastwards with a void
+	tile := &DEMTile{
+		Origin: Coord{0.0, 2.0},
+		Step:   Coord{1.0, 1.0},
+		Width:  5,
+		Height: 3,
+		Data: []float32{
+			0, 10, 20, 30, 40,
+			0, 10, 20, 30, 40,
+			0, 10, 20, 30, float32(math.NaN()),
+		},
+	}
+	geoms := tile.Contours(&ContourOptions{Levels: []float64{-5.0, 15.0, 35.0}})
+	if len(geoms) != 2 || geoms[0].Tags.Find("ele") != "15" || geoms[0].Tags.Find("contour") != "elevation" || geoms[1].Tags.Find("ele") != "35" {
+		t.Fatalf("wrong contours %v", geoms)
+	}
+	if lines := geoms[0].LineStrings; len(lines) != 1 || len(lines[0]) != 3 || lines[0][0] != (Coord{1.5, 0.0}) || lines[0][2] != (Coord{1.5, 2.0}) {
+		t.Errorf("expected northward line with higher ground on the right, got %v", lines)
+	}
+	if lines := geoms[1].LineStrings; len(lines) != 1 || len(lines[0]) != 2 || lines[0][0] != (Coord{3.5, 1.0}) || lines[0][1] != (Coord{3.5, 2.0}) {
+		t.Errorf("expected line ending at the void, got %v", lines)
+	}
+	if geoms := tile.Contours(&ContourOptions{Interval: 15.0}); len(geoms) != 2 || geoms[0].Tags.Find("ele") != "15" || geoms[1].Tags.Find("ele") != "30" {
+		t.Errorf("wrong contours at interval %v", geoms)
+	}
+
+	geoms = tile.Contours(&ContourOptions{Levels: []float64{15.0}, Smooth: 2, Region: Bounds{{0.0, 0.5}, {5.0, 1.5}}})
+	if len(geoms) != 1 || len(geoms[0].LineStrings) != 1 {
+		t.Fatalf("wrong contours %v", geoms)
+	} else if line := geoms[0].LineStrings[0]; line[0].Y < 0.5 || line[len(line)-1].Y > 1.5 {
+		t.Errorf("expected clipped line, got %v", line)
+	}
+
+	// peak in the centre
+	tile = &DEMTile{
+		Origin: Coord{0.0, 2.0},
+		Step:   Coord{1.0, 1.0},
+		Width:  3,
+		Height: 3,
+		Data: []float32{
+			0, 0, 0,
+			0, 100, 0,
+			0, 0, 0,
+		},
+	}
+	geoms = tile.Contours(&ContourOptions{Interval: 50.0, Smooth: 1})
+	if len(geoms) != 1 || geoms[0].Tags.Find("ele") != "50" || len(geoms[0].LineStrings) != 1 {
+		t.Fatalf("wrong contours %v", geoms)
+	} else if ring := geoms[0].LineStrings[0]; len(ring) != 9 || ring[0] != ring[len(ring)-1] || 0.0 <= ringArea(ring) {
+		t.Errorf("expected clockwise ring around the peak, got %v", ring)
+	}
+
+	// combine tiles
+	grid, err := NewDEM(tile).Grid(Bounds{{0.0, 0.0}, {2.0, 2.0}}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	} else if grid.Width != 4 || grid.Height != 4 || grid.Origin != (Coord{0.25, 1.75}) || grid.Data[5] != 56.25 {
+		t.Errorf("wrong grid %v %vx%v %v", grid.Origin, grid.Width, grid.Height, grid.Data)
+	}
+}
+
+func TestSmoothLineString(t *testing.T) {
+	line := smoothLineString([]Coord{{0, 0}, {4, 0}, {4, 4}}, 1)
+	if len(line) != 6 || line[0] != (Coord{0, 0}) || line[1] != (Coord{1, 0}) || line[2] != (Coord{3, 0}) || line[3] != (Coord{4, 1}) || line[5] != (Coord{4, 4}) {
+		t.Errorf("wrong smoothed line %v", line)
+	}
+	ring := smoothLineString([]Coord{{0, 0}, {4, 0}, {4, 4}, {0, 0}}, 2)
+	if len(ring) != 13 || ring[0] != ring[len(ring)-1] {
+		t.Errorf("wrong smoothed ring %v", ring)
+	}
+}
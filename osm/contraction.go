@@ -0,0 +1,777 @@
+package osm
+
+import (
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+)
+
+const chMagic = "CHGR"
+const chVersion = 1
+
+// chWitnessLimit is the maximum number of vertices settled by a witness search during contraction.
+const chWitnessLimit = 500
+
+// chSimulateLimit is the maximum number of vertices settled by a witness search when estimating the priority of a vertex.
+const chSimulateLimit = 50
+
+// chArc is an arc of the contraction hierarchy, which is either edge a of the graph when b is -1, or a shortcut over the arcs a and b.
+type chArc struct {
+	from, to       uint32
+	weight, length float32
+	a, b           int32
+}
+
+// ContractionHierarchy answers shortest path queries on a routing graph by travel time. Vertices are contracted in order of importance, adding shortcuts that preserve shortest paths, so that queries only need to search towards more important vertices from both ends.
+type ContractionHierarchy struct {
+	graph *Graph
+	ranks []uint32
+	arcs  []chArc
+
+	upOffsets   []uint32 // arcs to higher ranked vertices by source vertex
+	up          []uint32
+	downOffsets []uint32 // arcs from higher ranked vertices by target vertex
+	down        []uint32
+}
+
+// ExtractContractionHierarchy builds a routing graph for the profile and preprocesses it into a contraction hierarchy, see ExtractGraph.
+func (z *Parser) ExtractContractionHierarchy(ctx context.Context, profile Profile) (*ContractionHierarchy, error) {
+	g, err := z.ExtractGraph(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	return NewContractionHierarchy(g), nil
+}
+
+// NewContractionHierarchy preprocesses the routing graph into a contraction hierarchy with travel time as weight. Vertices are contracted by the lazily updated priority of the edge difference and the number of contracted neighbours.
+func NewContractionHierarchy(g *Graph) *ContractionHierarchy {
+	n := g.NumVertices()
+	c := &chContractor{
+		out:        make([][]int32, n),
+		in:         make([][]int32, n),
+		contracted: make([]bool, n),
+		deleted:    make([]int, n),
+		dist:       make([]float64, n),
+		target:     make([]int, n),
+	}
+	for v := range c.dist {
+		c.dist[v] = math.Inf(1)
+	}
+	for v := 0; v < n; v++ {
+		start, end := g.Edges(v)
+		for e := start; e < end; e++ {
+			if to := g.Targets[e]; int(to) != v {
+				c.addArc(chArc{uint32(v), to, g.Times[e], g.Lengths[e], int32(e), -1})
+			}
+		}
+	}
+
+	queue := &chQueue{index: make([]int, n)}
+	for v := 0; v < n; v++ {
+		heap.Push(queue, chQueueItem{uint32(v), c.priority(uint32(v))})
+	}
+	ranks := make([]uint32, n)
+	stamps := make([]int, n)
+	for rank := 0; 0 < queue.Len(); {
+		item := heap.Pop(queue).(chQueueItem)
+		v := item.v
+		if priority := c.priority(v); 0 < queue.Len() && queue.items[0].priority < priority {
+			heap.Push(queue, chQueueItem{v, priority})
+			continue
+		}
+		c.contract(v, false)
+		c.contracted[v] = true
+		c.remove(v)
+		ranks[v] = uint32(rank)
+		rank++
+
+		stamps[v] = rank
+		for _, list := range [][]int32{c.in[v], c.out[v]} {
+			for _, i := range list {
+				u := c.arcs[i].from
+				if u == v {
+					u = c.arcs[i].to
+				}
+				if !c.contracted[u] && stamps[u] != rank {
+					stamps[u] = rank
+					c.deleted[u]++
+					queue.items[queue.index[u]].priority++
+					heap.Fix(queue, queue.index[u])
+				}
+			}
+		}
+	}
+
+	ch := &ContractionHierarchy{
+		graph: g,
+		ranks: ranks,
+		arcs:  c.arcs,
+	}
+	ch.index()
+	return ch
+}
+
+// index builds the upward and downward arcs.
+func (ch *ContractionHierarchy) index() {
+	n := ch.graph.NumVertices()
+	ch.upOffsets = make([]uint32, n+1)
+	ch.downOffsets = make([]uint32, n+1)
+	for _, arc := range ch.arcs {
+		if ch.ranks[arc.from] < ch.ranks[arc.to] {
+			ch.upOffsets[arc.from+1]++
+		} else {
+			ch.downOffsets[arc.to+1]++
+		}
+	}
+	for v := 1; v <= n; v++ {
+		ch.upOffsets[v] += ch.upOffsets[v-1]
+		ch.downOffsets[v] += ch.downOffsets[v-1]
+	}
+	ch.up = make([]uint32, ch.upOffsets[n])
+	ch.down = make([]uint32, ch.downOffsets[n])
+	upPos, downPos := slices.Clone(ch.upOffsets), slices.Clone(ch.downOffsets)
+	for i, arc := range ch.arcs {
+		if ch.ranks[arc.from] < ch.ranks[arc.to] {
+			ch.up[upPos[arc.from]] = uint32(i)
+			upPos[arc.from]++
+		} else {
+			ch.down[downPos[arc.to]] = uint32(i)
+			downPos[arc.to]++
+		}
+	}
+}
+
+// Graph returns the routing graph.
+func (ch *ContractionHierarchy) Graph() *Graph {
+	return ch.graph
+}
+
+type chContractor struct {
+	arcs       []chArc
+	out, in    [][]int32
+	contracted []bool
+	deleted    []int // number of contracted neighbours
+
+	// witness search
+	dist    []float64
+	touched []uint32
+	queue   chHeap
+	target  []int // equal to stamp for the targets of the current search
+	stamp   int
+}
+
+// addArc adds an arc, or replaces the arc between the same vertices if it is faster.
+func (c *chContractor) addArc(arc chArc) {
+	for _, i := range c.out[arc.from] {
+		if c.arcs[i].to == arc.to {
+			if arc.weight < c.arcs[i].weight {
+				c.arcs[i] = arc
+			}
+			return
+		}
+	}
+	c.out[arc.from] = append(c.out[arc.from], int32(len(c.arcs)))
+	c.in[arc.to] = append(c.in[arc.to], int32(len(c.arcs)))
+	c.arcs = append(c.arcs, arc)
+}
+
+// remove removes the arcs of the contracted vertex v from the lists of its neighbours.
+func (c *chContractor) remove(v uint32) {
+	for _, i := range c.in[v] {
+		u := c.arcs[i].from
+		c.out[u] = slices.DeleteFunc(c.out[u], func(j int32) bool {
+			return c.arcs[j].to == v
+		})
+	}
+	for _, i := range c.out[v] {
+		w := c.arcs[i].to
+		c.in[w] = slices.DeleteFunc(c.in[w], func(j int32) bool {
+			return c.arcs[j].from == v
+		})
+	}
+}
+
+// witness runs a Dijkstra search from u over the vertices that are not contracted, skipping v, up to the given weight and number of settled vertices.
+func (c *chContractor) witness(u, v uint32, maxWeight float64, targets, limit int) {
+	for _, w := range c.touched {
+		c.dist[w] = math.Inf(1)
+	}
+	c.touched = append(c.touched[:0], u)
+	c.dist[u] = 0.0
+	c.queue = append(c.queue[:0], chHeapItem{u, 0.0})
+	for settled := 0; 0 < c.queue.Len() && settled < limit; settled++ {
+		item := heap.Pop(&c.queue).(chHeapItem)
+		if c.dist[item.v] < item.dist {
+			continue
+		} else if maxWeight < item.dist {
+			break
+		} else if c.target[item.v] == c.stamp {
+			if targets--; targets == 0 {
+				break
+			}
+		}
+		for _, i := range c.out[item.v] {
+			arc := c.arcs[i]
+			if arc.to == v || c.contracted[arc.to] {
+				continue
+			}
+			if d := item.dist + float64(arc.weight); d < c.dist[arc.to] {
+				if math.IsInf(c.dist[arc.to], 1) {
+					c.touched = append(c.touched, arc.to)
+				}
+				c.dist[arc.to] = d
+				heap.Push(&c.queue, chHeapItem{arc.to, d})
+			}
+		}
+	}
+}
+
+// contract adds the shortcuts needed to contract vertex v and returns their number, or only counts them when simulating.
+func (c *chContractor) contract(v uint32, simulate bool) int {
+	shortcuts := 0
+	for _, i := range c.in[v] {
+		in := c.arcs[i]
+		if in.to != v || c.contracted[in.from] {
+			continue
+		}
+		c.stamp++
+		targets, maxWeight := 0, 0.0
+		for _, j := range c.out[v] {
+			if out := c.arcs[j]; out.from == v && out.to != in.from && !c.contracted[out.to] {
+				c.target[out.to] = c.stamp
+				targets++
+				maxWeight = max(maxWeight, float64(in.weight)+float64(out.weight))
+			}
+		}
+		if targets == 0 {
+			continue
+		}
+
+		if simulate {
+			c.witness(in.from, v, maxWeight, targets, chSimulateLimit)
+		} else {
+			c.witness(in.from, v, maxWeight, targets, chWitnessLimit)
+		}
+		for _, j := range c.out[v] {
+			out := c.arcs[j]
+			if out.from != v || out.to == in.from || c.contracted[out.to] {
+				continue
+			}
+			weight := float64(in.weight) + float64(out.weight)
+			if c.dist[out.to] <= weight {
+				continue
+			}
+			shortcuts++
+			if !simulate {
+				c.addArc(chArc{in.from, out.to, float32(weight), in.length + out.length, i, j})
+			}
+		}
+	}
+	return shortcuts
+}
+
+// priority returns the contraction priority of vertex v, where vertices with a lower priority are contracted first.
+func (c *chContractor) priority(v uint32) float64 {
+	removed := 0
+	for _, i := range c.in[v] {
+		if arc := c.arcs[i]; arc.to == v && !c.contracted[arc.from] {
+			removed++
+		}
+	}
+	for _, i := range c.out[v] {
+		if arc := c.arcs[i]; arc.from == v && !c.contracted[arc.to] {
+			removed++
+		}
+	}
+	return float64(c.contract(v, true)-removed) + float64(c.deleted[v])
+}
+
+type chHeapItem struct {
+	v    uint32
+	dist float64
+}
+
+type chHeap []chHeapItem
+
+func (h chHeap) Len() int           { return len(h) }
+func (h chHeap) Less(i, j int) bool { return h[i].dist < h[j].dist }
+func (h chHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *chHeap) Push(x any)        { *h = append(*h, x.(chHeapItem)) }
+func (h *chHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+type chQueueItem struct {
+	v        uint32
+	priority float64
+}
+
+// chQueue is the contraction order, which keeps track of the position of each vertex so that priorities can be updated.
+type chQueue struct {
+	items []chQueueItem
+	index []int
+}
+
+func (q chQueue) Len() int { return len(q.items) }
+func (q chQueue) Less(i, j int) bool {
+	if q.items[i].priority == q.items[j].priority {
+		return q.items[i].v < q.items[j].v
+	}
+	return q.items[i].priority < q.items[j].priority
+}
+func (q chQueue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.index[q.items[i].v], q.index[q.items[j].v] = i, j
+}
+func (q *chQueue) Push(x any) {
+	item := x.(chQueueItem)
+	q.index[item.v] = len(q.items)
+	q.items = append(q.items, item)
+}
+func (q *chQueue) Pop() any {
+	n := len(q.items)
+	x := q.items[n-1]
+	q.items = q.items[:n-1]
+	return x
+}
+
+// Snap returns the position on the nearest edge of the graph to c, see Graph.Snap.
+func (ch *ContractionHierarchy) Snap(c Coord) (Snap, bool) {
+	return ch.graph.Snap(c)
+}
+
+// lineDistance returns the planar distance in degrees from c to the line string.
+func lineDistance(coords []Coord, c Coord) float64 {
+	dist := math.Inf(1)
+	for i := 1; i < len(coords); i++ {
+		dist = math.Min(dist, segmentDistance(c, coords[i-1], coords[i]))
+	}
+	return dist
+}
+
+// projectLine returns the nearest point on the line string to c in planar coordinates, its geodesic distance along the line string, and the geodesic length of the line string.
+func projectLine(coords []Coord, c Coord) (Coord, float64, float64) {
+	p, along, length := coords[0], 0.0, 0.0
+	dist := math.Inf(1)
+	for i := 1; i < len(coords); i++ {
+		a, b := coords[i-1], coords[i]
+		ab, ac := b.Sub(a), c.Sub(a)
+		t := 0.0
+		if l := ab.X*ab.X + ab.Y*ab.Y; l != 0.0 {
+			t = math.Max(0.0, math.Min(1.0, (ac.X*ab.X+ac.Y*ab.Y)/l))
+		}
+		q := Coord{a.X + t*ab.X, a.Y + t*ab.Y}
+		if d := math.Hypot(c.X-q.X, c.Y-q.Y); d < dist {
+			p, along, dist = q, length+a.Distance(q), d
+		}
+		length += a.Distance(b)
+	}
+	return p, along, length
+}
+
+// lineSlice returns the part of the line string between the fractions t0 and t1 of its geodesic length.
+func lineSlice(coords []Coord, t0, t1 float64) []Coord {
+	length := 0.0
+	for i := 1; i < len(coords); i++ {
+		length += coords[i-1].Distance(coords[i])
+	}
+	from, to := t0*length, t1*length
+
+	slice := []Coord{}
+	pos := 0.0
+	for i := 1; i < len(coords); i++ {
+		a, b := coords[i-1], coords[i]
+		l := a.Distance(b)
+		interpolate := func(d float64) Coord {
+			if l == 0.0 {
+				return a
+			}
+			f := math.Max(0.0, math.Min(1.0, (d-pos)/l))
+			return Coord{a.X + f*(b.X-a.X), a.Y + f*(b.Y-a.Y)}
+		}
+		if len(slice) == 0 && from <= pos+l {
+			slice = append(slice, interpolate(from))
+		}
+		if 0 < len(slice) {
+			if to <= pos+l || i == len(coords)-1 {
+				return append(slice, interpolate(to))
+			}
+			slice = append(slice, b)
+		}
+		pos += l
+	}
+	return slice
+}
+
+type chLabel struct {
+	time, length float64
+	arc          int32 // arc to the previous vertex, or -1 for the start of the search
+	seed         int32 // index of the snapped edge at the start of the search
+	settled      bool
+}
+
+// chSearch is a Dijkstra search towards higher ranked vertices, either forward from a source or backward from a target.
+type chSearch struct {
+	ch       *ContractionHierarchy
+	backward bool
+	labels   map[uint32]chLabel
+	queue    chHeap
+}
+
+func (ch *ContractionHierarchy) newSearch(snap Snap, backward bool) *chSearch {
+	s := &chSearch{
+		ch:       ch,
+		backward: backward,
+		labels:   map[uint32]chLabel{},
+	}
+	g := ch.graph
+	for i, e := range snap.Edges {
+		t := 1.0 - snap.Fractions[i]
+		v := g.Targets[e]
+		if backward {
+			t = snap.Fractions[i]
+			v = uint32(g.Source(e))
+		}
+		label := chLabel{t * float64(g.Times[e]), t * float64(g.Lengths[e]), -1, int32(i), false}
+		if prev, ok := s.labels[v]; !ok || label.time < prev.time {
+			s.labels[v] = label
+			heap.Push(&s.queue, chHeapItem{v, label.time})
+		}
+	}
+	return s
+}
+
+// min returns the smallest travel time in the queue.
+func (s *chSearch) min() float64 {
+	for 0 < s.queue.Len() {
+		if item := s.queue[0]; !s.labels[item.v].settled && item.dist <= s.labels[item.v].time {
+			return item.dist
+		}
+		heap.Pop(&s.queue)
+	}
+	return math.Inf(1)
+}
+
+// next settles the next vertex and returns it, or returns false if the search is exhausted.
+func (s *chSearch) next() (uint32, chLabel, bool) {
+	if math.IsInf(s.min(), 1) {
+		return 0, chLabel{}, false
+	}
+	v := heap.Pop(&s.queue).(chHeapItem).v
+	label := s.labels[v]
+	label.settled = true
+	s.labels[v] = label
+
+	offsets, arcs := s.ch.upOffsets, s.ch.up
+	if s.backward {
+		offsets, arcs = s.ch.downOffsets, s.ch.down
+	}
+	for _, i := range arcs[offsets[v]:offsets[v+1]] {
+		arc := s.ch.arcs[i]
+		w := arc.to
+		if s.backward {
+			w = arc.from
+		}
+		next := chLabel{label.time + float64(arc.weight), label.length + float64(arc.length), int32(i), label.seed, false}
+		if prev, ok := s.labels[w]; !ok || !prev.settled && next.time < prev.time {
+			s.labels[w] = next
+			heap.Push(&s.queue, chHeapItem{w, next.time})
+		}
+	}
+	return v, label, true
+}
+
+// unpack appends the edges of the graph that the arc consists of.
+func (ch *ContractionHierarchy) unpack(edges []int, i int32) []int {
+	arc := ch.arcs[i]
+	if arc.b == -1 {
+		return append(edges, int(arc.a))
+	}
+	edges = ch.unpack(edges, arc.a)
+	return ch.unpack(edges, arc.b)
+}
+
+// Route is a path over the routing graph between two snapped positions.
+type Route struct {
+	Time   float64 // travel time in seconds
+	Length float64 // length in meters
+	Edges  []int   // edges of the graph, where the first and last edges may be traversed partially
+	Coords []Coord
+}
+
+// Route returns the fastest route from source to target. It returns false if the target cannot be reached.
+func (ch *ContractionHierarchy) Route(source, target Snap) (Route, bool) {
+	g := ch.graph
+	best, length := math.Inf(1), 0.0
+	direct := [2]int{-1, -1}
+	for i, e := range source.Edges {
+		for j, f := range target.Edges {
+			if e == f && source.Fractions[i] <= target.Fractions[j] {
+				t := target.Fractions[j] - source.Fractions[i]
+				if time := t * float64(g.Times[e]); time < best {
+					best, length = time, t*float64(g.Lengths[e])
+					direct = [2]int{i, j}
+				}
+			}
+		}
+	}
+
+	forward, backward := ch.newSearch(source, false), ch.newSearch(target, true)
+	meet, found := uint32(0), false
+	for {
+		s, other := forward, backward
+		if backward.min() < forward.min() {
+			s, other = backward, forward
+		}
+		if best <= s.min() {
+			break
+		}
+		v, label, _ := s.next()
+		if label2, ok := other.labels[v]; ok && label.time+label2.time < best {
+			best, length = label.time+label2.time, label.length+label2.length
+			meet, found = v, true
+		}
+	}
+	if math.IsInf(best, 1) {
+		return Route{}, false
+	}
+
+	route := Route{Time: best, Length: length}
+	if !found {
+		e := source.Edges[direct[0]]
+		route.Edges = []int{e}
+		route.Coords = lineSlice(g.EdgeCoords(e), source.Fractions[direct[0]], target.Fractions[direct[1]])
+		route.Coords[0], route.Coords[len(route.Coords)-1] = source.Coord, target.Coord
+		return route, true
+	}
+
+	// unpack the arcs from the source to the meeting vertex and from the meeting vertex to the target
+	var arcs []int32
+	v := meet
+	for label := forward.labels[v]; label.arc != -1; label = forward.labels[v] {
+		arcs = append(arcs, label.arc)
+		v = ch.arcs[label.arc].from
+	}
+	slices.Reverse(arcs)
+	first := forward.labels[v].seed
+	v = meet
+	for label := backward.labels[v]; label.arc != -1; label = backward.labels[v] {
+		arcs = append(arcs, label.arc)
+		v = ch.arcs[label.arc].to
+	}
+	last := backward.labels[v].seed
+
+	route.Edges = []int{source.Edges[first]}
+	for _, i := range arcs {
+		route.Edges = ch.unpack(route.Edges, i)
+	}
+	route.Edges = append(route.Edges, target.Edges[last])
+
+	route.Coords = lineSlice(g.EdgeCoords(source.Edges[first]), source.Fractions[first], 1.0)
+	for _, e := range route.Edges[1 : len(route.Edges)-1] {
+		route.Coords = append(route.Coords, g.EdgeCoords(e)[1:]...)
+	}
+	route.Coords = append(route.Coords, lineSlice(g.EdgeCoords(target.Edges[last]), 0.0, target.Fractions[last])[1:]...)
+	route.Coords[0], route.Coords[len(route.Coords)-1] = source.Coord, target.Coord
+	return route, true
+}
+
+// OneToMany returns the travel times in seconds and lengths in meters of the fastest routes from the source to each target, see Matrix.
+func (ch *ContractionHierarchy) OneToMany(source Snap, targets []Snap) ([]float64, []float64) {
+	times, lengths := ch.Matrix([]Snap{source}, targets)
+	return times[0], lengths[0]
+}
+
+type chBucket struct {
+	target       int
+	time, length float64
+}
+
+// Matrix returns the travel times in seconds and lengths in meters of the fastest routes from each source (rows) to each target (columns). Unreachable targets have an infinite time and length. It runs one backward search per target and one forward search per source, where the searches meet in buckets at the vertices.
+func (ch *ContractionHierarchy) Matrix(sources, targets []Snap) ([][]float64, [][]float64) {
+	buckets := map[uint32][]chBucket{}
+	for j, target := range targets {
+		s := ch.newSearch(target, true)
+		for {
+			v, label, ok := s.next()
+			if !ok {
+				break
+			}
+			buckets[v] = append(buckets[v], chBucket{j, label.time, label.length})
+		}
+	}
+
+	g := ch.graph
+	times := make([][]float64, len(sources))
+	lengths := make([][]float64, len(sources))
+	for i, source := range sources {
+		times[i] = make([]float64, len(targets))
+		lengths[i] = make([]float64, len(targets))
+		for j, target := range targets {
+			times[i][j], lengths[i][j] = math.Inf(1), math.Inf(1)
+			for k, e := range source.Edges {
+				for l, f := range target.Edges {
+					if e == f && source.Fractions[k] <= target.Fractions[l] {
+						t := target.Fractions[l] - source.Fractions[k]
+						if time := t * float64(g.Times[e]); time < times[i][j] {
+							times[i][j], lengths[i][j] = time, t*float64(g.Lengths[e])
+						}
+					}
+				}
+			}
+		}
+
+		s := ch.newSearch(source, false)
+		for {
+			v, label, ok := s.next()
+			if !ok {
+				break
+			}
+			for _, bucket := range buckets[v] {
+				if time := label.time + bucket.time; time < times[i][bucket.target] {
+					times[i][bucket.target] = time
+					lengths[i][bucket.target] = label.length + bucket.length
+				}
+			}
+		}
+	}
+	return times, lengths
+}
+
+// Bytes returns the serialized contraction hierarchy including its routing graph, which can be loaded with LoadContractionHierarchy.
+func (ch *ContractionHierarchy) Bytes() []byte {
+	g := ch.graph
+	b := []byte(chMagic)
+	b = binary.AppendUvarint(b, chVersion)
+	b = binary.AppendUvarint(b, uint64(g.NumVertices()))
+	for v, coord := range g.Coords {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(coord.X))
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(coord.Y))
+		b = binary.LittleEndian.AppendUint32(b, ch.ranks[v])
+		b = binary.AppendUvarint(b, g.NodeIDs[v])
+	}
+	for _, offset := range g.Offsets {
+		b = binary.LittleEndian.AppendUint32(b, offset)
+	}
+
+	b = binary.AppendUvarint(b, uint64(g.NumEdges()))
+	for e, target := range g.Targets {
+		b = binary.LittleEndian.AppendUint32(b, target)
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(g.Lengths[e]))
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(g.Times[e]))
+		b = binary.LittleEndian.AppendUint32(b, g.ShapeOffsets[e+1]-g.ShapeOffsets[e])
+		b = binary.AppendUvarint(b, g.WayIDs[e])
+	}
+	b = binary.AppendUvarint(b, uint64(len(g.Shapes)))
+	for _, coord := range g.Shapes {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(coord.X))
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(coord.Y))
+	}
+
+	b = binary.AppendUvarint(b, uint64(len(ch.arcs)))
+	for _, arc := range ch.arcs {
+		b = binary.LittleEndian.AppendUint32(b, arc.from)
+		b = binary.LittleEndian.AppendUint32(b, arc.to)
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(arc.weight))
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(arc.length))
+		b = binary.LittleEndian.AppendUint32(b, uint32(arc.a))
+		b = binary.LittleEndian.AppendUint32(b, uint32(arc.b))
+	}
+	return b
+}
+
+// LoadContractionHierarchy returns the contraction hierarchy serialized by Bytes.
+func LoadContractionHierarchy(b []byte) (*ContractionHierarchy, error) {
+	if len(b) < len(chMagic) || string(b[:len(chMagic)]) != chMagic {
+		return nil, fmt.Errorf("invalid contraction hierarchy header")
+	}
+	r := &binaryReader{name: "contraction hierarchy", b: b[len(chMagic):]}
+	if version := r.uvarint(); r.err == nil && version != chVersion {
+		return nil, fmt.Errorf("unsupported contraction hierarchy version %v", version)
+	}
+
+	g := &Graph{edges: &edgeIndex{}}
+	n := r.count(21)
+	g.Coords = make([]Coord, n)
+	g.NodeIDs = make([]uint64, n)
+	ranks := make([]uint32, n)
+	for v := 0; v < n; v++ {
+		g.Coords[v] = Coord{r.float64(), r.float64()}
+		ranks[v] = r.uint32()
+		g.NodeIDs[v] = r.uvarint()
+	}
+	g.Offsets = make([]uint32, n+1)
+	for v := range g.Offsets {
+		g.Offsets[v] = r.uint32()
+	}
+
+	m := r.count(17)
+	g.Targets = make([]uint32, m)
+	g.Lengths = make([]float32, m)
+	g.Times = make([]float32, m)
+	g.WayIDs = make([]uint64, m)
+	g.ShapeOffsets = make([]uint32, m+1)
+	for e := 0; e < m; e++ {
+		g.Targets[e] = r.uint32()
+		g.Lengths[e] = r.float32()
+		g.Times[e] = r.float32()
+		g.ShapeOffsets[e+1] = g.ShapeOffsets[e] + r.uint32()
+		g.WayIDs[e] = r.uvarint()
+	}
+	g.Shapes = make([]Coord, r.count(16))
+	for i := range g.Shapes {
+		g.Shapes[i] = Coord{r.float64(), r.float64()}
+	}
+
+	arcs := make([]chArc, r.count(24))
+	for i := range arcs {
+		arcs[i] = chArc{r.uint32(), r.uint32(), r.float32(), r.float32(), int32(r.uint32()), int32(r.uint32())}
+	}
+	if r.err != nil {
+		return nil, r.err
+	} else if len(r.b) != 0 {
+		return nil, fmt.Errorf("invalid contraction hierarchy data")
+	}
+
+	// validate indices so that queries cannot go out of range
+	valid := g.Offsets[0] == 0 && int(g.Offsets[n]) == m && int(g.ShapeOffsets[m]) == len(g.Shapes)
+	for v := 0; v < n && valid; v++ {
+		valid = g.Offsets[v] <= g.Offsets[v+1] && int(ranks[v]) < n
+	}
+	for e := 0; e < m && valid; e++ {
+		valid = int(g.Targets[e]) < n
+	}
+	for _, arc := range arcs {
+		if !valid {
+			break
+		} else if valid = int(arc.from) < n && int(arc.to) < n; !valid {
+			break
+		}
+		if arc.b == -1 {
+			valid = 0 <= arc.a && int(arc.a) < m && g.Source(int(arc.a)) == int(arc.from) && g.Targets[arc.a] == arc.to
+		} else if valid = 0 <= arc.a && int(arc.a) < len(arcs) && 0 <= arc.b && int(arc.b) < len(arcs); valid {
+			// shortcuts go over a lower ranked vertex, which guarantees that unpacking terminates
+			a, b := arcs[arc.a], arcs[arc.b]
+			mid := a.to
+			valid = a.from == arc.from && b.from == mid && b.to == arc.to && ranks[mid] < ranks[arc.from] && ranks[mid] < ranks[arc.to]
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("invalid contraction hierarchy data")
+	}
+
+	ch := &ContractionHierarchy{
+		graph: g,
+		ranks: ranks,
+		arcs:  arcs,
+	}
+	ch.index()
+	return ch, nil
+}
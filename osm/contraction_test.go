@@ -0,0 +1,180 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// testGridGraph returns a graph of a grid of roads with random types and oneway roads.
+func testGridGraph(t *testing.T, size int) *Graph {
+	rnd := rand.New(rand.NewSource(1))
+	highways := []string{"primary", "secondary", "residential", "residential", "service"}
+	nodes := []Node{}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			nodes = append(nodes, Node{ID: uint64(1 + y*size + x), Lon: 0.01 * float64(x), Lat: 0.01 * float64(y)})
+		}
+	}
+	ways := []Way{}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			id := uint64(1 + y*size + x)
+			for _, next := range []uint64{id + 1, id + uint64(size)} {
+				if (next == id+1 && x == size-1) || (next == id+uint64(size) && y == size-1) || rnd.Intn(10) == 0 {
+					continue
+				}
+				tags := Tags{{"highway", highways[rnd.Intn(len(highways))]}}
+				if rnd.Intn(5) == 0 {
+					tags = append(tags, Tag{"oneway", []string{"yes", "-1"}[rnd.Intn(2)]})
+				}
+				ways = append(ways, Way{ID: uint64(len(ways) + 1), Refs: []uint64{id, next}, Tags: tags})
+			}
+		}
+	}
+	b := writeTestPBF(t, nodes, ways, nil)
+
+	z := NewParser(bytes.NewReader(b))
+	g, err := z.ExtractGraph(context.Background(), CarProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// graphDijkstra returns the travel time between the snapped positions using Dijkstra's algorithm over the graph.
+func graphDijkstra(g *Graph, source, target Snap) float64 {
+	dist := make([]float64, g.NumVertices())
+	done := make([]bool, g.NumVertices())
+	for v := range dist {
+		dist[v] = math.Inf(1)
+	}
+	best := math.Inf(1)
+	for i, e := range source.Edges {
+		dist[g.Targets[e]] = math.Min(dist[g.Targets[e]], (1.0-source.Fractions[i])*float64(g.Times[e]))
+		for j, f := range target.Edges {
+			if e == f && source.Fractions[i] <= target.Fractions[j] {
+				best = math.Min(best, (target.Fractions[j]-source.Fractions[i])*float64(g.Times[e]))
+			}
+		}
+	}
+	for {
+		v := -1
+		for w := range dist {
+			if !done[w] && !math.IsInf(dist[w], 1) && (v == -1 || dist[w] < dist[v]) {
+				v = w
+			}
+		}
+		if v == -1 {
+			break
+		}
+		done[v] = true
+		start, end := g.Edges(v)
+		for e := start; e < end; e++ {
+			dist[g.Targets[e]] = math.Min(dist[g.Targets[e]], dist[v]+float64(g.Times[e]))
+		}
+	}
+	for j, f := range target.Edges {
+		best = math.Min(best, dist[g.Source(f)]+target.Fractions[j]*float64(g.Times[f]))
+	}
+	return best
+}
+
+func TestContractionHierarchy(t *testing.T) {
+	g := testGridGraph(t, 12)
+	ch := NewContractionHierarchy(g)
+
+	rnd := rand.New(rand.NewSource(2))
+	snaps := []Snap{}
+	for i := 0; i < 12; i++ {
+		c := Coord{0.11 * rnd.Float64(), 0.11 * rnd.Float64()}
+		snap, ok := ch.Snap(c)
+		if !ok {
+			t.Fatal("snap failed")
+		}
+		snaps = append(snaps, snap)
+	}
+
+	times, lengths := ch.Matrix(snaps, snaps)
+	for i, source := range snaps {
+		for j, target := range snaps {
+			expected := graphDijkstra(g, source, target)
+			if 1e-3 < math.Abs(times[i][j]-expected) && !(math.IsInf(expected, 1) && math.IsInf(times[i][j], 1)) {
+				t.Errorf("%v→%v: expected time %v, got %v", i, j, expected, times[i][j])
+			}
+
+			route, ok := ch.Route(source, target)
+			if ok != !math.IsInf(expected, 1) {
+				t.Errorf("%v→%v: expected route %v, got %v", i, j, !math.IsInf(expected, 1), ok)
+			} else if !ok {
+				continue
+			}
+			if 1e-3 < math.Abs(route.Time-expected) || 1e-3 < math.Abs(route.Length-lengths[i][j]) {
+				t.Errorf("%v→%v: expected %v s and %v m, got %v s and %v m", i, j, expected, lengths[i][j], route.Time, route.Length)
+			}
+			if route.Coords[0] != source.Coord || route.Coords[len(route.Coords)-1] != target.Coord {
+				t.Errorf("%v→%v: route from %v to %v does not connect %v and %v", i, j, route.Coords[0], route.Coords[len(route.Coords)-1], source.Coord, target.Coord)
+			}
+			if 1e-3 < math.Abs(GeodesicLength(route.Coords)-route.Length) {
+				t.Errorf("%v→%v: route length %v does not match coordinates %v", i, j, route.Length, GeodesicLength(route.Coords))
+			}
+			for k := 1; k < len(route.Edges); k++ {
+				if g.Targets[route.Edges[k-1]] != uint32(g.Source(route.Edges[k])) {
+					t.Errorf("%v→%v: edges %v and %v are not connected", i, j, route.Edges[k-1], route.Edges[k])
+				}
+			}
+		}
+	}
+
+	oneTimes, _ := ch.OneToMany(snaps[0], snaps)
+	if !slices.Equal(oneTimes, times[0]) {
+		t.Errorf("one-to-many %v does not match matrix %v", oneTimes, times[0])
+	}
+
+	ch2, err := LoadContractionHierarchy(ch.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	times2, _ := ch2.Matrix(snaps, snaps)
+	for i := range times {
+		if !slices.Equal(times[i], times2[i]) {
+			t.Errorf("loaded matrix %v does not match %v", times2[i], times[i])
+		}
+	}
+	if snap, _ := ch2.Snap(snaps[3].Coord); snap.Coord != snaps[3].Coord || !slices.Equal(snap.Edges, snaps[3].Edges) {
+		t.Errorf("loaded snap %v does not match %v", snap, snaps[3])
+	}
+
+	b := ch.Bytes()
+	if _, err := LoadContractionHierarchy(b[:len(b)-1]); err == nil {
+		t.Errorf("expected error for truncated data")
+	}
+	b[len(b)-5] = 0x7f // child arc of the last arc
+	if _, err := LoadContractionHierarchy(b); err == nil {
+		t.Errorf("expected error for invalid arc")
+	}
+}
+
+func TestContractionHierarchyRestrictions(t *testing.T) {
+	ch := NewContractionHierarchy(testGraph(t, CarProfile))
+	source, _ := ch.Snap(Coord{0.01, 0.005}) // way 3, oneway to node 2
+	target, _ := ch.Snap(Coord{0.01, -0.005})
+	if len(source.Edges) != 1 || len(target.Edges) != 2 {
+		t.Fatalf("expected one source edge and two target edges, got %v and %v", source.Edges, target.Edges)
+	}
+
+	route, ok := ch.Route(source, target)
+	if !ok {
+		t.Fatal("no route")
+	}
+	// no left turn at node 2, so make a U-turn at node 3
+	if !slices.Contains(route.Coords, Coord{0.02, 0.0}) {
+		t.Errorf("route %v does not make a U-turn at node 3", route.Coords)
+	}
+	if _, ok := ch.Route(target, source); ok {
+		t.Errorf("expected no route against the oneway")
+	}
+}
@@ -0,0 +1,185 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// CutStrategy is the strategy used to select objects when cutting a region, following the strategies of osmium extract.
+type CutStrategy int
+
+const (
+	// SimpleStrategy selects all nodes within the region, all ways with at least one node within the region, and all relations with at least one selected member. Ways may reference nodes that are not selected.
+	SimpleStrategy CutStrategy = iota
+	// CompleteWaysStrategy is like SimpleStrategy, but also selects all nodes of the selected ways so that ways are complete.
+	CompleteWaysStrategy
+	// SmartStrategy is like CompleteWaysStrategy, but also selects all member ways, and their nodes, of selected multipolygon relations so that multipolygons are complete.
+	SmartStrategy
+)
+
+func (s CutStrategy) String() string {
+	switch s {
+	case SimpleStrategy:
+		return "simple"
+	case CompleteWaysStrategy:
+		return "complete_ways"
+	case SmartStrategy:
+		return "smart"
+	}
+	return fmt.Sprintf("CutStrategy(%d)", int(s))
+}
+
+// Cut writes all objects that are selected by the region and strategy to w in the OSM PBF file format, with the region's bounds in the header. Objects are written in the same order as the input. Relations with selected relation members are selected recursively, up to MaxRelationDepth levels deep. The input must be sorted by type (nodes, ways, then relations) and by ID, as is required by Writer. This function requires parsing the file four times (or five for the smart strategy), and the last pass uses a single worker to preserve the order of objects.
+func (z *Parser) Cut(ctx context.Context, w io.Writer, region Region, strategy CutStrategy) error {
+	bounds := region.Bounds()
+	var mu sync.Mutex
+
+	// select nodes within the region
+	nodes := NewUint64Set(8, 0.6)
+	nodeFunc := func(node Node) {
+		coord := Coord{node.Lon, node.Lat}
+		if bounds.Contains(coord) && region.Contains(coord) {
+			mu.Lock()
+			nodes.Add(node.ID)
+			mu.Unlock()
+		}
+	}
+	if err := z.Parse(ctx, nodeFunc, nil, nil); err != nil {
+		return err
+	}
+
+	// select ways with at least one node within the region
+	ways := NewUint64Set(8, 0.6)
+	wayNodes := NewUint64Set(8, 0.6) // nodes of selected ways
+	wayFunc := func(way Way) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, ref := range way.Refs {
+			if nodes.Has(ref) {
+				ways.Add(way.ID)
+				if strategy != SimpleStrategy {
+					for _, ref := range way.Refs {
+						wayNodes.Add(ref)
+					}
+				}
+				return
+			}
+		}
+	}
+	if err := z.Parse(ctx, nil, wayFunc, nil); err != nil {
+		return err
+	}
+
+	// select relations with at least one selected member
+	relations := NewUint64Set(8, 0.6)
+	parents := map[uint64][]uint64{}          // parent relations by child relation
+	multipolygonWays := map[uint64][]uint64{} // member ways by multipolygon relation
+	relationFunc := func(relation Relation) {
+		selected := false
+		var memberWays []uint64
+		mu.Lock()
+		for _, member := range relation.Members {
+			if member.Type == NodeType && nodes.Has(member.ID) || member.Type == WayType && ways.Has(member.ID) {
+				selected = true
+			}
+			if member.Type == WayType {
+				memberWays = append(memberWays, member.ID)
+			} else if member.Type == RelationType {
+				parents[member.ID] = append(parents[member.ID], relation.ID)
+			}
+		}
+		if selected {
+			relations.Add(relation.ID)
+		}
+		if strategy == SmartStrategy && relation.Tags.Find("type") == "multipolygon" && 0 < len(memberWays) {
+			multipolygonWays[relation.ID] = memberWays
+		}
+		mu.Unlock()
+	}
+	if err := z.Parse(ctx, nil, nil, relationFunc); err != nil {
+		return err
+	}
+
+	// select parent relations of selected relations
+	var addParents func(uint64, int)
+	addParents = func(id uint64, depth int) {
+		if MaxRelationDepth <= depth {
+			return
+		}
+		for _, parent := range parents[id] {
+			if !relations.Has(parent) {
+				relations.Add(parent)
+				addParents(parent, depth+1)
+			}
+		}
+	}
+	for child := range parents {
+		if relations.Has(child) {
+			addParents(child, 0)
+		}
+	}
+	parents = nil
+
+	if strategy == SmartStrategy {
+		// select all member ways of selected multipolygons and their nodes
+		extraWays := NewUint64Set(8, 0.6)
+		for id, memberWays := range multipolygonWays {
+			if relations.Has(id) {
+				for _, way := range memberWays {
+					if !ways.Has(way) {
+						extraWays.Add(way)
+					}
+				}
+			}
+		}
+		if 0 < extraWays.Size() {
+			wayFunc := func(way Way) {
+				if extraWays.Has(way.ID) {
+					mu.Lock()
+					for _, ref := range way.Refs {
+						wayNodes.Add(ref)
+					}
+					mu.Unlock()
+				}
+			}
+			if err := z.Parse(ctx, nil, wayFunc, nil); err != nil {
+				return err
+			}
+			for _, memberWays := range multipolygonWays {
+				for _, way := range memberWays {
+					if extraWays.Has(way) {
+						ways.Add(way)
+					}
+				}
+			}
+		}
+	}
+	multipolygonWays = nil
+
+	// write selected objects in order using a single worker
+	var errWrite error
+	pw := NewWriter(w, bounds)
+	nodeFunc = func(node Node) {
+		if errWrite == nil && (nodes.Has(node.ID) || wayNodes.Has(node.ID)) {
+			errWrite = pw.WriteNode(node)
+		}
+	}
+	wayFunc = func(way Way) {
+		if errWrite == nil && ways.Has(way.ID) {
+			errWrite = pw.WriteWay(way)
+		}
+	}
+	relationFunc = func(relation Relation) {
+		if errWrite == nil && relations.Has(relation.ID) {
+			errWrite = pw.WriteRelation(relation)
+		}
+	}
+	if err := z.parse(ctx, 1, nodeFunc, wayFunc, relationFunc); err != nil {
+		return err
+	} else if errWrite != nil {
+		return errWrite
+	}
+	return pw.Close()
+}
@@ -0,0 +1,80 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestCut(t *testing.T) {
+	b := writeTestPBF(t, testNodes, testWays, testRelations)
+	tests := []struct {
+		strategy  CutStrategy
+		nodes     []uint64
+		ways      []uint64
+		relations []uint64
+	}{
+		{SimpleStrategy, []uint64{1}, []uint64{10}, []uint64{20, 22}},
+		{CompleteWaysStrategy, []uint64{1, 2}, []uint64{10}, []uint64{20, 22}},
+		{SmartStrategy, []uint64{1, 2, 3, 4}, []uint64{10, 12}, []uint64{20, 22}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			z := NewParser(bytes.NewReader(b))
+			if err := z.Cut(context.Background(), &buf, Bounds{{0.0, 0.0}, {10.0, 10.0}}, tt.strategy); err != nil {
+				t.Fatal(err)
+			}
+
+			nodes, ways, relations := parseTestPBF(t, buf.Bytes())
+			ids := []uint64{}
+			for _, node := range nodes {
+				ids = append(ids, node.ID)
+			}
+			if !slices.Equal(ids, tt.nodes) {
+				t.Errorf("expected nodes %v, got %v", tt.nodes, ids)
+			}
+			ids = ids[:0]
+			for _, way := range ways {
+				ids = append(ids, way.ID)
+			}
+			if !slices.Equal(ids, tt.ways) {
+				t.Errorf("expected ways %v, got %v", tt.ways, ids)
+			}
+			ids = ids[:0]
+			for _, relation := range relations {
+				ids = append(ids, relation.ID)
+			}
+			if !slices.Equal(ids, tt.relations) {
+				t.Errorf("expected relations %v, got %v", tt.relations, ids)
+			}
+		})
+	}
+}
+
+func TestCutWrapped(t *testing.T) {
+	b := writeTestPBF(t, testNodes, testWays, testRelations)
+	var buf bytes.Buffer
+	z := NewParser(bytes.NewReader(b))
+	if err := z.Cut(context.Background(), &buf, Bounds{{10.0, -10.0}, {0.0, 10.0}}, SimpleStrategy); err != nil {
+		t.Fatal(err)
+	}
+
+	// header spans all longitudes
+	var header bytes.Buffer
+	if err := NewWriter(&header, Bounds{{-180.0, -10.0}, {180.0, 10.0}}).Close(); err != nil {
+		t.Fatal(err)
+	} else if !bytes.HasPrefix(buf.Bytes(), header.Bytes()) {
+		t.Errorf("expected header with bounds spanning all longitudes")
+	}
+
+	nodes, _, _ := parseTestPBF(t, buf.Bytes())
+	ids := []uint64{}
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	if !slices.Equal(ids, []uint64{2, 3, 4}) {
+		t.Errorf("expected nodes on both sides of the antimeridian, got %v", ids)
+	}
+}
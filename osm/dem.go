@@ -0,0 +1,671 @@
+package osm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// demSampleDistance is the distance in meters between elevation samples along line strings, which is about the resolution of 1 arc second DEMs.
+const demSampleDistance = 30.0
+
+// DEMTile is a raster of elevations in meters of a digital elevation model (DEM) in geographic coordinates.
+type DEMTile struct {
+	Origin        Coord     // centre of the north-west pixel
+	Step          Coord     // distance in degrees between pixel centres, where latitude decreases with rows
+	Width, Height int       // number of columns and rows
+	Data          []float32 // elevations by row from north to south, NaN if there is no data
+}
+
+// Bounds returns the area covered by the pixels of the tile.
+func (t *DEMTile) Bounds() Bounds {
+	return Bounds{
+		{t.Origin.X - t.Step.X/2.0, t.Origin.Y - (float64(t.Height)-0.5)*t.Step.Y},
+		{t.Origin.X + (float64(t.Width)-0.5)*t.Step.X, t.Origin.Y + t.Step.Y/2.0},
+	}
+}
+
+// Elevation returns the elevation at c using bilinear interpolation between the four surrounding pixel centres, ignoring pixels without data. It returns NaN if c is outside the tile or there is no data.
+func (t *DEMTile) Elevation(c Coord) float64 {
+	fx, fy := (c.X-t.Origin.X)/t.Step.X, (t.Origin.Y-c.Y)/t.Step.Y
+	if t.Width == 0 || t.Height == 0 || fx < -0.5 || float64(t.Width)-0.5 < fx || fy < -0.5 || float64(t.Height)-0.5 < fy {
+		return math.NaN()
+	}
+	fx = math.Max(0.0, math.Min(float64(t.Width-1), fx))
+	fy = math.Max(0.0, math.Min(float64(t.Height-1), fy))
+	i0, j0 := int(fx), int(fy)
+	i1, j1 := min(i0+1, t.Width-1), min(j0+1, t.Height-1)
+	tx, ty := fx-float64(i0), fy-float64(j0)
+
+	z, w := 0.0, 0.0
+	for _, p := range [4]struct {
+		i, j int
+		w    float64
+	}{
+		{i0, j0, (1.0 - tx) * (1.0 - ty)},
+		{i1, j0, tx * (1.0 - ty)},
+		{i0, j1, (1.0 - tx) * ty},
+		{i1, j1, tx * ty},
+	} {
+		if v := float64(t.Data[p.j*t.Width+p.i]); 0.0 < p.w && !math.IsNaN(v) {
+			z += p.w * v
+			w += p.w
+		}
+	}
+	if w == 0.0 {
+		return math.NaN()
+	}
+	return z / w
+}
+
+// hgtSouthWest returns the south-west corner of an SRTM tile from its filename, such as N53E006.hgt.
+func hgtSouthWest(filename string) (Coord, error) {
+	name := strings.ToUpper(strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+	if len(name) != 7 || (name[0] != 'N' && name[0] != 'S') || (name[3] != 'E' && name[3] != 'W') {
+		return Coord{}, fmt.Errorf("invalid HGT filename %v", filename)
+	}
+	lat, err := strconv.Atoi(name[1:3])
+	if err != nil {
+		return Coord{}, fmt.Errorf("invalid HGT filename %v", filename)
+	}
+	lon, err := strconv.Atoi(name[4:7])
+	if err != nil {
+		return Coord{}, fmt.Errorf("invalid HGT filename %v", filename)
+	}
+	if name[0] == 'S' {
+		lat = -lat
+	}
+	if name[3] == 'W' {
+		lon = -lon
+	}
+	return Coord{float64(lon), float64(lat)}, nil
+}
+
+// ReadHGT reads an SRTM tile of one by one degree, where the location is derived from the filename such as N53E006.hgt. The tile consists of big-endian 16-bit elevations by row from north to south, where the outer rows and columns lie on the edges of the tile, such as 3601x3601 for 1 arc second and 1201x1201 for 3 arc seconds. Voids are returned as NaN.
+func ReadHGT(r io.Reader, filename string) (*DEMTile, error) {
+	sw, err := hgtSouthWest(filename)
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	n := int(math.Sqrt(float64(len(b) / 2)))
+	if n < 2 || 2*n*n != len(b) {
+		return nil, fmt.Errorf("invalid HGT size of %v bytes", len(b))
+	}
+
+	step := 1.0 / float64(n-1)
+	tile := &DEMTile{
+		Origin: Coord{sw.X, sw.Y + 1.0},
+		Step:   Coord{step, step},
+		Width:  n,
+		Height: n,
+		Data:   make([]float32, n*n),
+	}
+	for i := range tile.Data {
+		if v := int16(binary.BigEndian.Uint16(b[2*i:])); v == -32768 {
+			tile.Data[i] = float32(math.NaN())
+		} else {
+			tile.Data[i] = float32(v)
+		}
+	}
+	return tile, nil
+}
+
+// TIFF tags
+const (
+	tiffImageWidth        = 256
+	tiffImageLength       = 257
+	tiffBitsPerSample     = 258
+	tiffCompression       = 259
+	tiffStripOffsets      = 273
+	tiffSamplesPerPixel   = 277
+	tiffRowsPerStrip      = 278
+	tiffStripByteCounts   = 279
+	tiffPredictor         = 317
+	tiffTileWidth         = 322
+	tiffTileLength        = 323
+	tiffTileOffsets       = 324
+	tiffTileByteCounts    = 325
+	tiffSampleFormat      = 339
+	tiffModelPixelScale   = 33550
+	tiffModelTiepoint     = 33922
+	tiffModelTransform    = 34264
+	tiffGeoKeyDirectory   = 34735
+	tiffGDALNoData        = 42113
+	geoKeyModelType       = 1024
+	geoKeyRasterType      = 1025
+	geoModelTypeProjected = 1
+	geoRasterPixelIsPoint = 2
+)
+
+// tiffTypeSizes are the sizes in bytes of the TIFF field types.
+var tiffTypeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+type tiffField struct {
+	typ  uint16
+	data []byte
+}
+
+type tiffReader struct {
+	r      io.ReaderAt
+	order  binary.ByteOrder
+	fields map[uint16]tiffField
+}
+
+func (t *tiffReader) read(offset int64, n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := t.r.ReadAt(b, offset); err != nil {
+		return nil, fmt.Errorf("invalid TIFF data: %w", err)
+	}
+	return b, nil
+}
+
+func (t *tiffReader) values(tag uint16) []float64 {
+	field, ok := t.fields[tag]
+	if !ok {
+		return nil
+	}
+	size := tiffTypeSizes[field.typ]
+	vs := make([]float64, len(field.data)/size)
+	for i := range vs {
+		b := field.data[i*size:]
+		switch field.typ {
+		case 1, 7:
+			vs[i] = float64(b[0])
+		case 6:
+			vs[i] = float64(int8(b[0]))
+		case 3:
+			vs[i] = float64(t.order.Uint16(b))
+		case 8:
+			vs[i] = float64(int16(t.order.Uint16(b)))
+		case 4:
+			vs[i] = float64(t.order.Uint32(b))
+		case 9:
+			vs[i] = float64(int32(t.order.Uint32(b)))
+		case 5:
+			vs[i] = float64(t.order.Uint32(b)) / float64(t.order.Uint32(b[4:]))
+		case 10:
+			vs[i] = float64(int32(t.order.Uint32(b))) / float64(int32(t.order.Uint32(b[4:])))
+		case 11:
+			vs[i] = float64(math.Float32frombits(t.order.Uint32(b)))
+		case 12:
+			vs[i] = math.Float64frombits(t.order.Uint64(b))
+		default:
+			return nil
+		}
+	}
+	return vs
+}
+
+func (t *tiffReader) value(tag uint16, def float64) float64 {
+	if vs := t.values(tag); 0 < len(vs) {
+		return vs[0]
+	}
+	return def
+}
+
+// ReadGeoTIFF reads a single-band GeoTIFF with elevations in geographic coordinates, such as the Copernicus DEM or SRTM tiles in GeoTIFF format. Images may be stored in strips or tiles, uncompressed or with deflate compression, with or without a predictor, and have integer or floating-point samples. The GDAL no data value is returned as NaN. Projected coordinate systems and rotated images are not supported.
+func ReadGeoTIFF(r io.ReaderAt) (*DEMTile, error) {
+	return readGeoTIFF(r, true)
+}
+
+func readGeoTIFF(r io.ReaderAt, data bool) (*DEMTile, error) {
+	t := &tiffReader{r: r, fields: map[uint16]tiffField{}}
+	header, err := t.read(0, 8)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(header, []byte("II")) {
+		t.order = binary.LittleEndian
+	} else if bytes.HasPrefix(header, []byte("MM")) {
+		t.order = binary.BigEndian
+	} else {
+		return nil, fmt.Errorf("invalid TIFF header")
+	}
+	if version := t.order.Uint16(header[2:]); version == 43 {
+		return nil, fmt.Errorf("unsupported BigTIFF")
+	} else if version != 42 {
+		return nil, fmt.Errorf("invalid TIFF header")
+	}
+
+	// first image file directory
+	offset := int64(t.order.Uint32(header[4:]))
+	b, err := t.read(offset, 2)
+	if err != nil {
+		return nil, err
+	}
+	n := int(t.order.Uint16(b))
+	if b, err = t.read(offset+2, 12*n); err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		entry := b[12*i : 12*i+12]
+		tag, typ, count := t.order.Uint16(entry), t.order.Uint16(entry[2:]), int64(t.order.Uint32(entry[4:]))
+		size, ok := tiffTypeSizes[typ]
+		if !ok {
+			continue
+		} else if 1<<30 < count*int64(size) {
+			return nil, fmt.Errorf("invalid TIFF data: field %v too large", tag)
+		}
+		field := tiffField{typ: typ}
+		if count*int64(size) <= 4 {
+			field.data = entry[8 : 8+count*int64(size)]
+		} else if field.data, err = t.read(int64(t.order.Uint32(entry[8:])), int(count)*size); err != nil {
+			return nil, err
+		}
+		t.fields[tag] = field
+	}
+
+	width, height := int(t.value(tiffImageWidth, 0)), int(t.value(tiffImageLength, 0))
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid TIFF image size %vx%v", width, height)
+	} else if samples := t.value(tiffSamplesPerPixel, 1); samples != 1 {
+		return nil, fmt.Errorf("unsupported TIFF with %v samples per pixel", samples)
+	}
+
+	// georeferencing
+	modelType, rasterType := 0, 0
+	if keys := t.values(tiffGeoKeyDirectory); 4 <= len(keys) {
+		for i := 0; i < int(keys[3]) && 8+4*i <= len(keys); i++ {
+			key := keys[4+4*i : 8+4*i]
+			if key[1] != 0 {
+				continue // value is stored in another tag
+			} else if key[0] == geoKeyModelType {
+				modelType = int(key[3])
+			} else if key[0] == geoKeyRasterType {
+				rasterType = int(key[3])
+			}
+		}
+	}
+	if modelType == geoModelTypeProjected {
+		return nil, fmt.Errorf("unsupported projected coordinate system in GeoTIFF")
+	}
+	var tie [4]float64 // raster I, J and model X, Y
+	var scale [2]float64
+	if vs, ss := t.values(tiffModelTiepoint), t.values(tiffModelPixelScale); 6 <= len(vs) && 2 <= len(ss) {
+		tie = [4]float64{vs[0], vs[1], vs[3], vs[4]}
+		scale = [2]float64{ss[0], ss[1]}
+	} else if m := t.values(tiffModelTransform); 16 <= len(m) {
+		if m[1] != 0.0 || m[4] != 0.0 {
+			return nil, fmt.Errorf("unsupported rotated GeoTIFF")
+		}
+		tie = [4]float64{0.0, 0.0, m[3], m[7]}
+		scale = [2]float64{m[0], -m[5]}
+	} else {
+		return nil, fmt.Errorf("missing georeferencing in GeoTIFF")
+	}
+	if !(0.0 < scale[0]) || !(0.0 < scale[1]) {
+		return nil, fmt.Errorf("unsupported GeoTIFF pixel scale %v", scale)
+	}
+	centre := 0.5
+	if rasterType == geoRasterPixelIsPoint {
+		centre = 0.0
+	}
+	tile := &DEMTile{
+		Origin: Coord{tie[2] + (centre-tie[0])*scale[0], tie[3] - (centre-tie[1])*scale[1]},
+		Step:   Coord{scale[0], scale[1]},
+		Width:  width,
+		Height: height,
+	}
+	if !data {
+		return tile, nil
+	}
+
+	// sample format
+	bits, format := int(t.value(tiffBitsPerSample, 1)), int(t.value(tiffSampleFormat, 1))
+	compression, predictor := int(t.value(tiffCompression, 1)), int(t.value(tiffPredictor, 1))
+	if compression != 1 && compression != 8 && compression != 32946 {
+		return nil, fmt.Errorf("unsupported TIFF compression %v", compression)
+	} else if predictor != 1 && !(predictor == 2 && format != 3) && !(predictor == 3 && format == 3) {
+		return nil, fmt.Errorf("unsupported TIFF predictor %v", predictor)
+	}
+	order := t.order
+	if predictor == 3 {
+		order = binary.BigEndian // the floating-point predictor stores the most significant bytes first
+	}
+	var sample func([]byte) float64
+	switch {
+	case format == 1 && bits == 8:
+		sample = func(b []byte) float64 { return float64(b[0]) }
+	case format == 2 && bits == 8:
+		sample = func(b []byte) float64 { return float64(int8(b[0])) }
+	case format == 1 && bits == 16:
+		sample = func(b []byte) float64 { return float64(order.Uint16(b)) }
+	case format == 2 && bits == 16:
+		sample = func(b []byte) float64 { return float64(int16(order.Uint16(b))) }
+	case format == 1 && bits == 32:
+		sample = func(b []byte) float64 { return float64(order.Uint32(b)) }
+	case format == 2 && bits == 32:
+		sample = func(b []byte) float64 { return float64(int32(order.Uint32(b))) }
+	case format == 3 && bits == 32:
+		sample = func(b []byte) float64 { return float64(math.Float32frombits(order.Uint32(b))) }
+	case format == 3 && bits == 64:
+		sample = func(b []byte) float64 { return math.Float64frombits(order.Uint64(b)) }
+	default:
+		return nil, fmt.Errorf("unsupported TIFF sample format %v with %v bits", format, bits)
+	}
+	size := bits / 8
+	noData := math.NaN()
+	if field, ok := t.fields[tiffGDALNoData]; ok {
+		if noData, err = strconv.ParseFloat(strings.TrimSpace(strings.TrimRight(string(field.data), "\x00")), 64); err != nil {
+			return nil, fmt.Errorf("invalid GDAL no data value %q", field.data)
+		}
+	}
+
+	// strips or tiles
+	var chunkWidth, chunkHeight int
+	var offsets, counts []float64
+	if _, ok := t.fields[tiffTileOffsets]; ok {
+		chunkWidth, chunkHeight = int(t.value(tiffTileWidth, 0)), int(t.value(tiffTileLength, 0))
+		offsets, counts = t.values(tiffTileOffsets), t.values(tiffTileByteCounts)
+	} else {
+		chunkWidth, chunkHeight = width, min(height, int(t.value(tiffRowsPerStrip, float64(height))))
+		offsets, counts = t.values(tiffStripOffsets), t.values(tiffStripByteCounts)
+	}
+	if chunkWidth <= 0 || chunkHeight <= 0 {
+		return nil, fmt.Errorf("invalid TIFF chunk size %vx%v", chunkWidth, chunkHeight)
+	}
+	across, down := (width+chunkWidth-1)/chunkWidth, (height+chunkHeight-1)/chunkHeight
+	if len(offsets) < across*down || len(counts) < across*down {
+		return nil, fmt.Errorf("invalid TIFF data: missing strips or tiles")
+	}
+
+	tile.Data = make([]float32, width*height)
+	rowSize := chunkWidth * size
+	for k := 0; k < across*down; k++ {
+		x0, y0 := (k%across)*chunkWidth, (k/across)*chunkHeight
+		rows := chunkHeight
+		if _, ok := t.fields[tiffTileOffsets]; !ok {
+			rows = min(chunkHeight, height-y0) // the last strip may be shorter
+		}
+		b, err := t.read(int64(offsets[k]), int(counts[k]))
+		if err != nil {
+			return nil, err
+		}
+		if compression != 1 {
+			zr, err := newZlibReader(bytes.NewReader(b))
+			if err != nil {
+				return nil, fmt.Errorf("invalid TIFF data: %w", err)
+			}
+			b = make([]byte, rows*rowSize)
+			_, err = io.ReadFull(zr, b)
+			zr.Close()
+			if err != nil {
+				return nil, fmt.Errorf("invalid TIFF data: %w", err)
+			}
+		} else if len(b) < rows*rowSize {
+			return nil, fmt.Errorf("invalid TIFF data: strip or tile too short")
+		}
+
+		tmp := make([]byte, rowSize)
+		for j := 0; j < rows; j++ {
+			row := b[j*rowSize : (j+1)*rowSize]
+			switch predictor {
+			case 2:
+				for i := size; i < rowSize; i += size {
+					switch size {
+					case 1:
+						row[i] += row[i-1]
+					case 2:
+						t.order.PutUint16(row[i:], t.order.Uint16(row[i:])+t.order.Uint16(row[i-2:]))
+					case 4:
+						t.order.PutUint32(row[i:], t.order.Uint32(row[i:])+t.order.Uint32(row[i-4:]))
+					}
+				}
+			case 3:
+				for i := 1; i < rowSize; i++ {
+					row[i] += row[i-1]
+				}
+				for i := 0; i < chunkWidth; i++ {
+					for l := 0; l < size; l++ {
+						tmp[i*size+l] = row[l*chunkWidth+i]
+					}
+				}
+				copy(row, tmp)
+			}
+			if height <= y0+j {
+				continue
+			}
+			for i := 0; i < chunkWidth && x0+i < width; i++ {
+				v := sample(row[i*size:])
+				if v == noData || math.IsNaN(v) {
+					tile.Data[(y0+j)*width+x0+i] = float32(math.NaN())
+				} else {
+					tile.Data[(y0+j)*width+x0+i] = float32(v)
+				}
+			}
+		}
+	}
+	return tile, nil
+}
+
+type demFile struct {
+	filename string
+	bounds   Bounds
+	once     sync.Once
+	tile     *DEMTile
+	err      error
+}
+
+func (f *demFile) load() (*DEMTile, error) {
+	f.once.Do(func() {
+		var r *os.File
+		if r, f.err = os.Open(f.filename); f.err != nil {
+			return
+		}
+		defer r.Close()
+		if strings.EqualFold(filepath.Ext(f.filename), ".hgt") {
+			f.tile, f.err = ReadHGT(r, f.filename)
+		} else {
+			f.tile, f.err = ReadGeoTIFF(r)
+		}
+		if f.err != nil {
+			f.err = fmt.Errorf("%v: %w", f.filename, f.err)
+		}
+	})
+	return f.tile, f.err
+}
+
+// DEM is a digital elevation model consisting of tiles, which are loaded from disk when first needed and kept in memory. It is safe for concurrent use.
+type DEM struct {
+	files []*demFile
+}
+
+// NewDEM returns a digital elevation model of tiles in memory.
+func NewDEM(tiles ...*DEMTile) *DEM {
+	dem := &DEM{}
+	for _, tile := range tiles {
+		f := &demFile{bounds: tile.Bounds()}
+		f.once.Do(func() { f.tile = tile })
+		dem.files = append(dem.files, f)
+	}
+	return dem
+}
+
+// OpenDEM returns a digital elevation model of the SRTM .hgt tiles and GeoTIFF .tif files in a directory. Only the headers of GeoTIFF files are read to determine their bounds, and the elevations are loaded when first needed.
+func OpenDEM(dir string) (*DEM, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	dem := &DEM{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		filename := filepath.Join(dir, entry.Name())
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".hgt":
+			sw, err := hgtSouthWest(filename)
+			if err != nil {
+				return nil, err
+			}
+			// include half a pixel of 3 arc second tiles around the tile
+			d := 1.0 / 2400.0
+			dem.files = append(dem.files, &demFile{filename: filename, bounds: Bounds{{sw.X - d, sw.Y - d}, {sw.X + 1.0 + d, sw.Y + 1.0 + d}}})
+		case ".tif", ".tiff":
+			r, err := os.Open(filename)
+			if err != nil {
+				return nil, err
+			}
+			tile, err := readGeoTIFF(r, false)
+			r.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", filename, err)
+			}
+			dem.files = append(dem.files, &demFile{filename: filename, bounds: tile.Bounds()})
+		}
+	}
+	return dem, nil
+}
+
+// Bounds returns the area covered by the tiles.
+func (d *DEM) Bounds() Bounds {
+	bounds := Bounds{{math.Inf(1), math.Inf(1)}, {math.Inf(-1), math.Inf(-1)}}
+	for _, f := range d.files {
+		bounds = bounds.union(f.bounds)
+	}
+	return bounds
+}
+
+// Elevation returns the elevation in meters at c from the first tile that has data, see DEMTile.Elevation. It returns NaN if there is no data, or an error if a tile cannot be loaded.
+func (d *DEM) Elevation(c Coord) (float64, error) {
+	for _, f := range d.files {
+		if f.bounds.Contains(c) {
+			tile, err := f.load()
+			if err != nil {
+				return math.NaN(), err
+			} else if z := tile.Elevation(c); !math.IsNaN(z) {
+				return z, nil
+			}
+		}
+	}
+	return math.NaN(), nil
+}
+
+// Grid returns a tile of the elevations within the bounds, sampled at pixel centres that are step degrees apart, such as 1/3600 for 1 arc second. This combines the data of multiple tiles, for example to compute contour lines of an area.
+func (d *DEM) Grid(bounds Bounds, step float64) (*DEMTile, error) {
+	if !(0.0 < step) {
+		return nil, fmt.Errorf("invalid step %v", step)
+	}
+	width, height := max(1, int(math.Ceil(bounds.W()/step))), max(1, int(math.Ceil(bounds.H()/step)))
+	tile := &DEMTile{
+		Origin: Coord{bounds[0].X + step/2.0, bounds[1].Y - step/2.0},
+		Step:   Coord{step, step},
+		Width:  width,
+		Height: height,
+		Data:   make([]float32, width*height),
+	}
+	for j := 0; j < height; j++ {
+		for i := 0; i < width; i++ {
+			z, err := d.Elevation(Coord{tile.Origin.X + float64(i)*step, tile.Origin.Y - float64(j)*step})
+			if err != nil {
+				return nil, err
+			}
+			tile.Data[j*width+i] = float32(z)
+		}
+	}
+	return tile, nil
+}
+
+// ElevationProfile is the elevation along line strings.
+type ElevationProfile struct {
+	Elevations [][]float64 // elevation in meters of each coordinate of each line string, NaN if unknown
+	Ascent     float64     // total ascent in meters
+	Descent    float64     // total descent in meters
+	Min, Max   float64     // minimum and maximum elevation in meters, NaN if unknown
+}
+
+// Profile returns the elevations at the coordinates of the line strings, and the total ascent and descent, and the minimum and maximum elevation from samples about every 30 meters along the line strings.
+func (d *DEM) Profile(lineStrings [][]Coord) (ElevationProfile, error) {
+	profile := ElevationProfile{
+		Elevations: make([][]float64, len(lineStrings)),
+		Min:        math.NaN(),
+		Max:        math.NaN(),
+	}
+	for k, lineString := range lineStrings {
+		prev := math.NaN()
+		add := func(z float64) {
+			if math.IsNaN(z) {
+				return
+			} else if !math.IsNaN(prev) {
+				if prev < z {
+					profile.Ascent += z - prev
+				} else {
+					profile.Descent += prev - z
+				}
+			}
+			if math.IsNaN(profile.Min) || z < profile.Min {
+				profile.Min = z
+			}
+			if math.IsNaN(profile.Max) || profile.Max < z {
+				profile.Max = z
+			}
+			prev = z
+		}
+
+		profile.Elevations[k] = make([]float64, len(lineString))
+		for i, c := range lineString {
+			if 0 < i {
+				a := lineString[i-1]
+				n := int(math.Ceil(a.Distance(c) / demSampleDistance))
+				for l := 1; l < n; l++ {
+					t := float64(l) / float64(n)
+					z, err := d.Elevation(Coord{a.X + t*(c.X-a.X), a.Y + t*(c.Y-a.Y)})
+					if err != nil {
+						return ElevationProfile{}, err
+					}
+					add(z)
+				}
+			}
+			z, err := d.Elevation(c)
+			if err != nil {
+				return ElevationProfile{}, err
+			}
+			profile.Elevations[k][i] = z
+			add(z)
+		}
+	}
+	return profile, nil
+}
+
+// Annotate adds elevation tags to a geometry. Geometries with a single point get an ele tag, and geometries with line strings get ascent and descent tags in whole meters, replacing existing tags. It returns the elevation profile of the line strings.
+func (d *DEM) Annotate(geom *Geometry) (ElevationProfile, error) {
+	setTag := func(key string, val float64) {
+		tag := Tag{key, strconv.FormatFloat(math.Round(val), 'f', -1, 64)}
+		for i := range geom.Tags {
+			if geom.Tags[i].Key == key {
+				geom.Tags[i] = tag
+				return
+			}
+		}
+		geom.Tags = append(geom.Tags, tag)
+	}
+
+	if len(geom.Points) == 1 && len(geom.LineStrings) == 0 && len(geom.Polygons) == 0 {
+		z, err := d.Elevation(geom.Points[0])
+		if err != nil {
+			return ElevationProfile{}, err
+		} else if !math.IsNaN(z) {
+			setTag("ele", z)
+		}
+	}
+	profile, err := d.Profile(geom.LineStrings)
+	if err != nil {
+		return ElevationProfile{}, err
+	} else if !math.IsNaN(profile.Min) {
+		setTag("ascent", profile.Ascent)
+		setTag("descent", profile.Descent)
+	}
+	return profile, nil
+}
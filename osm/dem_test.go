@@ -0,0 +1,354 @@
+package osm
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeTestHGT(values []int16) []byte {
+	b := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(b[2*i:], uint16(v))
+	}
+	return b
+}
+
+type testGeoTIFF struct {
+	order         binary.ByteOrder
+	width, height int
+	format, bits  int
+	compression   int
+	predictor     int
+	tileSize      int // strips of one row if zero
+	values        []float64
+	noData        string
+}
+
+func (tiff testGeoTIFF) bytes(t *testing.T) []byte {
+	t.Helper()
+	size := tiff.bits / 8
+	chunkWidth, chunkHeight := tiff.width, 1
+	if tiff.tileSize != 0 {
+		chunkWidth, chunkHeight = tiff.tileSize, tiff.tileSize
+	}
+	across, down := (tiff.width+chunkWidth-1)/chunkWidth, (tiff.height+chunkHeight-1)/chunkHeight
+	putSample := func(b []byte, order binary.ByteOrder, v float64) {
+		switch {
+		case tiff.format == 3 && size == 4:
+			order.PutUint32(b, math.Float32bits(float32(v)))
+		case tiff.format == 3 && size == 8:
+			order.PutUint64(b, math.Float64bits(v))
+		case size == 2:
+			order.PutUint16(b, uint16(int16(v)))
+		case size == 4:
+			order.PutUint32(b, uint32(int32(v)))
+		default:
+			b[0] = byte(int8(v))
+		}
+	}
+
+	var chunks [][]byte
+	for k := 0; k < across*down; k++ {
+		x0, y0 := (k%across)*chunkWidth, (k/across)*chunkHeight
+		chunk := []byte{}
+		for j := 0; j < chunkHeight; j++ {
+			row := make([]byte, chunkWidth*size)
+			for i := 0; i < chunkWidth; i++ {
+				v := 0.0
+				if x0+i < tiff.width && y0+j < tiff.height {
+					v = tiff.values[(y0+j)*tiff.width+x0+i]
+				}
+				if tiff.predictor == 3 {
+					putSample(row[i*size:], binary.BigEndian, v)
+				} else {
+					putSample(row[i*size:], tiff.order, v)
+				}
+			}
+			switch tiff.predictor {
+			case 2:
+				for i := len(row) - size; 0 < i; i -= size {
+					if size == 2 {
+						tiff.order.PutUint16(row[i:], tiff.order.Uint16(row[i:])-tiff.order.Uint16(row[i-2:]))
+					} else {
+						t.Fatal("unsupported predictor in test")
+					}
+				}
+			case 3:
+				tmp := make([]byte, len(row))
+				for i := 0; i < chunkWidth; i++ {
+					for l := 0; l < size; l++ {
+						tmp[l*chunkWidth+i] = row[i*size+l]
+					}
+				}
+				for i := len(tmp) - 1; 0 < i; i-- {
+					tmp[i] -= tmp[i-1]
+				}
+				row = tmp
+			}
+			chunk = append(chunk, row...)
+		}
+		if tiff.compression == 8 || tiff.compression == 32946 {
+			var buf bytes.Buffer
+			zw := zlib.NewWriter(&buf)
+			zw.Write(chunk)
+			zw.Close()
+			chunk = buf.Bytes()
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	type field struct {
+		tag, typ uint16
+		count    int
+		data     []byte
+	}
+	var fields []field
+	shorts := func(tag uint16, vs ...int) {
+		b := make([]byte, 2*len(vs))
+		for i, v := range vs {
+			tiff.order.PutUint16(b[2*i:], uint16(v))
+		}
+		fields = append(fields, field{tag, 3, len(vs), b})
+	}
+	longs := func(tag uint16, vs ...int) {
+		b := make([]byte, 4*len(vs))
+		for i, v := range vs {
+			tiff.order.PutUint32(b[4*i:], uint32(v))
+		}
+		fields = append(fields, field{tag, 4, len(vs), b})
+	}
+	doubles := func(tag uint16, vs ...float64) {
+		b := make([]byte, 8*len(vs))
+		for i, v := range vs {
+			tiff.order.PutUint64(b[8*i:], math.Float64bits(v))
+		}
+		fields = append(fields, field{tag, 12, len(vs), b})
+	}
+
+	// grid of one by one degree from 6E 53N with pixels as areas
+	offset := 8
+	var offsets, counts []int
+	for _, chunk := range chunks {
+		offsets = append(offsets, offset)
+		counts = append(counts, len(chunk))
+		offset += len(chunk)
+	}
+	longs(tiffImageWidth, tiff.width)
+	longs(tiffImageLength, tiff.height)
+	shorts(tiffBitsPerSample, tiff.bits)
+	shorts(tiffCompression, tiff.compression)
+	shorts(tiffSamplesPerPixel, 1)
+	shorts(tiffPredictor, tiff.predictor)
+	shorts(tiffSampleFormat, tiff.format)
+	if tiff.tileSize != 0 {
+		longs(tiffTileWidth, tiff.tileSize)
+		longs(tiffTileLength, tiff.tileSize)
+		longs(tiffTileOffsets, offsets...)
+		longs(tiffTileByteCounts, counts...)
+	} else {
+		longs(tiffRowsPerStrip, 1)
+		longs(tiffStripOffsets, offsets...)
+		longs(tiffStripByteCounts, counts...)
+	}
+	doubles(tiffModelPixelScale, 1.0/float64(tiff.width), 1.0/float64(tiff.height), 0.0)
+	doubles(tiffModelTiepoint, 0.0, 0.0, 0.0, 6.0, 54.0, 0.0)
+	shorts(tiffGeoKeyDirectory, 1, 1, 0, 2, geoKeyModelType, 0, 1, 2, geoKeyRasterType, 0, 1, 1)
+	if tiff.noData != "" {
+		fields = append(fields, field{tiffGDALNoData, 2, len(tiff.noData) + 1, append([]byte(tiff.noData), 0)})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].tag < fields[j].tag })
+
+	b := make([]byte, 8)
+	if tiff.order == binary.LittleEndian {
+		copy(b, "II")
+	} else {
+		copy(b, "MM")
+	}
+	tiff.order.PutUint16(b[2:], 42)
+	for _, chunk := range chunks {
+		b = append(b, chunk...)
+	}
+	extra := []byte{}
+	ifdOffset := offset
+	extraOffset := ifdOffset + 2 + 12*len(fields) + 4
+	ifd := make([]byte, 2, 2+12*len(fields)+4)
+	tiff.order.PutUint16(ifd, uint16(len(fields)))
+	for _, f := range fields {
+		entry := make([]byte, 12)
+		tiff.order.PutUint16(entry, f.tag)
+		tiff.order.PutUint16(entry[2:], f.typ)
+		tiff.order.PutUint32(entry[4:], uint32(f.count))
+		if len(f.data) <= 4 {
+			copy(entry[8:], f.data)
+		} else {
+			tiff.order.PutUint32(entry[8:], uint32(extraOffset+len(extra)))
+			extra = append(extra, f.data...)
+		}
+		ifd = append(ifd, entry...)
+	}
+	ifd = append(ifd, 0, 0, 0, 0)
+	tiff.order.PutUint32(b[4:], uint32(ifdOffset))
+	b = append(b, ifd...)
+	return append(b, extra...)
+}
+
+func TestReadHGT(t *testing.T) {
+	tile, err := ReadHGT(bytes.NewReader(writeTestHGT([]int16{
+		10, 20, 30,
+		40, 50, 60,
+		70, -32768, 90,
+	})), "data/n53e006.hgt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tile.Width != 3 || tile.Height != 3 || tile.Origin != (Coord{6.0, 54.0}) || tile.Step != (Coord{0.5, 0.5}) {
+		t.Errorf("wrong tile %v %v %vx%v", tile.Origin, tile.Step, tile.Width, tile.Height)
+	}
+	if bounds := tile.Bounds(); bounds != (Bounds{{5.75, 52.75}, {7.25, 54.25}}) {
+		t.Errorf("wrong bounds %v", bounds)
+	}
+
+	tests := []struct {
+		c Coord
+		z float64
+	}{
+		{Coord{6.0, 54.0}, 10.0},
+		{Coord{6.5, 53.5}, 50.0},
+		{Coord{6.25, 53.75}, 30.0},
+		{Coord{7.0, 53.75}, 45.0},
+		{Coord{7.1, 54.1}, 30.0},          // clamped to the edge
+		{Coord{6.5, 53.0}, 80.0},          // the void is ignored
+		{Coord{6.25, 53.25}, 160.0 / 3.0}, // the void is ignored
+	}
+	for _, tt := range tests {
+		if z := tile.Elevation(tt.c); 1e-6 < math.Abs(z-tt.z) {
+			t.Errorf("%v: expected %v, got %v", tt.c, tt.z, z)
+		}
+	}
+	if z := tile.Elevation(Coord{7.5, 53.5}); !math.IsNaN(z) {
+		t.Errorf("expected NaN outside the tile, got %v", z)
+	}
+
+	if _, err := ReadHGT(bytes.NewReader(make([]byte, 10)), "N53E006.hgt"); err == nil {
+		t.Errorf("expected error for invalid size")
+	}
+	if _, err := ReadHGT(bytes.NewReader(make([]byte, 18)), "tile.hgt"); err == nil {
+		t.Errorf("expected error for invalid filename")
+	}
+	if sw, err := hgtSouthWest("S12W077.hgt"); err != nil || sw != (Coord{-77.0, -12.0}) {
+		t.Errorf("wrong south-west corner %v: %v", sw, err)
+	}
+}
+
+func TestReadGeoTIFF(t *testing.T) {
+	values := []float64{
+		1, 2, 3, 4, 5,
+		6, 7, 8, 9, 10,
+		11, 12, -9999, 14, 15,
+		16, 17, 18, 19, 20,
+	}
+	tests := []testGeoTIFF{
+		{order: binary.LittleEndian, format: 2, bits: 16, compression: 1, predictor: 1},
+		{order: binary.BigEndian, format: 2, bits: 16, compression: 8, predictor: 2},
+		{order: binary.LittleEndian, format: 3, bits: 32, compression: 8, predictor: 3, tileSize: 16},
+		{order: binary.BigEndian, format: 3, bits: 64, compression: 1, predictor: 1, tileSize: 16},
+		{order: binary.LittleEndian, format: 2, bits: 32, compression: 32946, predictor: 1, tileSize: 3},
+	}
+	for k, tt := range tests {
+		tt.width, tt.height, tt.values, tt.noData = 5, 4, values, "-9999"
+		tile, err := ReadGeoTIFF(bytes.NewReader(tt.bytes(t)))
+		if err != nil {
+			t.Errorf("%v: %v", k, err)
+			continue
+		}
+		if tile.Width != 5 || tile.Height != 4 || 1e-9 < math.Abs(tile.Origin.X-6.1) || 1e-9 < math.Abs(tile.Origin.Y-53.875) {
+			t.Errorf("%v: wrong tile %v %v %vx%v", k, tile.Origin, tile.Step, tile.Width, tile.Height)
+		}
+		for i, v := range values {
+			if z := tile.Data[i]; v == -9999 && !math.IsNaN(float64(z)) || v != -9999 && float64(z) != v {
+				t.Errorf("%v: wrong value %v at %v, expected %v", k, z, i, v)
+				break
+			}
+		}
+		if z := tile.Elevation(Coord{6.2, 53.75}); 1e-6 < math.Abs(z-4.0) {
+			t.Errorf("%v: expected 4, got %v", k, z)
+		}
+	}
+
+	b := testGeoTIFF{order: binary.LittleEndian, width: 2, height: 2, format: 1, bits: 16, compression: 5, predictor: 1, values: []float64{1, 2, 3, 4}}.bytes(t)
+	if _, err := ReadGeoTIFF(bytes.NewReader(b)); err == nil || !strings.Contains(err.Error(), "compression") {
+		t.Errorf("expected error for unsupported compression, got %v", err)
+	}
+	if _, err := ReadGeoTIFF(bytes.NewReader([]byte("not a tiff"))); err == nil {
+		t.Errorf("expected error for invalid header")
+	}
+}
+
+func TestDEM(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "N53E006.hgt"), writeTestHGT([]int16{
+		100, 100, 100,
+		0, 0, 0,
+		0, 0, 0,
+	}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "other.tif"), testGeoTIFF{
+		order: binary.LittleEndian, width: 2, height: 2, format: 2, bits: 16, compression: 8, predictor: 2, values: []float64{10, 20, 30, 40},
+	}.bytes(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("tiles"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dem, err := OpenDEM(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(dem.files) != 2 {
+		t.Fatalf("expected two files, got %v", len(dem.files))
+	}
+	if z, err := dem.Elevation(Coord{6.5, 53.75}); err != nil || z != 50.0 {
+		t.Errorf("expected 50, got %v: %v", z, err)
+	}
+	if z, err := dem.Elevation(Coord{20.0, 20.0}); err != nil || !math.IsNaN(z) {
+		t.Errorf("expected NaN, got %v: %v", z, err)
+	}
+
+	// down the slope and back up, about 28 km
+	geom := Geometry{
+		LineStrings: [][]Coord{{{6.5, 53.5}, {6.5, 54.0}, {6.5, 53.75}}},
+		Tags:        Tags{{"route", "hiking"}, {"ascent", "1"}},
+	}
+	profile, err := dem.Annotate(&geom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profile.Elevations) != 1 || len(profile.Elevations[0]) != 3 || profile.Elevations[0][0] != 0.0 || profile.Elevations[0][1] != 100.0 || profile.Elevations[0][2] != 50.0 {
+		t.Errorf("wrong elevations %v", profile.Elevations)
+	}
+	if 1e-6 < math.Abs(profile.Ascent-100.0) || 1e-6 < math.Abs(profile.Descent-50.0) || profile.Min != 0.0 || profile.Max != 100.0 {
+		t.Errorf("wrong profile %v", profile)
+	}
+	if geom.Tags.Find("ascent") != "100" || geom.Tags.Find("descent") != "50" || len(geom.Tags) != 3 {
+		t.Errorf("wrong tags %v", geom.Tags)
+	}
+
+	point := Geometry{Points: []Coord{{6.5, 53.75}}}
+	if _, err := dem.Annotate(&point); err != nil || point.Tags.Find("ele") != "50" {
+		t.Errorf("wrong tags %v: %v", point.Tags, err)
+	}
+
+	// in-memory tiles
+	dem = NewDEM(&DEMTile{Origin: Coord{0.0, 1.0}, Step: Coord{1.0, 1.0}, Width: 2, Height: 2, Data: []float32{1, 2, 3, 4}})
+	if z, err := dem.Elevation(Coord{0.5, 0.5}); err != nil || z != 2.5 {
+		t.Errorf("expected 2.5, got %v: %v", z, err)
+	}
+}
@@ -1,9 +1,10 @@
 package osm
 
 import (
+	"cmp"
 	"context"
-	"fmt"
 	"slices"
+	"strings"
 	"sync"
 )
 
@@ -13,83 +14,244 @@ type Class uint32
 // FilterFunc returns the class of the object, where returning zero will skip the object.
 type FilterFunc func(Type, uint64, Tags) Class
 
-type Polygon struct {
-	Coords []Coord
-	Fill   bool
-}
-
+// Geometry is a resolved object consisting of points, line strings, and polygons.
 type Geometry struct {
 	Type        Type
 	ID          uint64
 	Points      []Coord
 	LineStrings [][]Coord
-	Polygons    []Polygon
+	Polygons    []PolygonWithHoles
 	Tags        Tags
 }
 
 type wayNode struct {
-	Coord   Coord
-	Class   Class
-	Outcode uint8
+	Coord Coord
+	Class Class
 }
 
 type relationWay struct {
+	ID          uint64
 	Coords      []Coord
 	First, Last uint64 // IDs of first and last node
 }
 
-// Extract extracts a subset of the data that is within the bounds. If filter is not nil, it will also filter based on object types, IDs, or tags. It will parse and resolve all selected geometries and categorise by class. This function is optimised to limit peak memory usage but requires parsing the file three times (or five if filter is set).
+// ExtractOptions are options for ExtractFunc and ExtractManyFunc.
+type ExtractOptions struct {
+	// Ordered passes geometries ordered by type (nodes, ways, then relations) and by ID. This requires keeping all geometries of one type in memory before passing them on.
+	Ordered bool
+
+	// ProblemFunc is called for each problem encountered, such as unclosed rings, missing members or nodes, degenerate polygons, or relations nested too deep. It is not called concurrently.
+	ProblemFunc ProblemFunc
+
+	// AreaRules decides whether closed ways are areas, DefaultAreaRules is used if nil.
+	AreaRules AreaRules
+}
+
+// GeometryFunc is called for each extracted geometry with its class.
+type GeometryFunc func(Class, Geometry)
+
+// RegionGeometryFunc is called for each extracted geometry with the index of its region and its class.
+type RegionGeometryFunc func(int, Class, Geometry)
+
+// Extract extracts a subset of the data that is within the region, which is either a Bounds or a PolygonRegion. If filter is not nil, it will also filter based on object types, IDs, or tags. It will parse and resolve all selected geometries and categorise by class. It parses the file as often as ExtractFunc does, see ExtractFunc for more details.
 // - There is no guarantee of order between geometries.
-// - Nodes within or on bounds and ways that pass through the bounds are matched. Relations contain the members that matched.
-// - Multiple ways in a relation are joined by their endpoints (referencing same nodes). The result is either a line string (open) or a polygon (closed). A relation may have multiple sets of ways with no matching endpoints.
-// - Line strings and polygons are clipped to the bounds and any superfluous nodes are removed. Care is taken to maintain direction and closedness.
+// - Problems are ignored, use ExtractFunc with ProblemFunc to collect them.
+func (z *Parser) Extract(ctx context.Context, region Region, filter FilterFunc) (map[Class][]Geometry, error) {
+	geometries := map[Class][]Geometry{}
+	fn := func(class Class, geom Geometry) {
+		geometries[class] = append(geometries[class], geom)
+	}
+	if err := z.ExtractFunc(ctx, region, filter, nil, fn); err != nil {
+		return nil, err
+	}
+	return geometries, nil
+}
+
+// ExtractFunc extracts a subset of the data that is within the region, which is either a Bounds or a PolygonRegion. If filter is not nil, it will also filter based on object types, IDs, or tags. It will parse and resolve all selected geometries and pass each to fn as soon as it is complete, so that the result does not need to fit in memory. The function fn is not called concurrently and may keep the geometry. This function is optimised to limit peak memory usage but requires parsing the file three times (five if filter is set, six if there are also child relations).
+// - Nodes are passed first, then ways, then relations. Within each type there is no guarantee of order, unless opts.Ordered is set in which case they are ordered by ID.
+// - Nodes within or on the region and ways that pass through the region are matched. Relations contain the members that matched. For polygon regions, nodes are first checked against the bounding box.
+// - Members of child relations are added to their parent relations recursively, with cycle detection and up to MaxRelationDepth levels deep.
+// - Multiple ways in a relation are joined by their endpoints (referencing same nodes). A relation may have multiple sets of ways with no matching endpoints.
+// - Multipolygon and boundary relations, or relations with outer or inner members, are assembled into polygons with holes. The nesting of rings is decided by geometry rather than by role. Ways that cannot be closed are returned as line strings. Other relations return line strings only.
+// - Line strings and polygons are clipped to the region and any superfluous nodes are removed. Care is taken to maintain direction and closedness. For polygon regions, line strings may be split into multiple parts and polygons may be split into multiple polygons.
+// - Line strings and polygons that cross the antimeridian are split into parts on either side. Wrapped bounds, where the minimum longitude is larger than the maximum longitude, select both sides of the antimeridian.
+// - Closed ways are areas if opts.AreaRules (or DefaultAreaRules) decides so, and are returned as polygons.
 // - Filled polygons are CCW oriented and holes are CW oriented.
-func (z *Parser) Extract(ctx context.Context, bounds Bounds, filter FilterFunc) (map[Class][]Geometry, error) {
+func (z *Parser) ExtractFunc(ctx context.Context, region Region, filter FilterFunc, opts *ExtractOptions, fn GeometryFunc) error {
+	return z.ExtractManyFunc(ctx, []Region{region}, filter, opts, func(_ int, class Class, geom Geometry) {
+		fn(class, geom)
+	})
+}
+
+// ExtractMany extracts a subset of the data for each region, which is either a Bounds or a PolygonRegion, and returns a result per region in the same order. It parses the file as often as ExtractFunc does, independent of the number of regions. See ExtractFunc for more details.
+func (z *Parser) ExtractMany(ctx context.Context, regions []Region, filter FilterFunc) ([]map[Class][]Geometry, error) {
+	geometries := make([]map[Class][]Geometry, len(regions))
+	for i := range geometries {
+		geometries[i] = map[Class][]Geometry{}
+	}
+	fn := func(i int, class Class, geom Geometry) {
+		geometries[i][class] = append(geometries[i][class], geom)
+	}
+	if err := z.ExtractManyFunc(ctx, regions, filter, nil, fn); err != nil {
+		return nil, err
+	}
+	return geometries, nil
+}
+
+// ExtractManyFunc is like ExtractFunc but extracts multiple regions while sharing the passes over the file. Each geometry is passed to fn with the index of its region, and a geometry that is within multiple regions is passed once for each region, clipped to that region. A grid index over the regions is used to find the regions of each object. Problems are reported once, irrespective of the number of regions.
+func (z *Parser) ExtractManyFunc(ctx context.Context, regions []Region, filter FilterFunc, opts *ExtractOptions, fn RegionGeometryFunc) error {
+	if opts == nil {
+		opts = &ExtractOptions{}
+	}
+	areaRules := opts.AreaRules
+	if areaRules == nil {
+		areaRules = DefaultAreaRules
+	}
+	if len(regions) == 0 {
+		return nil
+	}
+	index := newRegionIndex(regions)
+	extent := index.extent
 	var mu1, mu2, mu3 sync.RWMutex
 
+	// pass geometries to fn one at a time, or keep them until all geometries of the same type have been extracted
+	var muFn sync.Mutex
+	var pending []classGeometry
+	emit := func(region int, class Class, geom Geometry) {
+		muFn.Lock()
+		if opts.Ordered {
+			pending = append(pending, classGeometry{region, class, geom})
+		} else {
+			fn(region, class, geom)
+		}
+		muFn.Unlock()
+	}
+	report := func(problem Problem) {
+		if opts.ProblemFunc != nil {
+			problem.Role = strings.Clone(problem.Role) // points into the parser's buffer
+			muFn.Lock()
+			opts.ProblemFunc(problem)
+			muFn.Unlock()
+		}
+	}
+	flush := func() {
+		slices.SortFunc(pending, func(a, b classGeometry) int {
+			if c := cmp.Compare(a.Geometry.ID, b.Geometry.ID); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Region, b.Region)
+		})
+		for _, item := range pending {
+			fn(item.Region, item.Class, item.Geometry)
+		}
+		pending = pending[:0]
+	}
+
 	selectedNodes := NewUint64Map(8, 0.6)     // matches filter
 	selectedWays := NewUint64Map(8, 0.6)      // matches filter
 	selectedRelations := NewUint64Map(8, 0.6) // matches filter
-	if filter != nil {
-		// add relation dependents and build dependent trees for super relations
-		relationFunc := func(relation Relation) {
-			if class := filter(RelationType, relation.ID, relation.Tags); class != 0 {
-				var nodes, ways []uint64
-				for _, member := range relation.Members {
-					if member.Type == WayType {
-						ways = append(ways, member.ID)
-					} else if member.Type == NodeType {
-						nodes = append(nodes, member.ID)
-					}
+
+	// add relation dependents and build dependent trees for super relations
+	relationChildren := map[uint64][]uint64{}
+	relationFunc := func(relation Relation) {
+		var class Class
+		if filter != nil {
+			class = filter(RelationType, relation.ID, relation.Tags)
+		}
+		var nodes, ways, relations []uint64
+		for _, member := range relation.Members {
+			if member.Type == WayType {
+				ways = append(ways, member.ID)
+			} else if member.Type == NodeType {
+				nodes = append(nodes, member.ID)
+			} else if member.Type == RelationType {
+				relations = append(relations, member.ID)
+			}
+		}
+		if 0 < len(relations) {
+			mu1.Lock()
+			relationChildren[relation.ID] = relations
+			mu1.Unlock()
+		}
+		if class != 0 {
+			if 0 < len(ways) || 0 < len(nodes) || 0 < len(relations) {
+				mu1.Lock()
+				selectedRelations.Put(relation.ID, uint64(class))
+				mu1.Unlock()
+			}
+			if 0 < len(ways) {
+				mu2.Lock()
+				for _, id := range ways {
+					selectedWays.Put(id, 0)
 				}
-				if 0 < len(ways) || 0 < len(nodes) {
-					mu1.Lock()
-					selectedRelations.Put(relation.ID, uint64(class))
-					mu1.Unlock()
+				mu2.Unlock()
+			}
+			if 0 < len(nodes) {
+				mu3.Lock()
+				for _, id := range nodes {
+					selectedNodes.Put(id, 0)
 				}
-				if 0 < len(ways) {
+				mu3.Unlock()
+			}
+		}
+	}
+
+	// find all descendants of selected super relations
+	childRelations := NewUint64Set(8, 0.6)
+	var addChildRelations func([]uint64, int)
+	addChildRelations = func(ids []uint64, depth int) {
+		if MaxRelationDepth <= depth {
+			return
+		}
+		for _, id := range ids {
+			if !childRelations.Has(id) {
+				childRelations.Add(id)
+				addChildRelations(relationChildren[id], depth+1)
+			}
+		}
+	}
+	findChildRelations := func() {
+		for id, children := range relationChildren {
+			if filter == nil || selectedRelations.Has(id) {
+				addChildRelations(children, 1)
+			}
+		}
+	}
+
+	// without filter no dependents are selected, so relations are parsed together with nodes
+	nodeRelationFunc := relationFunc
+	if filter != nil {
+		if err := z.Parse(ctx, nil, nil, relationFunc); err != nil {
+			return err
+		}
+		findChildRelations()
+		nodeRelationFunc = nil
+		if 0 < childRelations.Size() {
+			// add dependents of child relations
+			relationFunc := func(relation Relation) {
+				if childRelations.Has(relation.ID) {
 					mu2.Lock()
-					for _, id := range ways {
-						selectedWays.Put(id, 0)
+					for _, member := range relation.Members {
+						if member.Type == WayType {
+							selectedWays.Put(member.ID, 0)
+						} else if member.Type == NodeType {
+							selectedNodes.Put(member.ID, 0)
+						}
 					}
 					mu2.Unlock()
 				}
-				if 0 < len(nodes) {
-					mu3.Lock()
-					for _, id := range nodes {
-						selectedNodes.Put(id, 0)
-					}
-					mu3.Unlock()
-				}
 			}
-		}
-		if err := z.Parse(ctx, nil, nil, relationFunc); err != nil {
-			return nil, err
+			if err := z.Parse(ctx, nil, nil, relationFunc); err != nil {
+				return err
+			}
 		}
 
 		// add way dependents
 		wayFunc := func(way Way) {
-			if class := filter(WayType, way.ID, way.Tags); class != 0 || selectedWays.Has(way.ID) {
+			mu1.RLock()
+			dependent := selectedWays.Has(way.ID)
+			mu1.RUnlock()
+			if class := filter(WayType, way.ID, way.Tags); class != 0 || dependent {
 				if class != 0 {
 					// overwrite class if selected by relation
 					mu1.Lock()
@@ -106,43 +268,46 @@ func (z *Parser) Extract(ctx context.Context, bounds Bounds, filter FilterFunc)
 			}
 		}
 		if err := z.Parse(ctx, nil, wayFunc, nil); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	geometries := map[Class][]Geometry{}
-
 	nodes := map[uint64]wayNode{}
 	nodeFunc := func(node Node) {
 		var class Class
 		if filter != nil {
 			class = filter(NodeType, node.ID, node.Tags)
 		}
-		outcode := cohenSutherlandOutcode(bounds, Coord{node.Lon, node.Lat})
+		coord := Coord{node.Lon, node.Lat}
 
 		mu1.Lock()
 		if filter == nil || selectedNodes.Has(node.ID) {
 			nodes[node.ID] = wayNode{
-				Coord:   Coord{node.Lon, node.Lat},
-				Class:   class,
-				Outcode: outcode,
+				Coord: coord,
+				Class: class,
 			}
 		}
 		mu1.Unlock()
-		if (filter == nil || class != 0) && outcode == 0b0000 {
-			mu2.Lock()
-			geometries[class] = append(geometries[class], Geometry{
-				Type:   NodeType,
-				ID:     node.ID,
-				Points: []Coord{{node.Lon, node.Lat}},
-				Tags:   node.Tags.Clone(),
+		if filter == nil || class != 0 {
+			index.query(Bounds{coord, coord}, func(i int) {
+				if cohenSutherlandOutcode(index.bounds[i], coord) == 0b0000 && index.regions[i].Contains(coord) {
+					emit(i, class, Geometry{
+						Type:   NodeType,
+						ID:     node.ID,
+						Points: []Coord{coord},
+						Tags:   node.Tags.Clone(),
+					})
+				}
 			})
-			mu2.Unlock()
 		}
 	}
-	if err := z.Parse(ctx, nodeFunc, nil, nil); err != nil {
-		return nil, err
+	if err := z.Parse(ctx, nodeFunc, nil, nodeRelationFunc); err != nil {
+		return err
 	}
+	if filter == nil {
+		findChildRelations()
+	}
+	flush()
 	selectedNodes = nil
 
 	ways := map[uint64]relationWay{}
@@ -159,85 +324,61 @@ func (z *Parser) Extract(ctx context.Context, bounds Bounds, filter FilterFunc)
 			}
 			if selected && 0 < len(way.Refs) {
 				// filtered (class!=0) or dependent (class=0)
-				// optimise way: remove superfluous nodes outside of the bounds
-				var coords []Coord
-				var prevCoord Coord
-				prevOutcode := uint8(0b1111)
+				raw := make([]Coord, 0, len(way.Refs))
 				for _, ref := range way.Refs {
 					if node, ok := nodes[ref]; ok {
 						// node exists
-						coord, outcode := node.Coord, node.Outcode
-						if outcode == 0b0000 {
-							if prevOutcode != 0b0000 && prevOutcode != 0b1111 {
-								// cross bounds to inside
-								coords = append(coords, clipCoord(bounds, coord, prevCoord, prevOutcode))
-							}
-							coords = append(coords, coord)
-						} else if prevOutcode == 0b0000 {
-							// cross bounds to outside
-							coords = append(coords, clipCoord(bounds, prevCoord, coord, outcode))
-						} else if outcode != prevOutcode {
-							// both are outside but in different outcode regions
-							// put coordinates at the corners of the bounds to avoid crossing the inner region
-							corner := true
-							if outcode == 0b0101 {
-								// left bottom
-								coord = bounds[0]
-							} else if outcode == 0b1001 {
-								// left top
-								coord = Coord{bounds[0].X, bounds[1].Y}
-							} else if outcode == 0b0110 {
-								// right bottom
-								coord = Coord{bounds[1].X, bounds[0].Y}
-							} else if outcode == 0b1010 {
-								// right top
-								coord = bounds[1]
-							} else {
-								corner = false
-							}
-							if corner && (len(coords) == 0 || coords[len(coords)-1] != coord) {
-								if 1 < len(coords) && coords[len(coords)-2] == coord {
-									// optimise and avoid overlapping segments
-									coords = coords[:len(coords)-1]
-								} else {
-									coords = append(coords, coord)
-								}
-							}
+						raw = append(raw, node.Coord)
+					} else {
+						var coord Coord
+						if 0 < len(raw) {
+							coord = raw[len(raw)-1]
 						}
-						prevOutcode = outcode
-						prevCoord = coord
+						report(Problem{Type: MissingNode, ObjectType: WayType, ID: way.ID, Ref: ref, Coord: coord})
 					}
 				}
-
-				closed := way.Refs[0] == way.Refs[len(way.Refs)-1]
-				if (closed && len(coords) < 3) || (!closed && len(coords) < 2) {
-					coords = nil
-				} else if closed && coords[0] != coords[len(coords)-1] {
-					coords = append(coords, coords[0])
+				if len(raw) == 0 {
+					return
 				}
 
-				if (filter == nil || class != 0) && coords != nil {
+				// optimise way: remove superfluous nodes outside of the bounds, ways crossing the antimeridian are clipped after splitting
+				coords := raw
+				crosses := crossesAntimeridian(raw)
+				if !crosses {
+					coords = clipBounds(extent, raw)
+				}
+				closed := way.Refs[0] == way.Refs[len(way.Refs)-1]
+				if (filter == nil || class != 0) && (closed && 2 < len(coords) || !closed && 1 < len(coords)) {
 					// is (partially) inside or surrounds bounds
-					geom := Geometry{
-						Type: WayType,
-						ID:   way.ID,
-						Tags: way.Tags.Clone(),
-					}
-					if closed && way.Tags.IsArea() {
-						if !isCCW(coords) {
-							reverseOrientation(coords)
-						}
-						geom.Polygons = []Polygon{{coords, true}}
+					isArea := closed && areaRules.IsArea(way.Tags)
+					if isArea && degenerateRing(raw) {
+						report(Problem{Type: DegeneratePolygon, ObjectType: WayType, ID: way.ID, Coord: raw[0]})
 					} else {
-						geom.LineStrings = [][]Coord{coords}
+						index.query(ringBounds(raw), func(i int) {
+							geom := Geometry{
+								Type: WayType,
+								ID:   way.ID,
+							}
+							geom.LineStrings, geom.Polygons = wayGeometry(index.bounds[i], index.regions[i], raw, closed, isArea)
+							if 0 < len(geom.LineStrings) || 0 < len(geom.Polygons) {
+								geom.Tags = way.Tags.Clone()
+								emit(i, class, geom)
+							}
+						})
 					}
-					mu2.Lock()
-					geometries[class] = append(geometries[class], geom)
-					mu2.Unlock()
+				}
+
+				// keep endpoints outside of the bounds so that ways can be joined exactly
+				if first := raw[0]; cohenSutherlandOutcode(extent, first) != 0b0000 && (len(coords) == 0 || coords[0] != first) {
+					coords = append([]Coord{first}, coords...)
+				}
+				if last := raw[len(raw)-1]; cohenSutherlandOutcode(extent, last) != 0b0000 && coords[len(coords)-1] != last {
+					coords = append(coords, last)
 				}
 
 				mu3.Lock()
 				ways[way.ID] = relationWay{
+					ID:     way.ID,
 					Coords: coords,
 					First:  way.Refs[0],
 					Last:   way.Refs[len(way.Refs)-1],
@@ -246,12 +387,33 @@ func (z *Parser) Extract(ctx context.Context, bounds Bounds, filter FilterFunc)
 			}
 		}
 		if err := z.Parse(ctx, nil, wayFunc, nil); err != nil {
-			return nil, err
+			return err
 		}
 	}
+	flush()
 	selectedWays = nil
 
+	// assemble a relation once and clip it to each region
+	emitRelation := func(id uint64, class Class, tags Tags, members relationMembers) {
+		polygons, lines := assembleRelation(extent, id, tags, members.Ways, report)
+		b := ringBounds(members.Points)
+		for _, polygon := range polygons {
+			b = b.union(ringBounds(polygon.Outer))
+		}
+		for _, line := range lines {
+			b = b.union(ringBounds(line))
+		}
+		index.query(b, func(i int) {
+			if geom, ok := relationGeometry(index.bounds[i], index.regions[i], id, members.Points, polygons, lines); ok {
+				geom.Tags = tags.Clone()
+				emit(i, class, geom)
+			}
+		})
+	}
+
 	if filter == nil || 0 < selectedRelations.Size() {
+		childMembers := map[uint64]relationMembers{}
+		superRelations := []superRelation{}
 		relationFunc := func(relation Relation) {
 			var class Class
 			if filter != nil {
@@ -260,73 +422,248 @@ func (z *Parser) Extract(ctx context.Context, bounds Bounds, filter FilterFunc)
 				mu1.RUnlock()
 				class = Class(relationItem)
 			}
-			if (filter == nil || class != 0) && 0 < len(relation.Members) {
-				geom := Geometry{
-					Type: RelationType,
-					ID:   relation.ID,
-					Tags: relation.Tags, // cloned later
-				}
-
-				relationWayRoles := map[string][]relationWay{}
+			isChild := childRelations.Has(relation.ID)
+			if (filter == nil || class != 0 || isChild) && 0 < len(relation.Members) {
+				members := relationMembers{}
 				for _, member := range relation.Members {
 					if member.Type == WayType {
 						if way, ok := ways[member.ID]; ok {
-							relationWayRoles[member.Role] = append(relationWayRoles[member.Role], way)
+							members.Ways = append(members.Ways, memberWay{way, member.Role})
+						} else {
+							report(Problem{Type: MissingWay, ObjectType: RelationType, ID: relation.ID, Role: member.Role, Relation: relation.ID, Ref: member.ID})
 						}
 					} else if member.Type == NodeType {
-						if node, ok := nodes[member.ID]; ok && node.Outcode == 0b0000 {
-							geom.Points = append(geom.Points, node.Coord)
+						if node, ok := nodes[member.ID]; !ok {
+							report(Problem{Type: MissingNode, ObjectType: RelationType, ID: relation.ID, Role: member.Role, Relation: relation.ID, Ref: member.ID})
+						} else {
+							members.Points = append(members.Points, node.Coord)
 						}
+					} else if member.Type == RelationType {
+						members.Relations = append(members.Relations, member.ID)
 					}
 				}
 
-				for role, relationWays := range relationWayRoles {
-					contours := connectRelationWays(relationWays)
-					for _, coords := range contours {
-						if 1 < len(coords) {
-							if 2 < len(coords) && coords[0] == coords[len(coords)-1] {
-								// closed
-								fill := role != "inner"
-								if fill != isCCW(coords) {
-									coords = reverseOrientation(coords)
-								}
-								geom.Polygons = append(geom.Polygons, Polygon{
-									Coords: coords,
-									Fill:   fill,
-								})
-							} else {
-								// open
-								if role == "outer" || role == "inner" {
-									fmt.Printf("WARNING: could not close %v ways in relation %v\n", role, relation.ID)
-								}
-								geom.LineStrings = append(geom.LineStrings, coords)
-							}
-						}
+				if isChild {
+					// keep members for parent relations
+					mu3.Lock()
+					childMembers[relation.ID] = members.clone()
+					mu3.Unlock()
+				}
+
+				if filter == nil || class != 0 {
+					if 0 < len(members.Relations) {
+						// resolve super relations when all child relations have been parsed
+						mu3.Lock()
+						superRelations = append(superRelations, superRelation{
+							ID:      relation.ID,
+							Class:   class,
+							Tags:    relation.Tags.Clone(),
+							Members: members.clone(),
+						})
+						mu3.Unlock()
+					} else {
+						emitRelation(relation.ID, class, relation.Tags, members)
 					}
 				}
+			}
+		}
+		if err := z.Parse(ctx, nil, nil, relationFunc); err != nil {
+			return err
+		}
 
-				if 0 < len(geom.Points) || 0 < len(geom.LineStrings) || 0 < len(geom.Polygons) {
-					// sort polygons by filling first
-					slices.SortFunc(geom.Polygons, func(a, b Polygon) int {
-						if a.Fill == b.Fill {
-							return 0
-						} else if a.Fill {
-							return -1
-						}
-						return 1
-					})
+		// add members of child relations recursively
+		for _, relation := range superRelations {
+			members := relation.Members
+			members.Ways = slices.Clone(members.Ways)
+			visited := map[uint64]bool{relation.ID: true}
+			var addMembers func([]uint64, int)
+			addMembers = func(ids []uint64, depth int) {
+				if MaxRelationDepth <= depth {
+					if 0 < len(ids) {
+						report(Problem{Type: RecursionDepthExceeded, ObjectType: RelationType, ID: relation.ID, Relation: relation.ID, Ref: ids[0]})
+					}
+					return
+				}
+				for _, id := range ids {
+					if visited[id] {
+						continue // cycle or already added
+					}
+					visited[id] = true
+					if children, ok := childMembers[id]; ok {
+						members.Ways = append(members.Ways, children.Ways...)
+						members.Points = append(members.Points, children.Points...)
+						addMembers(children.Relations, depth+1)
+					}
+				}
+			}
+			addMembers(relation.Members.Relations, 1)
 
-					geom.Tags = geom.Tags.Clone()
+			emitRelation(relation.ID, relation.Class, relation.Tags, members)
+		}
+	}
+	flush()
+	return nil
+}
 
-					mu2.Lock()
-					geometries[class] = append(geometries[class], geom)
-					mu2.Unlock()
+// classGeometry is a geometry with its region index and class.
+type classGeometry struct {
+	Region int
+	Class
+	Geometry
+}
+
+// relationMembers are the resolved members of a relation.
+type relationMembers struct {
+	Ways      []memberWay
+	Points    []Coord
+	Relations []uint64
+}
+
+// clone returns a copy of the members that remains valid after the parser reuses its buffers.
+func (m relationMembers) clone() relationMembers {
+	m.Ways = slices.Clone(m.Ways)
+	for i := range m.Ways {
+		m.Ways[i].Role = strings.Clone(m.Ways[i].Role)
+	}
+	return m
+}
+
+// superRelation is a relation with relation members.
+type superRelation struct {
+	ID      uint64
+	Class   Class
+	Tags    Tags
+	Members relationMembers
+}
+
+// wayGeometry clips the coordinates of a way to the bounds and the region, and returns its line strings or polygons.
+func wayGeometry(bounds Bounds, region Region, raw []Coord, closed, isArea bool) ([][]Coord, []PolygonWithHoles) {
+	coords := raw
+	crosses := crossesAntimeridian(raw)
+	if !crosses {
+		coords = clipBounds(bounds, raw)
+	}
+	if closed && len(coords) < 3 || !closed && len(coords) < 2 {
+		return nil, nil
+	} else if isArea && crosses {
+		return nil, clipPolygon(bounds, region, PolygonWithHoles{Outer: closeRing(coords)})
+	} else if isArea {
+		polygon := closeRing(coords)
+		if !isCCW(polygon) {
+			polygon = reverseOrientation(polygon)
+		}
+		return nil, regionPolygons(region, [][]Coord{polygon})
+	}
+
+	if closed {
+		coords = closeRing(coords)
+	}
+	if crosses {
+		return clipLineString(bounds, region, coords), nil
+	}
+	return region.clipLineString(coords), nil
+}
+
+// assembleRelation assembles the member ways of a relation into polygons and line strings and reports any problems.
+func assembleRelation(bounds Bounds, id uint64, tags Tags, ways []memberWay, report ProblemFunc) ([]PolygonWithHoles, [][]Coord) {
+	isArea := tags.Find("type") == "multipolygon" || tags.Find("type") == "boundary"
+	for _, way := range ways {
+		if way.Role == "outer" || way.Role == "inner" {
+			isArea = true
+			break
+		}
+	}
+	if isArea {
+		polygons, lines, problems := assembleMultipolygon(ways, bounds)
+		for _, problem := range problems {
+			problem.Relation = id
+			report(problem)
+		}
+		return polygons, lines
+	}
+
+	var lines [][]Coord
+	for _, chain := range joinWays(ways) {
+		lines = append(lines, chain.Coords)
+	}
+	return nil, lines
+}
+
+// relationGeometry clips the points, polygons, and line strings of a relation to the bounds and the region. It returns false if the geometry is empty.
+func relationGeometry(bounds Bounds, region Region, id uint64, points []Coord, polygons []PolygonWithHoles, lines [][]Coord) (Geometry, bool) {
+	geom := Geometry{
+		Type: RelationType,
+		ID:   id,
+	}
+	for _, point := range points {
+		if cohenSutherlandOutcode(bounds, point) == 0b0000 && region.Contains(point) {
+			geom.Points = append(geom.Points, point)
+		}
+	}
+	for _, polygon := range polygons {
+		geom.Polygons = append(geom.Polygons, clipPolygon(bounds, region, polygon)...)
+	}
+	for _, line := range lines {
+		geom.LineStrings = append(geom.LineStrings, clipLineString(bounds, region, line)...)
+	}
+	return geom, 0 < len(geom.Points) || 0 < len(geom.LineStrings) || 0 < len(geom.Polygons)
+}
+
+// clipLineString clips a line string to the bounds and the region, after splitting it where it crosses the antimeridian.
+func clipLineString(bounds Bounds, region Region, coords []Coord) [][]Coord {
+	var lines [][]Coord
+	for _, line := range splitLineStringAntimeridian(coords) {
+		if line = clipBounds(bounds, line); 1 < len(line) {
+			lines = append(lines, region.clipLineString(line)...)
+		}
+	}
+	return lines
+}
+
+// clipPolygon clips a polygon to the bounds and the region, which may split it into multiple polygons. Polygons crossing the antimeridian are split into polygons on either side.
+func clipPolygon(bounds Bounds, region Region, polygon PolygonWithHoles) []PolygonWithHoles {
+	rings := append([][]Coord{polygon.Outer}, polygon.Holes...)
+	if slices.ContainsFunc(rings, crossesAntimeridian) {
+		var clipped [][]Coord
+		for _, ring := range rings {
+			for _, ring := range splitRingAntimeridian(ring) {
+				if ring = closeRing(clipBounds(bounds, ring)); 3 < len(ring) {
+					clipped = append(clipped, region.clipRing(ring)...)
 				}
 			}
 		}
-		if err := z.Parse(ctx, nil, nil, relationFunc); err != nil {
-			return nil, err
+		polygons, _ := nestRings(clipped)
+		return polygons
+	}
+
+	var clipped [][]Coord
+	for i, ring := range rings {
+		if ring = closeRing(clipBounds(bounds, ring)); len(ring) < 4 {
+			if i == 0 {
+				return nil
+			}
+			continue
 		}
+		clipped = append(clipped, ring)
 	}
-	return geometries, nil
+	return regionPolygons(region, clipped)
+}
+
+// regionPolygons clips rings that have already been clipped to the bounds to the region. The first ring is the outer ring and the others are its holes, but if the region splits rings they are nested again by geometry.
+func regionPolygons(region Region, rings [][]Coord) []PolygonWithHoles {
+	if len(rings) == 0 {
+		return nil
+	} else if b, ok := region.(Bounds); ok && !b.IsWrapped() {
+		return []PolygonWithHoles{{
+			Outer: rings[0],
+			Holes: rings[1:],
+		}}
+	}
+
+	var clipped [][]Coord
+	for _, ring := range rings {
+		clipped = append(clipped, region.clipRing(ring)...)
+	}
+	polygons, _ := nestRings(clipped)
+	return polygons
 }
@@ -0,0 +1,36 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestExtractSuperRelation(t *testing.T) {
+	b := writeTestPBF(t, testNodes, testWays, testRelations)
+	filters := []FilterFunc{
+		nil,
+		func(typ Type, id uint64, tags Tags) Class {
+			if typ == RelationType && id == 22 {
+				return 1
+			}
+			return 0
+		},
+	}
+	for i, filter := range filters {
+		var relation *Geometry
+		z := NewParser(bytes.NewReader(b))
+		err := z.ExtractFunc(context.Background(), Bounds{{-10.0, -10.0}, {30.0, 10.0}}, filter, nil, func(class Class, geom Geometry) {
+			if geom.Type == RelationType && geom.ID == 22 {
+				relation = &geom
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if relation == nil {
+			t.Errorf("filter %v: expected super relation", i)
+		} else if len(relation.LineStrings) != 2 || len(relation.Points) != 1 {
+			t.Errorf("filter %v: expected members of child relation, got %v", i, relation)
+		}
+	}
+}
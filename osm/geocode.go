@@ -0,0 +1,700 @@
+package osm
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Address is a searchable object of the geocoder, which is an address, a named street, or a named place. Generated addresses of interpolation ways refer to the way.
+type Address struct {
+	Type        Type
+	ID          uint64
+	Coord       Coord
+	Name        string
+	Street      string
+	HouseNumber string
+	Postcode    string
+	City        string
+}
+
+func (a Address) String() string {
+	s := a.Name
+	if a.Street != "" && a.Street != a.Name {
+		if s != "" {
+			s += ", "
+		}
+		s += a.Street
+		if a.HouseNumber != "" {
+			s += " " + a.HouseNumber
+		}
+	}
+	if a.Postcode != "" || a.City != "" {
+		s += ", " + strings.TrimSpace(a.Postcode+" "+a.City)
+	}
+	return s
+}
+
+// isAddress returns true if the tags contain an address.
+func isAddress(tags Tags) bool {
+	return tags.Has("addr:housenumber") || tags.Has("addr:housename")
+}
+
+// isNamedStreet returns true if the tags describe a named street.
+func isNamedStreet(tags Tags) bool {
+	return tags.Has("highway") && tags.Has("name")
+}
+
+// isNamedPlace returns true if the tags describe a named place.
+func isNamedPlace(tags Tags) bool {
+	return tags.Has("place") && tags.Has("name")
+}
+
+func newAddress(typ Type, id uint64, coord Coord, tags Tags) Address {
+	a := Address{
+		Type:        typ,
+		ID:          id,
+		Coord:       coord,
+		Name:        tags.Find("name"),
+		Street:      tags.Find("addr:street"),
+		HouseNumber: tags.Find("addr:housenumber"),
+		Postcode:    tags.Find("addr:postcode"),
+		City:        tags.Find("addr:city"),
+	}
+	if a.Street == "" {
+		a.Street = tags.Find("addr:place")
+	}
+	if a.HouseNumber == "" {
+		a.HouseNumber = tags.Find("addr:housename")
+	}
+	if !isAddress(tags) && isNamedStreet(tags) {
+		a.Street = a.Name
+	}
+	return a
+}
+
+// lineMidpoint returns the coordinate halfway along the line string.
+func lineMidpoint(coords []Coord) Coord {
+	return lineInterpolate(coords, 0.5)
+}
+
+// lineInterpolate returns the coordinate at fraction t of the length of the line string.
+func lineInterpolate(coords []Coord, t float64) Coord {
+	if len(coords) == 0 {
+		return Coord{}
+	}
+	length := 0.0
+	for i := 1; i < len(coords); i++ {
+		length += math.Hypot(coords[i].X-coords[i-1].X, coords[i].Y-coords[i-1].Y)
+	}
+	d := t * length
+	for i := 1; i < len(coords); i++ {
+		l := math.Hypot(coords[i].X-coords[i-1].X, coords[i].Y-coords[i-1].Y)
+		if d <= l && l != 0.0 {
+			f := d / l
+			return Coord{coords[i-1].X + f*(coords[i].X-coords[i-1].X), coords[i-1].Y + f*(coords[i].Y-coords[i-1].Y)}
+		}
+		d -= l
+	}
+	return coords[len(coords)-1]
+}
+
+// wayCoord returns a representative coordinate of a way: a point on the surface for closed ways and the midpoint otherwise.
+func wayCoord(coords []Coord) Coord {
+	if 3 < len(coords) && coords[0] == coords[len(coords)-1] {
+		return PolygonWithHoles{Outer: coords}.PointOnSurface()
+	}
+	return lineMidpoint(coords)
+}
+
+// ExtractAddresses extracts all addresses (objects with addr:housenumber or addr:housename), named streets (highway=* with a name), and named places (place=* with a name) from nodes, ways, and relations. The coordinate of a way is a point on its surface if closed or its midpoint otherwise, and of a relation it is that of its first closed member way. Interpolation ways (addr:interpolation) generate the addresses between house numbers of their nodes. This function requires parsing the file three times.
+func (z *Parser) ExtractAddresses(ctx context.Context) ([]Address, error) {
+	var mu sync.Mutex
+	var relations []Relation
+	memberWays := NewUint64Set(8, 0.6)
+	relationFunc := func(relation Relation) {
+		if isAddress(relation.Tags) || isNamedPlace(relation.Tags) {
+			relation.Own()
+			mu.Lock()
+			relations = append(relations, relation)
+			for _, member := range relation.Members {
+				if member.Type == WayType {
+					memberWays.Add(member.ID)
+				}
+			}
+			mu.Unlock()
+		}
+	}
+	if err := z.Parse(ctx, nil, nil, relationFunc); err != nil {
+		return nil, err
+	}
+
+	var ways []Way
+	wayRefs := map[uint64][]uint64{} // member ways of relations
+	refs := NewUint64Set(8, 0.6)
+	wayFunc := func(way Way) {
+		tags := way.Tags
+		isWay := isAddress(tags) || isNamedStreet(tags) || isNamedPlace(tags) || tags.Has("addr:interpolation")
+		mu.Lock()
+		isMember := memberWays.Has(way.ID)
+		mu.Unlock()
+		if !isWay && !isMember {
+			return
+		}
+
+		way.Own()
+		mu.Lock()
+		if isWay {
+			ways = append(ways, way)
+		}
+		if isMember {
+			wayRefs[way.ID] = way.Refs
+		}
+		for _, ref := range way.Refs {
+			refs.Add(ref)
+		}
+		mu.Unlock()
+	}
+	if err := z.Parse(ctx, nil, wayFunc, nil); err != nil {
+		return nil, err
+	}
+	memberWays = nil
+
+	addresses := []Address{}
+	coords := map[uint64]Coord{}
+	nodeTags := map[uint64]Tags{} // address tags of nodes on ways, used for interpolation
+	nodeFunc := func(node Node) {
+		coord := Coord{node.Lon, node.Lat}
+		isNode := isAddress(node.Tags) || isNamedPlace(node.Tags)
+		mu.Lock()
+		if isNode {
+			addresses = append(addresses, newAddress(NodeType, node.ID, coord, node.Tags))
+		}
+		if refs.Has(node.ID) {
+			coords[node.ID] = coord
+			if isAddress(node.Tags) {
+				nodeTags[node.ID] = node.Tags.Clone()
+			}
+		}
+		mu.Unlock()
+	}
+	if err := z.Parse(ctx, nodeFunc, nil, nil); err != nil {
+		return nil, err
+	}
+	refs = nil
+
+	resolve := func(refs []uint64) []Coord {
+		wayCoords := make([]Coord, 0, len(refs))
+		for _, ref := range refs {
+			if coord, ok := coords[ref]; ok {
+				wayCoords = append(wayCoords, coord)
+			}
+		}
+		return wayCoords
+	}
+	for _, way := range ways {
+		wayCoords := resolve(way.Refs)
+		if len(wayCoords) == 0 {
+			continue
+		}
+		if way.Tags.Has("addr:interpolation") {
+			addresses = append(addresses, interpolateAddresses(way, coords, nodeTags)...)
+		}
+		if isAddress(way.Tags) || isNamedStreet(way.Tags) || isNamedPlace(way.Tags) {
+			addresses = append(addresses, newAddress(WayType, way.ID, wayCoord(wayCoords), way.Tags))
+		}
+	}
+	for _, relation := range relations {
+		var members []Coord
+		for _, member := range relation.Members {
+			if refs, ok := wayRefs[member.ID]; ok && member.Type == WayType {
+				wayCoords := resolve(refs)
+				if 3 < len(wayCoords) && wayCoords[0] == wayCoords[len(wayCoords)-1] {
+					members = wayCoords
+					break
+				} else if members == nil {
+					members = wayCoords
+				}
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+		addresses = append(addresses, newAddress(RelationType, relation.ID, wayCoord(members), relation.Tags))
+	}
+
+	slices.SortFunc(addresses, func(a, b Address) int {
+		if c := cmp.Compare(a.Type, b.Type); c != 0 {
+			return c
+		} else if c := cmp.Compare(a.ID, b.ID); c != 0 {
+			return c
+		}
+		return strings.Compare(a.HouseNumber, b.HouseNumber)
+	})
+	return addresses, nil
+}
+
+// interpolateAddresses returns the addresses between consecutive nodes with a numeric house number of an interpolation way. The interpolation is odd, even, all, or a numeric step. Odd and even segments whose end points have the wrong parity are skipped.
+func interpolateAddresses(way Way, coords map[uint64]Coord, nodeTags map[uint64]Tags) []Address {
+	step, parity := 1, -1
+	switch interpolation := way.Tags.Find("addr:interpolation"); interpolation {
+	case "odd":
+		step, parity = 2, 1
+	case "even":
+		step, parity = 2, 0
+	case "all":
+	default:
+		var err error
+		if step, err = strconv.Atoi(interpolation); err != nil || step < 1 {
+			return nil
+		}
+	}
+
+	addresses := []Address{}
+	prev, prevNumber := -1, 0
+	for i, ref := range way.Refs {
+		tags, ok := nodeTags[ref]
+		if !ok {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSpace(tags.Find("addr:housenumber")))
+		if err != nil {
+			continue
+		}
+		if 0 <= prev && prevNumber != number && (parity < 0 || prevNumber%2 == parity && number%2 == parity) {
+			var segment []Coord
+			for _, ref := range way.Refs[prev : i+1] {
+				if coord, ok := coords[ref]; ok {
+					segment = append(segment, coord)
+				}
+			}
+
+			dir := 1
+			if number < prevNumber {
+				dir = -1
+			}
+			n := (number - prevNumber) * dir
+			for k := step; k < n; k += step {
+				a := newAddress(WayType, way.ID, lineInterpolate(segment, float64(k)/float64(n)), tags)
+				a.Name = ""
+				a.HouseNumber = strconv.Itoa(prevNumber + dir*k)
+				if street := way.Tags.Find("addr:street"); street != "" {
+					a.Street = street
+				}
+				addresses = append(addresses, a)
+			}
+		}
+		prev, prevNumber = i, number
+	}
+	return addresses
+}
+
+// foldRune removes diacritics from common Latin letters.
+func foldRune(r rune) string {
+	switch {
+	case strings.ContainsRune("àáâãäåāăą", r):
+		return "a"
+	case strings.ContainsRune("çćĉċč", r):
+		return "c"
+	case strings.ContainsRune("ďđ", r):
+		return "d"
+	case strings.ContainsRune("èéêëēĕėęě", r):
+		return "e"
+	case strings.ContainsRune("ĝğġģ", r):
+		return "g"
+	case strings.ContainsRune("ìíîïĩīĭįı", r):
+		return "i"
+	case strings.ContainsRune("ĺļľŀł", r):
+		return "l"
+	case strings.ContainsRune("ñńņňŉ", r):
+		return "n"
+	case strings.ContainsRune("òóôõöøōŏő", r):
+		return "o"
+	case strings.ContainsRune("ŕŗř", r):
+		return "r"
+	case strings.ContainsRune("śŝşš", r):
+		return "s"
+	case strings.ContainsRune("ţťŧ", r):
+		return "t"
+	case strings.ContainsRune("ùúûüũūŭůűų", r):
+		return "u"
+	case strings.ContainsRune("ýÿŷ", r):
+		return "y"
+	case strings.ContainsRune("źżž", r):
+		return "z"
+	case r == 'ß':
+		return "ss"
+	case r == 'æ':
+		return "ae"
+	case r == 'œ':
+		return "oe"
+	}
+	return string(r)
+}
+
+// normalizeText returns the lowercase text with diacritics removed and all non-alphanumeric characters replaced by spaces.
+func normalizeText(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteString(foldRune(r))
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	return sb.String()
+}
+
+// normalizeHouseNumber returns the house number in lowercase and without separators between the number and its suffix, so that "12 A" and "12-a" both become "12a".
+func normalizeHouseNumber(s string) string {
+	runes := []rune(strings.Join(strings.Fields(normalizeText(s)), " "))
+	var sb strings.Builder
+	for i, r := range runes {
+		if r == ' ' && 0 < i && i+1 < len(runes) && unicode.IsDigit(runes[i-1]) != unicode.IsDigit(runes[i+1]) {
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// tokenize splits the normalised text into tokens. A number followed by a single letter is joined as a house number.
+func tokenize(s string) []string {
+	fields := strings.Fields(normalizeText(s))
+	tokens := fields[:0]
+	for i := 0; i < len(fields); i++ {
+		if i+1 < len(fields) && isNumeric(fields[i]) && len([]rune(fields[i+1])) == 1 && !isNumeric(fields[i+1]) {
+			tokens = append(tokens, fields[i]+fields[i+1])
+			i++
+			continue
+		}
+		tokens = append(tokens, fields[i])
+	}
+	return tokens
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// addressTokens returns the unique tokens of an address.
+func addressTokens(a Address) []string {
+	tokens := []string{}
+	for _, s := range []string{a.Name, a.Street, a.City} {
+		tokens = append(tokens, tokenize(s)...)
+	}
+	if houseNumber := normalizeHouseNumber(a.HouseNumber); houseNumber != "" {
+		tokens = append(tokens, strings.Fields(houseNumber)...)
+	}
+	if postcode := strings.ReplaceAll(normalizeText(a.Postcode), " ", ""); postcode != "" {
+		tokens = append(tokens, postcode)
+		tokens = append(tokens, tokenize(a.Postcode)...)
+	}
+	slices.Sort(tokens)
+	return slices.Compact(tokens)
+}
+
+// Geocoder is an offline forward geocoder, an inverted index from normalised tokens to addresses that supports prefix and fuzzy matching.
+type Geocoder struct {
+	addresses []Address
+	tokens    []string   // sorted
+	postings  [][]uint32 // sorted address indices per token
+}
+
+// GeocodeResult is an address that matches a query, with a score between zero and one.
+type GeocodeResult struct {
+	Address
+	Score float64
+}
+
+// NewGeocoder returns a geocoder that indexes the addresses.
+func NewGeocoder(addresses []Address) *Geocoder {
+	index := map[string][]uint32{}
+	for i, a := range addresses {
+		for _, token := range addressTokens(a) {
+			index[token] = append(index[token], uint32(i))
+		}
+	}
+	g := &Geocoder{
+		addresses: addresses,
+		tokens:    make([]string, 0, len(index)),
+		postings:  make([][]uint32, 0, len(index)),
+	}
+	for token := range index {
+		g.tokens = append(g.tokens, token)
+	}
+	slices.Sort(g.tokens)
+	for _, token := range g.tokens {
+		g.postings = append(g.postings, index[token])
+	}
+	return g
+}
+
+// Addresses returns all indexed addresses.
+func (g *Geocoder) Addresses() []Address {
+	return g.addresses
+}
+
+// editDistance returns the Levenshtein distance between a and b, or max+1 if it exceeds max.
+func editDistance(a, b []rune, max int) int {
+	if max < len(a)-len(b) || max < len(b)-len(a) {
+		return max + 1
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if max < rowMin {
+			return max + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// maxEditDistance returns the number of typos allowed for a query token, which depends on its length. Numbers must match exactly.
+func maxEditDistance(token []rune) int {
+	if isNumeric(string(token[:1])) {
+		return 0
+	} else if len(token) < 4 {
+		return 0
+	} else if len(token) < 8 {
+		return 1
+	}
+	return 2
+}
+
+// matchToken returns the score per token index that matches the query token: exact matches score 1, prefix matches 0.75 if prefix is true, and fuzzy matches 0.5.
+func (g *Geocoder) matchToken(token string, prefix bool) map[int]float64 {
+	matches := map[int]float64{}
+	i, _ := slices.BinarySearch(g.tokens, token)
+	if i < len(g.tokens) && g.tokens[i] == token {
+		matches[i] = 1.0
+	}
+	if prefix {
+		for j := i; j < len(g.tokens) && strings.HasPrefix(g.tokens[j], token); j++ {
+			if _, ok := matches[j]; !ok {
+				matches[j] = 0.75
+			}
+		}
+	}
+	q := []rune(token)
+	if max := maxEditDistance(q); 0 < max {
+		for j, t := range g.tokens {
+			if _, ok := matches[j]; !ok && editDistance(q, []rune(t), max) <= max {
+				matches[j] = 0.5
+			}
+		}
+	}
+	return matches
+}
+
+// Search returns up to limit addresses that match all tokens of the query, ordered by decreasing score. The last token may be a prefix, and tokens may contain typos. House numbers and postcodes are normalised.
+func (g *Geocoder) Search(query string, limit int) []GeocodeResult {
+	tokens := tokenize(query)
+	if len(tokens) == 0 || limit <= 0 {
+		return nil
+	}
+
+	var scores map[uint32]float64
+	for k, token := range tokens {
+		tokenScores := map[uint32]float64{}
+		for i, score := range g.matchToken(token, k == len(tokens)-1) {
+			for _, j := range g.postings[i] {
+				if scores == nil || scores[j] != 0.0 {
+					tokenScores[j] = math.Max(tokenScores[j], score)
+				}
+			}
+		}
+		if scores == nil {
+			scores = tokenScores
+		} else {
+			for j, score := range scores {
+				if tokenScore, ok := tokenScores[j]; ok {
+					scores[j] = score + tokenScore
+				} else {
+					delete(scores, j)
+				}
+			}
+		}
+		if len(scores) == 0 {
+			return nil
+		}
+	}
+
+	results := make([]GeocodeResult, 0, len(scores))
+	for j, score := range scores {
+		results = append(results, GeocodeResult{g.addresses[j], score / float64(len(tokens))})
+	}
+	slices.SortFunc(results, func(a, b GeocodeResult) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		} else if c := cmp.Compare(a.Type, b.Type); c != 0 {
+			return c
+		} else if c := cmp.Compare(a.ID, b.ID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.HouseNumber, b.HouseNumber)
+	})
+	if limit < len(results) {
+		results = results[:limit]
+	}
+	return results
+}
+
+const geocoderMagic = "GEOC"
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// Bytes returns the serialized geocoder, including its index, which can be loaded with LoadGeocoder.
+func (g *Geocoder) Bytes() []byte {
+	b := []byte(geocoderMagic)
+	b = binary.AppendUvarint(b, 1) // version
+	b = binary.AppendUvarint(b, uint64(len(g.addresses)))
+	for _, a := range g.addresses {
+		b = append(b, byte(a.Type))
+		b = binary.AppendUvarint(b, a.ID)
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(a.Coord.X))
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(a.Coord.Y))
+		for _, s := range []string{a.Name, a.Street, a.HouseNumber, a.Postcode, a.City} {
+			b = appendString(b, s)
+		}
+	}
+	b = binary.AppendUvarint(b, uint64(len(g.tokens)))
+	for i, token := range g.tokens {
+		b = appendString(b, token)
+		b = binary.AppendUvarint(b, uint64(len(g.postings[i])))
+		prev := uint32(0)
+		for _, j := range g.postings[i] {
+			b = binary.AppendUvarint(b, uint64(j-prev)) // delta encoded
+			prev = j
+		}
+	}
+	return b
+}
+
+// binaryReader reads serialized data, where reads return zero values after an error.
+type binaryReader struct {
+	name string
+	b    []byte
+	err  error
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.err = fmt.Errorf("invalid %s data", r.name)
+		r.b = nil
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *binaryReader) count(size int) int {
+	n := r.uvarint()
+	if uint64(len(r.b)/size) < n {
+		r.err = fmt.Errorf("invalid %s data", r.name)
+		r.b = nil
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binaryReader) bytes(n int) []byte {
+	if len(r.b) < n {
+		r.err = fmt.Errorf("invalid %s data", r.name)
+		r.b = nil
+		return make([]byte, n)
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *binaryReader) string() string {
+	return string(r.bytes(r.count(1)))
+}
+
+func (r *binaryReader) uint32() uint32 {
+	return binary.LittleEndian.Uint32(r.bytes(4))
+}
+
+func (r *binaryReader) float32() float32 {
+	return math.Float32frombits(r.uint32())
+}
+
+func (r *binaryReader) float64() float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(r.bytes(8)))
+}
+
+// LoadGeocoder returns the geocoder serialized by Bytes.
+func LoadGeocoder(b []byte) (*Geocoder, error) {
+	if len(b) < len(geocoderMagic) || string(b[:len(geocoderMagic)]) != geocoderMagic {
+		return nil, fmt.Errorf("invalid geocoder header")
+	}
+	r := &binaryReader{name: "geocoder", b: b[len(geocoderMagic):]}
+	if version := r.uvarint(); r.err == nil && version != 1 {
+		return nil, fmt.Errorf("unsupported geocoder version %v", version)
+	}
+
+	g := &Geocoder{}
+	g.addresses = make([]Address, r.count(20))
+	for i := range g.addresses {
+		a := &g.addresses[i]
+		a.Type = Type(r.bytes(1)[0])
+		a.ID = r.uvarint()
+		coord := r.bytes(16)
+		a.Coord.X = math.Float64frombits(binary.LittleEndian.Uint64(coord))
+		a.Coord.Y = math.Float64frombits(binary.LittleEndian.Uint64(coord[8:]))
+		a.Name, a.Street, a.HouseNumber, a.Postcode, a.City = r.string(), r.string(), r.string(), r.string(), r.string()
+	}
+	n := r.count(2)
+	g.tokens = make([]string, n)
+	g.postings = make([][]uint32, n)
+	for i := 0; i < n; i++ {
+		g.tokens[i] = r.string()
+		g.postings[i] = make([]uint32, r.count(1))
+		prev := uint64(0)
+		for k := range g.postings[i] {
+			prev += r.uvarint()
+			if uint64(len(g.addresses)) <= prev {
+				return nil, fmt.Errorf("invalid geocoder data")
+			}
+			g.postings[i][k] = uint32(prev)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	} else if len(r.b) != 0 {
+		return nil, fmt.Errorf("invalid geocoder data")
+	}
+	return g, nil
+}
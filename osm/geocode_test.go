@@ -0,0 +1,180 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeHouseNumber(t *testing.T) {
+	tests := []struct {
+		s, expected string
+	}{
+		{"12", "12"},
+		{"12 A", "12a"},
+		{"12-a", "12a"},
+		{"12a", "12a"},
+		{"12 bis", "12bis"},
+		{"10-12", "10 12"},
+	}
+	for _, tt := range tests {
+		if s := normalizeHouseNumber(tt.s); s != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.s, tt.expected, s)
+		}
+	}
+	if tokens := tokenize("Straße 12 b, Zürich"); !slices.Equal(tokens, []string{"strasse", "12b", "zurich"}) {
+		t.Errorf("wrong tokens %q", tokens)
+	}
+}
+
+func testGeocoder(t *testing.T) *Geocoder {
+	nodes := []Node{
+		{ID: 1, Lon: 6.56, Lat: 53.21, Tags: Tags{{"addr:street", "Hoofdstraat"}, {"addr:housenumber", "12 A"}, {"addr:postcode", "9712 AB"}, {"addr:city", "Groningen"}}},
+		{ID: 2, Lon: 6.57, Lat: 53.22, Tags: Tags{{"place", "city"}, {"name", "Groningen"}}},
+		{ID: 3, Lon: 6.55, Lat: 53.21},
+		{ID: 4, Lon: 6.57, Lat: 53.21},
+		{ID: 5, Lon: 6.0, Lat: 53.0},
+		{ID: 6, Lon: 6.1, Lat: 53.0},
+		{ID: 7, Lon: 6.1, Lat: 53.1},
+		{ID: 8, Lon: 6.0, Lat: 53.1},
+		{ID: 9, Lon: 7.0, Lat: 52.0, Tags: Tags{{"addr:street", "Kerkstraat"}, {"addr:housenumber", "2"}}},
+		{ID: 10, Lon: 7.4, Lat: 52.0, Tags: Tags{{"addr:street", "Kerkstraat"}, {"addr:housenumber", "10"}}},
+		{ID: 11, Lon: 5.0, Lat: 52.0},
+		{ID: 12, Lon: 5.1, Lat: 52.0},
+		{ID: 13, Lon: 5.1, Lat: 52.1},
+		{ID: 14, Lon: 5.0, Lat: 52.1},
+	}
+	ways := []Way{
+		{ID: 1, Refs: []uint64{3, 4}, Tags: Tags{{"highway", "residential"}, {"name", "Hoofdstraat"}}},
+		{ID: 2, Refs: []uint64{5, 6, 7, 8, 5}, Tags: Tags{{"building", "yes"}, {"addr:street", "Vismarkt"}, {"addr:housenumber", "5"}}},
+		{ID: 3, Refs: []uint64{9, 10}, Tags: Tags{{"addr:interpolation", "even"}}},
+		{ID: 4, Refs: []uint64{11, 12, 13, 14, 11}},
+	}
+	relations := []Relation{
+		{ID: 1, Members: []Member{{WayType, 4, "outer"}}, Tags: Tags{{"type", "multipolygon"}, {"building", "yes"}, {"addr:street", "Grote Markt"}, {"addr:housenumber", "1"}}},
+	}
+	b := writeTestPBF(t, nodes, ways, relations)
+
+	z := NewParser(bytes.NewReader(b))
+	addresses, err := z.ExtractAddresses(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewGeocoder(addresses)
+}
+
+func TestGeocoder(t *testing.T) {
+	g := testGeocoder(t)
+	if n := len(g.Addresses()); n != 10 {
+		t.Errorf("expected 10 addresses, got %v", n)
+	}
+
+	type ref struct {
+		Type        Type
+		ID          uint64
+		HouseNumber string
+	}
+	search := func(query string) []ref {
+		refs := []ref{}
+		for _, result := range g.Search(query, 10) {
+			refs = append(refs, ref{result.Type, result.ID, result.HouseNumber})
+		}
+		return refs
+	}
+	tests := []struct {
+		query    string
+		expected []ref
+	}{
+		{"Hoofdstraat 12 a", []ref{{NodeType, 1, "12 A"}}},
+		{"hoofdstraat 12A groningen", []ref{{NodeType, 1, "12 A"}}},
+		{"Hoofdstrat 12-a", []ref{{NodeType, 1, "12 A"}}},            // typo
+		{"hoofdstr", []ref{{NodeType, 1, "12 A"}, {WayType, 1, ""}}}, // prefix
+		{"9712ab", []ref{{NodeType, 1, "12 A"}}},
+		{"9712 AB", []ref{{NodeType, 1, "12 A"}}},
+		{"groningen", []ref{{NodeType, 1, "12 A"}, {NodeType, 2, ""}}},
+		{"Vismarkt 5", []ref{{WayType, 2, "5"}}},
+		{"Grote Markt 1", []ref{{RelationType, 1, "1"}}},
+		{"Kerkstraat 6", []ref{{WayType, 3, "6"}}}, // interpolated
+		{"Kerkstraat 5", []ref{}},
+		{"Nowhere", []ref{}},
+	}
+	for _, tt := range tests {
+		if found := search(tt.query); !reflect.DeepEqual(found, tt.expected) {
+			t.Errorf("%q: expected %v, got %v", tt.query, tt.expected, found)
+		}
+	}
+
+	if results := g.Search("kerkstraat", 10); len(results) != 5 {
+		t.Errorf("expected 5 addresses on Kerkstraat, got %v", len(results))
+	}
+	if results := g.Search("Kerkstraat 6", 1); len(results) != 1 || results[0].Coord != (Coord{7.2, 52.0}) {
+		t.Errorf("expected interpolated coordinate (7.2,52), got %v", results)
+	}
+	if results := g.Search("Vismarkt 5", 1); len(results) != 1 || !(Bounds{{6.0, 53.0}, {6.1, 53.1}}).Contains(results[0].Coord) {
+		t.Errorf("expected coordinate within building, got %v", results)
+	}
+}
+
+func TestInterpolateAddresses(t *testing.T) {
+	coords := map[uint64]Coord{1: {0.0, 0.0}, 2: {1.0, 0.0}, 3: {2.0, 0.0}}
+	nodeTags := map[uint64]Tags{
+		1: {{"addr:housenumber", "1"}},
+		2: {{"addr:housenumber", "6"}},
+		3: {{"addr:housenumber", "9"}},
+	}
+	tests := []struct {
+		interpolation string
+		expected      []string
+	}{
+		{"odd", []string{}}, // ends do not match parity
+		{"even", []string{}},
+		{"all", []string{"2", "3", "4", "5", "7", "8"}},
+		{"2", []string{"3", "5", "8"}},
+	}
+	for _, tt := range tests {
+		way := Way{ID: 1, Refs: []uint64{1, 2, 3}, Tags: Tags{{"addr:interpolation", tt.interpolation}}}
+		numbers := []string{}
+		for _, a := range interpolateAddresses(way, coords, nodeTags) {
+			numbers = append(numbers, a.HouseNumber)
+		}
+		if !slices.Equal(numbers, tt.expected) {
+			t.Errorf("%v: expected %v, got %v", tt.interpolation, tt.expected, numbers)
+		}
+	}
+
+	nodeTags[2] = Tags{{"addr:housenumber", "5"}}
+	way := Way{ID: 1, Refs: []uint64{1, 2, 3}, Tags: Tags{{"addr:interpolation", "odd"}}}
+	numbers := []string{}
+	for _, a := range interpolateAddresses(way, coords, nodeTags) {
+		numbers = append(numbers, a.HouseNumber)
+	}
+	if !slices.Equal(numbers, []string{"3", "7"}) {
+		t.Errorf("expected odd numbers 3 and 7, got %v", numbers)
+	}
+}
+
+func TestGeocoderBytes(t *testing.T) {
+	g := testGeocoder(t)
+	b := g.Bytes()
+	loaded, err := LoadGeocoder(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Addresses(), g.Addresses()) {
+		t.Errorf("expected addresses %v, got %v", g.Addresses(), loaded.Addresses())
+	}
+	for _, query := range []string{"hoofdstraat 12a", "kerkstr", "Grote Markt"} {
+		if expected, results := g.Search(query, 10), loaded.Search(query, 10); !reflect.DeepEqual(results, expected) {
+			t.Errorf("%q: expected %v, got %v", query, expected, results)
+		}
+	}
+
+	if _, err := LoadGeocoder(b[:len(b)-1]); err == nil {
+		t.Errorf("expected error for truncated data")
+	}
+	if _, err := LoadGeocoder([]byte("GEO")); err == nil {
+		t.Errorf("expected error for invalid header")
+	}
+}
@@ -0,0 +1,1013 @@
+package osm
+
+import (
+	"math"
+)
+
+// The geodesic computations follow C. F. F. Karney, Algorithms for geodesics, J. Geodesy 87, 43–55 (2013), and are ported from GeographicLib using series expansions to sixth order in the flattening.
+
+const (
+	geodOrder  = 6
+	geodNC3x   = (geodOrder * (geodOrder - 1)) / 2
+	geodNC4x   = (geodOrder * (geodOrder + 1)) / 2
+	geodMaxit1 = 20
+	geodMaxit2 = geodMaxit1 + 53 + 10
+	geodDegree = math.Pi / 180.0
+)
+
+var (
+	geodTiny     = math.Sqrt(math.SmallestNonzeroFloat64 * (1 << 52)) // square root of the smallest normal number
+	geodTol0     = math.Nextafter(1.0, 2.0) - 1.0
+	geodTol1     = 200.0 * geodTol0
+	geodTol2     = math.Sqrt(geodTol0)
+	geodTolb     = geodTol0
+	geodXThresh  = 1000.0 * geodTol2
+	geodSqrtHalf = math.Sqrt(0.5)
+)
+
+// Geodesic solves geodesic problems on an ellipsoid with semi-major axis A in meters and flattening F.
+type Geodesic struct {
+	A, F float64
+
+	f1, e2, ep2, n, b, c2, etol2 float64
+	a3x                          [geodOrder]float64
+	c3x                          [geodNC3x]float64
+	c4x                          [geodNC4x]float64
+}
+
+// WGS84Geodesic is the geodesic on the WGS84 ellipsoid, which is used by OpenStreetMap. It has the same parameters as geo.WGS84.
+var WGS84Geodesic = NewGeodesic(6378137.0, 1.0/298.257223563)
+
+// NewGeodesic returns a geodesic solver for the ellipsoid with semi-major axis a in meters and flattening f.
+func NewGeodesic(a, f float64) *Geodesic {
+	g := &Geodesic{A: a, F: f}
+	g.f1 = 1.0 - f
+	g.e2 = f * (2.0 - f)
+	g.ep2 = g.e2 / (g.f1 * g.f1)
+	g.n = f / (2.0 - f)
+	g.b = a * g.f1
+
+	// authalic radius squared
+	if g.e2 == 0.0 {
+		g.c2 = a * a
+	} else if 0.0 < g.e2 {
+		g.c2 = (a*a + g.b*g.b*math.Atanh(math.Sqrt(g.e2))/math.Sqrt(g.e2)) / 2.0
+	} else {
+		g.c2 = (a*a + g.b*g.b*math.Atan(math.Sqrt(-g.e2))/math.Sqrt(-g.e2)) / 2.0
+	}
+	g.etol2 = 0.1 * geodTol2 / math.Sqrt(math.Max(0.001, math.Abs(f))*math.Min(1.0, 1.0-f/2.0)/2.0)
+	g.a3coeff()
+	g.c3coeff()
+	g.c4coeff()
+	return g
+}
+
+// polyval evaluates the polynomial of order n with coefficients p, highest order first, using Horner's method.
+func polyval(n int, p []float64, x float64) float64 {
+	if n < 0 {
+		return 0.0
+	}
+	y := p[0]
+	for i := 1; i <= n; i++ {
+		y = y*x + p[i]
+	}
+	return y
+}
+
+// sumx returns the sum of u and v and its round-off error.
+func sumx(u, v float64) (float64, float64) {
+	s := u + v
+	up := s - v
+	vpp := s - up
+	up -= u
+	vpp -= v
+	if s == 0.0 {
+		return s, s
+	}
+	return s, -(up + vpp)
+}
+
+func angNormalize(x float64) float64 {
+	y := math.Remainder(x, 360.0)
+	if math.Abs(y) == 180.0 {
+		return math.Copysign(180.0, x)
+	}
+	return y
+}
+
+// angDiff returns the exact difference y-x reduced to [-180,180] and its error.
+func angDiff(x, y float64) (float64, float64) {
+	d, t := sumx(math.Remainder(-x, 360.0), math.Remainder(y, 360.0))
+	d, t = sumx(math.Remainder(d, 360.0), t)
+	if d == 0.0 || math.Abs(d) == 180.0 {
+		if t == 0.0 {
+			d = math.Copysign(d, y-x)
+		} else {
+			d = math.Copysign(d, -t)
+		}
+	}
+	return d, t
+}
+
+// angRound rounds tiny angles to zero to avoid underflow.
+func angRound(x float64) float64 {
+	const z = 1.0 / 16.0
+	y := math.Abs(x)
+	if w := z - y; 0.0 < w {
+		y = z - w
+	}
+	return math.Copysign(y, x)
+}
+
+func latFix(x float64) float64 {
+	if 90.0 < math.Abs(x) {
+		return math.NaN()
+	}
+	return x
+}
+
+// sincosde returns the sine and cosine of x+t in degrees, reducing the argument exactly.
+func sincosde(x, t float64) (float64, float64) {
+	q := math.Round(x / 90.0)
+	r := x - 90.0*q
+	r = angRound(r+t) * geodDegree
+	s, c := math.Sin(r), math.Cos(r)
+	var sinx, cosx float64
+	switch int(math.Mod(q, 4.0)+4.0) & 3 {
+	case 0:
+		sinx, cosx = s, c
+	case 1:
+		sinx, cosx = c, -s
+	case 2:
+		sinx, cosx = -s, -c
+	default:
+		sinx, cosx = -c, s
+	}
+	cosx += 0.0
+	if sinx == 0.0 {
+		sinx = math.Copysign(sinx, x)
+	}
+	return sinx, cosx
+}
+
+func sincosd(x float64) (float64, float64) {
+	q := math.Round(math.Remainder(x, 360.0) / 90.0)
+	r := (math.Remainder(x, 360.0) - 90.0*q) * geodDegree
+	s, c := math.Sin(r), math.Cos(r)
+	var sinx, cosx float64
+	switch int(q+4.0) & 3 {
+	case 0:
+		sinx, cosx = s, c
+	case 1:
+		sinx, cosx = c, -s
+	case 2:
+		sinx, cosx = -s, -c
+	default:
+		sinx, cosx = -c, s
+	}
+	cosx += 0.0
+	if sinx == 0.0 {
+		sinx = math.Copysign(sinx, x)
+	}
+	return sinx, cosx
+}
+
+// atan2d returns atan2(y,x) in degrees with exact results for multiples of 45 degrees.
+func atan2d(y, x float64) float64 {
+	q := 0
+	if math.Abs(x) < math.Abs(y) {
+		x, y = y, x
+		q = 2
+	}
+	if math.Signbit(x) {
+		x = -x
+		q++
+	}
+	ang := math.Atan2(y, x) / geodDegree
+	switch q {
+	case 1:
+		ang = math.Copysign(180.0, y) - ang
+	case 2:
+		ang = 90.0 - ang
+	case 3:
+		ang = -90.0 + ang
+	}
+	return ang
+}
+
+func norm2(x, y float64) (float64, float64) {
+	r := math.Hypot(x, y)
+	return x / r, y / r
+}
+
+// sinCosSeries evaluates a trigonometric series using Clenshaw summation. If sinp is true it evaluates sum(c[i]*sin(2*i*x), i=1..n), otherwise sum(c[i]*cos((2*i+1)*x), i=0..n-1).
+func sinCosSeries(sinp bool, sinx, cosx float64, c []float64, n int) float64 {
+	k := n
+	if sinp {
+		k++
+	}
+	ar := 2.0 * (cosx - sinx) * (cosx + sinx)
+	y0, y1 := 0.0, 0.0
+	if n&1 == 1 {
+		k--
+		y0 = c[k]
+	}
+	for i := n / 2; 0 < i; i-- {
+		k--
+		y1 = ar*y0 - y1 + c[k]
+		k--
+		y0 = ar*y1 - y0 + c[k]
+	}
+	if sinp {
+		return 2.0 * sinx * cosx * y0
+	}
+	return cosx * (y0 - y1)
+}
+
+func a1m1f(eps float64) float64 {
+	coeff := [...]float64{1, 4, 64, 0, 256}
+	t := polyval(3, coeff[:], eps*eps) / coeff[4]
+	return (t + eps) / (1.0 - eps)
+}
+
+func c1f(eps float64, c []float64) {
+	coeff := [...]float64{
+		-1, 6, -16, 32,
+		-9, 64, -128, 2048,
+		9, -16, 768,
+		3, -5, 512,
+		-7, 1280,
+		-7, 2048,
+	}
+	eps2, d := eps*eps, eps
+	o := 0
+	for l := 1; l <= geodOrder; l++ {
+		m := (geodOrder - l) / 2
+		c[l] = d * polyval(m, coeff[o:], eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+func c1pf(eps float64, c []float64) {
+	coeff := [...]float64{
+		205, -432, 768, 1536,
+		4005, -4736, 3840, 12288,
+		-225, 116, 384,
+		-7173, 2695, 7680,
+		3467, 7680,
+		38081, 61440,
+	}
+	eps2, d := eps*eps, eps
+	o := 0
+	for l := 1; l <= geodOrder; l++ {
+		m := (geodOrder - l) / 2
+		c[l] = d * polyval(m, coeff[o:], eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+func a2m1f(eps float64) float64 {
+	coeff := [...]float64{-11, -28, -192, 0, 256}
+	t := polyval(3, coeff[:], eps*eps) / coeff[4]
+	return (t - eps) / (1.0 + eps)
+}
+
+func c2f(eps float64, c []float64) {
+	coeff := [...]float64{
+		1, 2, 16, 32,
+		35, 64, 384, 2048,
+		15, 80, 768,
+		7, 35, 512,
+		63, 1280,
+		77, 2048,
+	}
+	eps2, d := eps*eps, eps
+	o := 0
+	for l := 1; l <= geodOrder; l++ {
+		m := (geodOrder - l) / 2
+		c[l] = d * polyval(m, coeff[o:], eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+func (g *Geodesic) a3coeff() {
+	coeff := [...]float64{
+		-3, 128,
+		-2, -3, 64,
+		-1, -3, -1, 16,
+		3, -1, -2, 8,
+		1, -1, 2,
+		1, 1,
+	}
+	o, k := 0, 0
+	for j := geodOrder - 1; 0 <= j; j-- {
+		m := min(geodOrder-j-1, j)
+		g.a3x[k] = polyval(m, coeff[o:], g.n) / coeff[o+m+1]
+		k++
+		o += m + 2
+	}
+}
+
+func (g *Geodesic) c3coeff() {
+	coeff := [...]float64{
+		3, 128,
+		2, 5, 128,
+		-1, 3, 3, 64,
+		-1, 0, 1, 8,
+		-1, 1, 4,
+		5, 256,
+		1, 3, 128,
+		-3, -2, 3, 64,
+		1, -3, 2, 32,
+		7, 512,
+		-10, 9, 384,
+		5, -9, 5, 192,
+		7, 512,
+		-14, 7, 512,
+		21, 2560,
+	}
+	o, k := 0, 0
+	for l := 1; l < geodOrder; l++ {
+		for j := geodOrder - 1; l <= j; j-- {
+			m := min(geodOrder-j-1, j)
+			g.c3x[k] = polyval(m, coeff[o:], g.n) / coeff[o+m+1]
+			k++
+			o += m + 2
+		}
+	}
+}
+
+func (g *Geodesic) c4coeff() {
+	coeff := [...]float64{
+		97, 15015,
+		1088, 156, 45045,
+		-224, -4784, 1573, 45045,
+		-10656, 14144, -4576, -858, 45045,
+		64, 624, -4576, 6864, -3003, 15015,
+		100, 208, 572, 3432, -12012, 30030, 45045,
+		1, 9009,
+		-2944, 468, 135135,
+		5792, 1040, -1287, 135135,
+		5952, -11648, 9152, -2574, 135135,
+		-64, -624, 4576, -6864, 3003, 135135,
+		8, 10725,
+		1856, -936, 225225,
+		-8448, 4992, -1144, 225225,
+		-1440, 4160, -4576, 1716, 225225,
+		-136, 63063,
+		1024, -208, 105105,
+		3584, -3328, 1144, 315315,
+		-128, 135135,
+		-2560, 832, 405405,
+		128, 99099,
+	}
+	o, k := 0, 0
+	for l := 0; l < geodOrder; l++ {
+		for j := geodOrder - 1; l <= j; j-- {
+			m := geodOrder - j - 1
+			g.c4x[k] = polyval(m, coeff[o:], g.n) / coeff[o+m+1]
+			k++
+			o += m + 2
+		}
+	}
+}
+
+func (g *Geodesic) a3f(eps float64) float64 {
+	return polyval(geodOrder-1, g.a3x[:], eps)
+}
+
+func (g *Geodesic) c3f(eps float64, c []float64) {
+	mult, o := 1.0, 0
+	c[0] = 0.0
+	for l := 1; l < geodOrder; l++ {
+		m := geodOrder - l - 1
+		mult *= eps
+		c[l] = mult * polyval(m, g.c3x[o:], eps)
+		o += m + 1
+	}
+}
+
+func (g *Geodesic) c4f(eps float64, c []float64) {
+	mult, o := 1.0, 0
+	for l := 0; l < geodOrder; l++ {
+		m := geodOrder - l - 1
+		c[l] = mult * polyval(m, g.c4x[o:], eps)
+		o += m + 1
+		mult *= eps
+	}
+}
+
+// lengths returns the distance and reduced length, both divided by b, and m0.
+func (g *Geodesic) lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2 float64) (float64, float64, float64) {
+	var ca, cb [geodOrder + 1]float64
+	a1 := a1m1f(eps)
+	c1f(eps, ca[:])
+	a2 := a2m1f(eps)
+	c2f(eps, cb[:])
+	m0 := a1 - a2
+	a1, a2 = 1.0+a1, 1.0+a2
+
+	b1 := sinCosSeries(true, ssig2, csig2, ca[:], geodOrder) - sinCosSeries(true, ssig1, csig1, ca[:], geodOrder)
+	s12b := a1 * (sig12 + b1)
+	b2 := sinCosSeries(true, ssig2, csig2, cb[:], geodOrder) - sinCosSeries(true, ssig1, csig1, cb[:], geodOrder)
+	j12 := m0*sig12 + (a1*b1 - a2*b2)
+	m12b := dn2*(csig1*ssig2) - dn1*(ssig1*csig2) - csig1*csig2*j12
+	return s12b, m12b, m0
+}
+
+// astroid solves k^4+2*k^3-(x^2+y^2-1)*k^2-2*y^2*k-y^2 = 0 for the positive root k.
+func astroid(x, y float64) float64 {
+	p, q := x*x, y*y
+	r := (p + q - 1.0) / 6.0
+	if q == 0.0 && r <= 0.0 {
+		return 0.0
+	}
+	S := p * q / 4.0
+	r2 := r * r
+	r3 := r * r2
+	disc := S * (S + 2.0*r3)
+	u := r
+	if 0.0 <= disc {
+		T3 := S + r3
+		if T3 < 0.0 {
+			T3 -= math.Sqrt(disc)
+		} else {
+			T3 += math.Sqrt(disc)
+		}
+		T := math.Cbrt(T3)
+		u += T
+		if T != 0.0 {
+			u += r2 / T
+		}
+	} else {
+		ang := math.Atan2(math.Sqrt(-disc), -(S + r3))
+		u += 2.0 * r * math.Cos(ang/3.0)
+	}
+	v := math.Sqrt(u*u + q)
+	uv := u + v
+	if u < 0.0 {
+		uv = q / (v - u)
+	}
+	w := (uv - q) / (2.0 * v)
+	return uv / (math.Sqrt(uv+w*w) + w)
+}
+
+// inverseStart returns a starting point for Newton's method in salp1 and calp1. If sig12 is non-negative, the solution is found for short lines and salp2, calp2, and dnm are set as well.
+func (g *Geodesic) inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2, lam12, slam12, clam12 float64) (sig12, salp1, calp1, salp2, calp2, dnm float64) {
+	sig12 = -1.0
+	sbet12 := sbet2*cbet1 - cbet2*sbet1
+	cbet12 := cbet2*cbet1 + sbet2*sbet1
+	sbet12a := sbet2*cbet1 + cbet2*sbet1
+	shortline := 0.0 <= cbet12 && sbet12 < 0.5 && cbet2*lam12 < 0.5
+	var somg12, comg12 float64
+	if shortline {
+		sbetm2 := (sbet1 + sbet2) * (sbet1 + sbet2)
+		sbetm2 /= sbetm2 + (cbet1+cbet2)*(cbet1+cbet2)
+		dnm = math.Sqrt(1.0 + g.ep2*sbetm2)
+		omg12 := lam12 / (g.f1 * dnm)
+		somg12, comg12 = math.Sin(omg12), math.Cos(omg12)
+	} else {
+		somg12, comg12 = slam12, clam12
+	}
+
+	salp1 = cbet2 * somg12
+	if 0.0 <= comg12 {
+		calp1 = sbet12 + cbet2*sbet1*somg12*somg12/(1.0+comg12)
+	} else {
+		calp1 = sbet12a - cbet2*sbet1*somg12*somg12/(1.0-comg12)
+	}
+	ssig12 := math.Hypot(salp1, calp1)
+	csig12 := sbet1*sbet2 + cbet1*cbet2*comg12
+
+	if shortline && ssig12 < g.etol2 {
+		// really short lines
+		salp2 = cbet1 * somg12
+		if 0.0 <= comg12 {
+			calp2 = sbet12 - cbet1*sbet2*somg12*somg12/(1.0+comg12)
+		} else {
+			calp2 = sbet12 - cbet1*sbet2*(1.0-comg12)
+		}
+		salp2, calp2 = norm2(salp2, calp2)
+		sig12 = math.Atan2(ssig12, csig12)
+	} else if 0.1 < math.Abs(g.n) || 0.0 <= csig12 || 6.0*math.Abs(g.n)*math.Pi*cbet1*cbet1 <= ssig12 {
+		// zeroth order spherical approximation is good enough
+	} else {
+		// scale to a coordinate system where the antipodal point is at the origin
+		lam12x := math.Atan2(-slam12, -clam12)
+		var x, y, lamscale, betscale float64
+		if 0.0 <= g.F {
+			k2 := sbet1 * sbet1 * g.ep2
+			eps := k2 / (2.0*(1.0+math.Sqrt(1.0+k2)) + k2)
+			lamscale = g.F * cbet1 * g.a3f(eps) * math.Pi
+			betscale = lamscale * cbet1
+			x = lam12x / lamscale
+			y = sbet12a / betscale
+		} else {
+			cbet12a := cbet2*cbet1 - sbet2*sbet1
+			bet12a := math.Atan2(sbet12a, cbet12a)
+			eps := g.n
+			_, m12b, m0 := g.lengths(eps, math.Pi+bet12a, sbet1, -cbet1, dn1, sbet2, cbet2, dn2)
+			x = -1.0 + m12b/(cbet1*cbet2*m0*math.Pi)
+			if x < -0.01 {
+				betscale = sbet12a / x
+			} else {
+				betscale = -g.F * cbet1 * cbet1 * math.Pi
+			}
+			lamscale = betscale / cbet1
+			y = lam12x / lamscale
+		}
+
+		if -geodTol1 < y && -1.0-geodXThresh < x {
+			if 0.0 <= g.F {
+				salp1 = math.Min(1.0, -x)
+				calp1 = -math.Sqrt(1.0 - salp1*salp1)
+			} else {
+				if -1.0 < x {
+					calp1 = math.Max(0.0, x)
+				} else {
+					calp1 = math.Max(-1.0, x)
+				}
+				salp1 = math.Sqrt(1.0 - calp1*calp1)
+			}
+		} else {
+			k := astroid(x, y)
+			var omg12a float64
+			if 0.0 <= g.F {
+				omg12a = lamscale * (-x * k / (1.0 + k))
+			} else {
+				omg12a = lamscale * (-y * (1.0 + k) / k)
+			}
+			somg12, comg12 = math.Sin(omg12a), -math.Cos(omg12a)
+			salp1 = cbet2 * somg12
+			calp1 = sbet12a - cbet2*sbet1*somg12*somg12/(1.0-comg12)
+		}
+	}
+	if !(salp1 <= 0.0) {
+		salp1, calp1 = norm2(salp1, calp1)
+	} else {
+		salp1, calp1 = 1.0, 0.0
+	}
+	return
+}
+
+type lambda12Result struct {
+	lam12, salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps, domg12, dlam12 float64
+}
+
+func (g *Geodesic) lambda12(sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam120, clam120 float64, diffp bool) lambda12Result {
+	var r lambda12Result
+	if sbet1 == 0.0 && calp1 == 0.0 {
+		calp1 = -geodTiny // break degeneracy of equatorial line
+	}
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+
+	var somg1, comg1, somg2, comg2 float64
+	r.ssig1 = sbet1
+	somg1 = salp0 * sbet1
+	r.csig1 = calp1 * cbet1
+	comg1 = r.csig1
+	r.ssig1, r.csig1 = norm2(r.ssig1, r.csig1)
+
+	if cbet2 != cbet1 {
+		r.salp2 = salp0 / cbet2
+	} else {
+		r.salp2 = salp1
+	}
+	if cbet2 != cbet1 || math.Abs(sbet2) != -sbet1 {
+		var d float64
+		if cbet1 < -sbet1 {
+			d = (cbet2 - cbet1) * (cbet1 + cbet2)
+		} else {
+			d = (sbet1 - sbet2) * (sbet1 + sbet2)
+		}
+		r.calp2 = math.Sqrt((calp1*cbet1)*(calp1*cbet1)+d) / cbet2
+	} else {
+		r.calp2 = math.Abs(calp1)
+	}
+
+	r.ssig2 = sbet2
+	somg2 = salp0 * sbet2
+	r.csig2 = r.calp2 * cbet2
+	comg2 = r.csig2
+	r.ssig2, r.csig2 = norm2(r.ssig2, r.csig2)
+
+	r.sig12 = math.Atan2(math.Max(0.0, r.csig1*r.ssig2-r.ssig1*r.csig2), r.csig1*r.csig2+r.ssig1*r.ssig2)
+	somg12 := math.Max(0.0, comg1*somg2-somg1*comg2)
+	comg12 := comg1*comg2 + somg1*somg2
+	eta := math.Atan2(somg12*clam120-comg12*slam120, comg12*clam120+somg12*slam120)
+
+	var ca [geodOrder]float64
+	k2 := calp0 * calp0 * g.ep2
+	r.eps = k2 / (2.0*(1.0+math.Sqrt(1.0+k2)) + k2)
+	g.c3f(r.eps, ca[:])
+	b312 := sinCosSeries(true, r.ssig2, r.csig2, ca[:], geodOrder-1) - sinCosSeries(true, r.ssig1, r.csig1, ca[:], geodOrder-1)
+	r.domg12 = -g.F * g.a3f(r.eps) * salp0 * (r.sig12 + b312)
+	r.lam12 = eta + r.domg12
+
+	if diffp {
+		if r.calp2 == 0.0 {
+			r.dlam12 = -2.0 * g.f1 * dn1 / sbet1
+		} else {
+			_, m12b, _ := g.lengths(r.eps, r.sig12, r.ssig1, r.csig1, dn1, r.ssig2, r.csig2, dn2)
+			r.dlam12 = m12b * g.f1 / (r.calp2 * cbet2)
+		}
+	}
+	return r
+}
+
+// inverse solves the inverse geodesic problem and returns the distance, the sines and cosines of the azimuths at both points, and the area between the geodesic and the equator.
+func (g *Geodesic) inverse(lat1, lon1, lat2, lon2 float64) (s12, salp1, calp1, salp2, calp2, S12 float64) {
+	lon12, lon12s := angDiff(lon1, lon2)
+	lonsign := 1.0
+	if math.Signbit(lon12) {
+		lonsign = -1.0
+	}
+	lon12 *= lonsign
+	lon12s *= lonsign
+	lam12 := lon12 * geodDegree
+	slam12, clam12 := sincosde(lon12, lon12s)
+	lon12s = (180.0 - lon12) - lon12s // supplementary longitude difference
+
+	// make lat1 <= -|lat2|
+	lat1, lat2 = angRound(latFix(lat1)), angRound(latFix(lat2))
+	swapp := 1.0
+	if math.Abs(lat1) < math.Abs(lat2) || math.IsNaN(lat2) {
+		swapp = -1.0
+		lonsign *= -1.0
+		lat1, lat2 = lat2, lat1
+	}
+	latsign := -1.0
+	if math.Signbit(lat1) {
+		latsign = 1.0
+	}
+	lat1 *= latsign
+	lat2 *= latsign
+
+	sbet1, cbet1 := sincosd(lat1)
+	sbet1, cbet1 = norm2(sbet1*g.f1, cbet1)
+	cbet1 = math.Max(geodTiny, cbet1)
+	sbet2, cbet2 := sincosd(lat2)
+	sbet2, cbet2 = norm2(sbet2*g.f1, cbet2)
+	cbet2 = math.Max(geodTiny, cbet2)
+
+	if cbet1 < -sbet1 {
+		if cbet2 == cbet1 {
+			sbet2 = math.Copysign(sbet1, sbet2)
+		}
+	} else if math.Abs(sbet2) == -sbet1 {
+		cbet2 = cbet1
+	}
+	dn1 := math.Sqrt(1.0 + g.ep2*sbet1*sbet1)
+	dn2 := math.Sqrt(1.0 + g.ep2*sbet2*sbet2)
+
+	var sig12, s12x, omg12 float64
+	somg12, comg12 := 2.0, 0.0
+	meridian := lat1 == -90.0 || slam12 == 0.0
+	if meridian {
+		// along a meridian
+		calp1, salp1 = clam12, slam12
+		calp2, salp2 = 1.0, 0.0
+		ssig1, csig1 := sbet1, calp1*cbet1
+		ssig2, csig2 := sbet2, calp2*cbet2
+		sig12 = math.Atan2(math.Max(0.0, csig1*ssig2-ssig1*csig2), csig1*csig2+ssig1*ssig2)
+		var m12x float64
+		s12x, m12x, _ = g.lengths(g.n, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2)
+		if sig12 < 1.0 || 0.0 <= m12x {
+			if sig12 < 3.0*geodTiny || sig12 < geodTol0 && (s12x < 0.0 || m12x < 0.0) {
+				sig12, s12x = 0.0, 0.0
+			}
+			s12x *= g.b
+		} else {
+			meridian = false // m12 < 0, prolate and too close to anti-podal
+		}
+	}
+
+	if !meridian && sbet1 == 0.0 && (g.F <= 0.0 || g.F*180.0 <= lon12s) {
+		// along the equator
+		calp1, calp2 = 0.0, 0.0
+		salp1, salp2 = 1.0, 1.0
+		s12x = g.A * lam12
+		sig12 = lam12 / g.f1
+		omg12 = sig12
+	} else if !meridian {
+		var dnm float64
+		sig12, salp1, calp1, salp2, calp2, dnm = g.inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2, lam12, slam12, clam12)
+		if 0.0 <= sig12 {
+			// short lines
+			s12x = sig12 * g.b * dnm
+			omg12 = lam12 / (g.f1 * dnm)
+		} else {
+			// Newton's method with bisection as fallback
+			var r lambda12Result
+			salp1a, calp1a, salp1b, calp1b := geodTiny, 1.0, geodTiny, -1.0
+			tripn, tripb := false, false
+			for numit := 0; numit < geodMaxit2; numit++ {
+				r = g.lambda12(sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam12, clam12, numit < geodMaxit1)
+				v := r.lam12
+				salp2, calp2 = r.salp2, r.calp2
+				tol := geodTol0
+				if tripn {
+					tol *= 8.0
+				}
+				if tripb || !(tol <= math.Abs(v)) {
+					break
+				}
+
+				// update bracketing values
+				if 0.0 < v && (geodMaxit1 < numit || calp1b/salp1b < calp1/salp1) {
+					salp1b, calp1b = salp1, calp1
+				} else if v < 0.0 && (geodMaxit1 < numit || calp1/salp1 < calp1a/salp1a) {
+					salp1a, calp1a = salp1, calp1
+				}
+				if numit < geodMaxit1 && 0.0 < r.dlam12 {
+					dalp1 := -v / r.dlam12
+					if math.Abs(dalp1) < math.Pi {
+						sdalp1, cdalp1 := math.Sin(dalp1), math.Cos(dalp1)
+						if nsalp1 := salp1*cdalp1 + calp1*sdalp1; 0.0 < nsalp1 {
+							calp1 = calp1*cdalp1 - salp1*sdalp1
+							salp1 = nsalp1
+							salp1, calp1 = norm2(salp1, calp1)
+							tripn = math.Abs(v) <= 16.0*geodTol0
+							continue
+						}
+					}
+				}
+
+				// use the midpoint of the bracket
+				salp1, calp1 = norm2((salp1a+salp1b)/2.0, (calp1a+calp1b)/2.0)
+				tripn = false
+				tripb = math.Abs(salp1a-salp1)+(calp1a-calp1) < geodTolb || math.Abs(salp1-salp1b)+(calp1-calp1b) < geodTolb
+			}
+			sig12 = r.sig12
+			s12x, _, _ = g.lengths(r.eps, sig12, r.ssig1, r.csig1, dn1, r.ssig2, r.csig2, dn2)
+			s12x *= g.b
+			sdomg12, cdomg12 := math.Sin(r.domg12), math.Cos(r.domg12)
+			somg12 = slam12*cdomg12 - clam12*sdomg12
+			comg12 = clam12*cdomg12 + slam12*sdomg12
+		}
+	}
+	s12 = 0.0 + s12x
+
+	// area between the geodesic and the equator
+	salp0, calp0 := salp1*cbet1, math.Hypot(calp1, salp1*sbet1)
+	if calp0 != 0.0 && salp0 != 0.0 {
+		ssig1, csig1 := norm2(sbet1, calp1*cbet1)
+		ssig2, csig2 := norm2(sbet2, calp2*cbet2)
+		k2 := calp0 * calp0 * g.ep2
+		eps := k2 / (2.0*(1.0+math.Sqrt(1.0+k2)) + k2)
+		a4 := g.A * g.A * calp0 * salp0 * g.e2
+		var ca [geodOrder]float64
+		g.c4f(eps, ca[:])
+		b41 := sinCosSeries(false, ssig1, csig1, ca[:], geodOrder)
+		b42 := sinCosSeries(false, ssig2, csig2, ca[:], geodOrder)
+		S12 = a4 * (b42 - b41)
+	}
+	if !meridian && somg12 == 2.0 {
+		somg12, comg12 = math.Sin(omg12), math.Cos(omg12)
+	}
+	var alp12 float64
+	if !meridian && -geodSqrtHalf < comg12 && sbet2-sbet1 < 1.75 {
+		// use tan(Gamma/2) = tan(omg12/2) * (tan(bet1/2)+tan(bet2/2))/(1+tan(bet1/2)*tan(bet2/2)) with tan(x/2) = sin(x)/(1+cos(x))
+		domg12, dbet1, dbet2 := 1.0+comg12, 1.0+cbet1, 1.0+cbet2
+		alp12 = 2.0 * math.Atan2(somg12*(sbet1*dbet2+sbet2*dbet1), domg12*(sbet1*sbet2+dbet1*dbet2))
+	} else {
+		salp12 := salp2*calp1 - calp2*salp1
+		calp12 := calp2*calp1 + salp2*salp1
+		if salp12 == 0.0 && calp12 < 0.0 {
+			salp12 = geodTiny * calp1
+			calp12 = -1.0
+		}
+		alp12 = math.Atan2(salp12, calp12)
+	}
+	S12 += g.c2 * alp12
+	S12 *= swapp * lonsign * latsign
+	S12 += 0.0
+
+	if swapp < 0.0 {
+		salp1, salp2 = salp2, salp1
+		calp1, calp2 = calp2, calp1
+	}
+	salp1 *= swapp * lonsign
+	calp1 *= swapp * latsign
+	salp2 *= swapp * lonsign
+	calp2 *= swapp * latsign
+	return
+}
+
+// Inverse returns the distance in meters between two points, and the azimuths in degrees clockwise from north at the first and second point.
+func (g *Geodesic) Inverse(lat1, lon1, lat2, lon2 float64) (float64, float64, float64) {
+	s12, salp1, calp1, salp2, calp2, _ := g.inverse(lat1, lon1, lat2, lon2)
+	return s12, atan2d(salp1, calp1), atan2d(salp2, calp2)
+}
+
+// Direct returns the point at distance s12 in meters from the first point in the direction of azimuth azi1 in degrees clockwise from north, and the azimuth at that point.
+func (g *Geodesic) Direct(lat1, lon1, azi1, s12 float64) (float64, float64, float64) {
+	azi1 = angNormalize(azi1)
+	salp1, calp1 := sincosd(angRound(azi1))
+
+	sbet1, cbet1 := sincosd(angRound(latFix(lat1)))
+	sbet1, cbet1 = norm2(sbet1*g.f1, cbet1)
+	cbet1 = math.Max(geodTiny, cbet1)
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+	ssig1, somg1 := sbet1, salp0*sbet1
+	csig1 := 1.0
+	if sbet1 != 0.0 || calp1 != 0.0 {
+		csig1 = cbet1 * calp1
+	}
+	comg1 := csig1
+	ssig1, csig1 = norm2(ssig1, csig1)
+
+	k2 := calp0 * calp0 * g.ep2
+	eps := k2 / (2.0*(1.0+math.Sqrt(1.0+k2)) + k2)
+	var c1a, c1pa [geodOrder + 1]float64
+	var c3a [geodOrder]float64
+	a1m1 := a1m1f(eps)
+	c1f(eps, c1a[:])
+	c1pf(eps, c1pa[:])
+	b11 := sinCosSeries(true, ssig1, csig1, c1a[:], geodOrder)
+	s, c := math.Sin(b11), math.Cos(b11)
+	stau1 := ssig1*c + csig1*s
+	ctau1 := csig1*c - ssig1*s
+	a3c := -g.F * salp0 * g.a3f(eps)
+	g.c3f(eps, c3a[:])
+	b31 := sinCosSeries(true, ssig1, csig1, c3a[:], geodOrder-1)
+
+	tau12 := s12 / (g.b * (1.0 + a1m1))
+	s, c = math.Sin(tau12), math.Cos(tau12)
+	b12 := -sinCosSeries(true, stau1*c+ctau1*s, ctau1*c-stau1*s, c1pa[:], geodOrder)
+	sig12 := tau12 - (b12 - b11)
+	ssig12, csig12 := math.Sin(sig12), math.Cos(sig12)
+	if 0.01 < math.Abs(g.F) {
+		// reverted series is not accurate enough, take one step of Newton's method
+		ssig2 := ssig1*csig12 + csig1*ssig12
+		csig2 := csig1*csig12 - ssig1*ssig12
+		b12 = sinCosSeries(true, ssig2, csig2, c1a[:], geodOrder)
+		serr := (1.0+a1m1)*(sig12+(b12-b11)) - s12/g.b
+		sig12 -= serr / math.Sqrt(1.0+k2*ssig2*ssig2)
+		ssig12, csig12 = math.Sin(sig12), math.Cos(sig12)
+	}
+	ssig2 := ssig1*csig12 + csig1*ssig12
+	csig2 := csig1*csig12 - ssig1*ssig12
+	sbet2 := calp0 * ssig2
+	cbet2 := math.Hypot(salp0, calp0*csig2)
+	if cbet2 == 0.0 {
+		cbet2, csig2 = geodTiny, geodTiny
+	}
+	salp2, calp2 := salp0, calp0*csig2
+
+	somg2, comg2 := salp0*ssig2, csig2
+	omg12 := math.Atan2(somg2*comg1-comg2*somg1, comg2*comg1+somg2*somg1)
+	lam12 := omg12 + a3c*(sig12+(sinCosSeries(true, ssig2, csig2, c3a[:], geodOrder-1)-b31))
+	lon2 := angNormalize(angNormalize(lon1) + angNormalize(lam12/geodDegree))
+	lat2 := atan2d(sbet2, g.f1*cbet2)
+	return lat2, lon2, atan2d(salp2, calp2)
+}
+
+// transit counts crossings of the prime meridian by an edge, which is used for polygon areas.
+func transit(lon1, lon2 float64) int {
+	lon12, _ := angDiff(lon1, lon2)
+	lon1, lon2 = angNormalize(lon1), angNormalize(lon2)
+	if 0.0 < lon12 && (lon1 < 0.0 && 0.0 <= lon2 || 0.0 < lon1 && lon2 == 0.0) {
+		return 1
+	} else if lon12 < 0.0 && 0.0 <= lon1 && lon2 < 0.0 {
+		return -1
+	}
+	return 0
+}
+
+// PolygonArea returns the signed area in square meters and the perimeter in meters of the ring, whose edges are geodesics. The area is positive for counter-clockwise rings. The ring is closed automatically.
+func (g *Geodesic) PolygonArea(ring []Coord) (float64, float64) {
+	if 0 < len(ring) && ring[0] == ring[len(ring)-1] {
+		ring = ring[:len(ring)-1]
+	}
+	if len(ring) < 2 {
+		return 0.0, 0.0
+	}
+
+	area, perimeter, crossings := 0.0, 0.0, 0
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		s12, _, _, _, _, S12 := g.inverse(p.Y, p.X, q.Y, q.X)
+		perimeter += s12
+		area -= S12
+		crossings += transit(p.X, q.X)
+	}
+
+	// reduce to the range of a single polygon
+	area0 := 4.0 * math.Pi * g.c2
+	area = math.Remainder(area, area0)
+	if crossings&1 == 1 {
+		if area < 0.0 {
+			area += area0 / 2.0
+		} else {
+			area -= area0 / 2.0
+		}
+	}
+	if area0/2.0 < area {
+		area -= area0
+	} else if area <= -area0/2.0 {
+		area += area0
+	}
+	return area + 0.0, perimeter
+}
+
+// Distance returns the geodesic distance in meters between two coordinates on WGS84.
+func (p Coord) Distance(q Coord) float64 {
+	s12, _, _, _, _, _ := WGS84Geodesic.inverse(p.Y, p.X, q.Y, q.X)
+	return s12
+}
+
+// Bearing returns the initial bearing in degrees clockwise from north of the geodesic from p to q on WGS84.
+func (p Coord) Bearing(q Coord) float64 {
+	_, azi1, _ := WGS84Geodesic.Inverse(p.Y, p.X, q.Y, q.X)
+	return azi1
+}
+
+// Destination returns the coordinate at the given distance in meters from p along the geodesic with the initial bearing in degrees clockwise from north on WGS84.
+func (p Coord) Destination(bearing, distance float64) Coord {
+	lat, lon, _ := WGS84Geodesic.Direct(p.Y, p.X, bearing, distance)
+	return Coord{lon, lat}
+}
+
+// SegmentDistance returns the geodesic distance in meters from p to the closest point on the geodesic segment between a and b on WGS84.
+func (p Coord) SegmentDistance(a, b Coord) float64 {
+	sab, aziab, _ := WGS84Geodesic.Inverse(a.Y, a.X, b.Y, b.X)
+	d, azi1, _ := WGS84Geodesic.Inverse(a.Y, a.X, p.Y, p.X)
+	if sab == 0.0 {
+		return d
+	}
+
+	// step along the segment until the geodesic to p is perpendicular, estimating each step by spherical trigonometry
+	s, azi, azi2 := 0.0, aziab, azi1
+	for i := 0; i < 20; i++ {
+		sigma := d / WGS84Geodesic.A
+		ds := WGS84Geodesic.A * math.Atan2(math.Sin(sigma)*math.Cos((azi2-azi)*geodDegree), math.Cos(sigma))
+		ds = math.Max(-s, math.Min(ds, sab-s))
+		if math.Abs(ds) < 1e-4 {
+			break
+		}
+		s += ds
+
+		var lat, lon float64
+		lat, lon, azi = WGS84Geodesic.Direct(a.Y, a.X, aziab, s)
+		d, azi2, _ = WGS84Geodesic.Inverse(lat, lon, p.Y, p.X)
+	}
+	return d
+}
+
+// GeodesicLength returns the length in meters of the line string along geodesics on WGS84.
+func GeodesicLength(coords []Coord) float64 {
+	length := 0.0
+	for i := 1; i < len(coords); i++ {
+		length += coords[i-1].Distance(coords[i])
+	}
+	return length
+}
+
+// GeodesicArea returns the signed area in square meters of the ring with geodesic edges on WGS84, which is positive for counter-clockwise rings.
+func GeodesicArea(ring []Coord) float64 {
+	area, _ := WGS84Geodesic.PolygonArea(ring)
+	return area
+}
+
+// Area returns the area in square meters of the polygon on WGS84, with the area of the holes subtracted.
+func (polygon PolygonWithHoles) Area() float64 {
+	area := math.Abs(GeodesicArea(polygon.Outer))
+	for _, hole := range polygon.Holes {
+		area -= math.Abs(GeodesicArea(hole))
+	}
+	return area
+}
+
+// Perimeter returns the length in meters of the outer ring and holes of the polygon on WGS84.
+func (polygon PolygonWithHoles) Perimeter() float64 {
+	_, perimeter := WGS84Geodesic.PolygonArea(polygon.Outer)
+	for _, hole := range polygon.Holes {
+		_, holePerimeter := WGS84Geodesic.PolygonArea(hole)
+		perimeter += holePerimeter
+	}
+	return perimeter
+}
+
+// Length returns the total length in meters of the line strings of the geometry on WGS84.
+func (g Geometry) Length() float64 {
+	length := 0.0
+	for _, lineString := range g.LineStrings {
+		length += GeodesicLength(lineString)
+	}
+	return length
+}
+
+// Area returns the total area in square meters of the polygons of the geometry on WGS84.
+func (g Geometry) Area() float64 {
+	area := 0.0
+	for _, polygon := range g.Polygons {
+		area += polygon.Area()
+	}
+	return area
+}
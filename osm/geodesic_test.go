@@ -0,0 +1,126 @@
+package osm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeodesicInverse(t *testing.T) {
+	// JFK to LHR, see GeographicLib
+	s12, azi1, azi2 := WGS84Geodesic.Inverse(40.6, -73.8, 51.6, -0.5)
+	if 1e-6 < math.Abs(s12-5551759.400319) || 1e-9 < math.Abs(azi1-51.198882845579) || 1e-9 < math.Abs(azi2-107.821776735514) {
+		t.Errorf("expected 5551759.400319 51.198882845579 107.821776735514, got %v %v %v", s12, azi1, azi2)
+	}
+
+	tests := []struct {
+		p, q Coord
+		s12  float64
+	}{
+		{Coord{0, 0}, Coord{1, 0}, 6378137.0 * math.Pi / 180.0}, // equator
+		{Coord{0, 0}, Coord{0, 90}, 10001965.729},               // quarter meridian
+		{Coord{0, 0}, Coord{179.5, 0.5}, 19936288.579},          // nearly antipodal
+		{Coord{5, 52}, Coord{5, 52}, 0.0},
+	}
+	for _, tt := range tests {
+		if s12 := tt.p.Distance(tt.q); 1e-3 < math.Abs(s12-tt.s12) {
+			t.Errorf("%v to %v: expected %v, got %v", tt.p, tt.q, tt.s12, s12)
+		}
+		if s21 := tt.q.Distance(tt.p); 1e-3 < math.Abs(s21-tt.s12) {
+			t.Errorf("%v to %v: expected %v, got %v", tt.q, tt.p, tt.s12, s21)
+		}
+	}
+}
+
+func TestGeodesicDirect(t *testing.T) {
+	lat2, lon2, azi2 := WGS84Geodesic.Direct(40.6, -73.8, 51.198882845579, 5551759.400319)
+	if 1e-9 < math.Abs(lat2-51.6) || 1e-9 < math.Abs(lon2 - -0.5) || 1e-9 < math.Abs(azi2-107.821776735514) {
+		t.Errorf("expected 51.6 -0.5 107.821776735514, got %v %v %v", lat2, lon2, azi2)
+	}
+
+	p := Coord{6.57, 53.16}
+	for _, bearing := range []float64{0.0, 45.0, 90.0, 180.0, -135.0} {
+		q := p.Destination(bearing, 12345.0)
+		if d := p.Distance(q); 1e-6 < math.Abs(d-12345.0) {
+			t.Errorf("bearing %v: expected distance 12345, got %v", bearing, d)
+		}
+		if b := p.Bearing(q); 1e-9 < math.Abs(math.Remainder(b-bearing, 360.0)) {
+			t.Errorf("expected bearing %v, got %v", bearing, b)
+		}
+	}
+}
+
+func TestGeodesicArea(t *testing.T) {
+	// see the planimeter tests of GeographicLib
+	tests := []struct {
+		name      string
+		ring      []Coord
+		area      float64
+		perimeter float64
+	}{
+		{"north", []Coord{{0, 89}, {90, 89}, {180, 89}, {270, 89}}, 24952305678.0, 631819.8745},
+		{"south", []Coord{{0, -89}, {90, -89}, {180, -89}, {270, -89}}, -24952305678.0, 631819.8745},
+		{"diamond", []Coord{{-1, 0}, {0, -1}, {1, 0}, {0, 1}, {-1, 0}}, 24619419146.0, 627598.2731},
+		{"octant", []Coord{{0, 90}, {0, 0}, {90, 0}}, 63758202715511.0, 30022685.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			area, perimeter := WGS84Geodesic.PolygonArea(tt.ring)
+			if 1.0 < math.Abs(area-tt.area) || 1.0 < math.Abs(perimeter-tt.perimeter) {
+				t.Errorf("expected %v %v, got %v %v", tt.area, tt.perimeter, area, perimeter)
+			}
+		})
+	}
+
+	// lune of one degree between the equator and the north pole
+	lune := []Coord{{0, 0}, {1, 0}, {1, 90}, {0, 90}, {0, 0}}
+	if area := GeodesicArea(lune); 1.0 < math.Abs(area-5.10065621724e14/720.0) {
+		t.Errorf("expected %v, got %v", 5.10065621724e14/720.0, area)
+	}
+}
+
+func TestGeometryMeasurements(t *testing.T) {
+	square := []Coord{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	hole := []Coord{{0.25, 0.25}, {0.25, 0.75}, {0.75, 0.75}, {0.75, 0.25}, {0.25, 0.25}}
+	g := Geometry{
+		LineStrings: [][]Coord{{{0, 0}, {1, 0}, {2, 0}}, {{0, 0}, {0, 1}}},
+		Polygons:    []PolygonWithHoles{{Outer: square, Holes: [][]Coord{hole}}},
+	}
+	if length, expected := g.Length(), 2.0*6378137.0*math.Pi/180.0+110574.389; 1e-3 < math.Abs(length-expected) {
+		t.Errorf("expected %v, got %v", expected, length)
+	}
+	if area, expected := g.Area(), GeodesicArea(square)+GeodesicArea(hole); 1e-3 < math.Abs(area-expected) {
+		t.Errorf("expected %v, got %v", expected, area)
+	}
+	if area := GeodesicArea(square); 1e8 < math.Abs(area-12308778361.0) {
+		t.Errorf("expected about 12308778361, got %v", area)
+	}
+	if perimeter, expected := g.Polygons[0].Perimeter(), GeodesicLength(square)+GeodesicLength(hole); 1e-3 < math.Abs(perimeter-expected) {
+		t.Errorf("expected %v, got %v", expected, perimeter)
+	}
+}
+
+func TestSegmentDistance(t *testing.T) {
+	a, b := Coord{-1, 0}, Coord{1, 0}
+	if d, expected := (Coord{0, 1}).SegmentDistance(a, b), (Coord{0, 0}).Distance(Coord{0, 1}); 1e-3 < math.Abs(d-expected) {
+		t.Errorf("expected %v, got %v", expected, d)
+	}
+	if d, expected := (Coord{3, 1}).SegmentDistance(a, b), (Coord{3, 1}).Distance(b); 1e-3 < math.Abs(d-expected) {
+		t.Errorf("expected %v, got %v", expected, d)
+	}
+	if d := (Coord{0.5, 0}).SegmentDistance(a, b); 1e-3 < d {
+		t.Errorf("expected 0, got %v", d)
+	}
+
+	// compare against sampling along the segment
+	a, b = Coord{4.9, 52.3}, Coord{6.6, 53.2}
+	p := Coord{5.5, 53.0}
+	s, azi, _ := WGS84Geodesic.Inverse(a.Y, a.X, b.Y, b.X)
+	expected := math.Inf(1)
+	for i := 0; i <= 10000; i++ {
+		lat, lon, _ := WGS84Geodesic.Direct(a.Y, a.X, azi, s*float64(i)/10000.0)
+		expected = math.Min(expected, p.Distance(Coord{lon, lat}))
+	}
+	if d := p.SegmentDistance(a, b); d < expected-1e-3 || expected+0.01 < d {
+		t.Errorf("expected %v, got %v", expected, d)
+	}
+}
@@ -0,0 +1,466 @@
+package osm
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+const gpxNamespace = "http://www.topografix.com/GPX/1/1"
+
+// GPXMetadata is the metadata of a GPX file.
+type GPXMetadata struct {
+	Name string
+	Desc string
+	Time time.Time
+}
+
+// GPXPoint is a waypoint, route point, or track point of a GPX file. Extensions is the raw XML content of the extensions element.
+type GPXPoint struct {
+	Coord      Coord
+	Elevation  float64 // meters, NaN if unknown
+	Time       time.Time
+	Name       string
+	Desc       string
+	Symbol     string
+	Type       string
+	Extensions []byte
+}
+
+// GPXRoute is a route of a GPX file, which is an ordered list of points leading to a destination.
+type GPXRoute struct {
+	Name       string
+	Desc       string
+	Type       string
+	Extensions []byte
+	Points     []GPXPoint
+}
+
+// GPXTrack is a track of a GPX file, which is a recorded path consisting of segments.
+type GPXTrack struct {
+	Name       string
+	Desc       string
+	Type       string
+	Extensions []byte
+	Segments   [][]GPXPoint
+}
+
+// GPX is the content of a GPX file. Namespaces maps prefixes to URIs of the namespaces declared on the root element, such as those used by extensions.
+type GPX struct {
+	Creator    string
+	Namespaces map[string]string
+	Metadata   GPXMetadata
+	Waypoints  []GPXPoint
+	Routes     []GPXRoute
+	Tracks     []GPXTrack
+}
+
+// NewGPXPoint returns a point without elevation.
+func NewGPXPoint(c Coord) GPXPoint {
+	return GPXPoint{Coord: c, Elevation: math.NaN()}
+}
+
+// NewGPXTrack returns a track of the line strings of a geometry, such as a route=hiking relation returned by Extract, with one segment per line string. The name, description, and type are taken from the name, description, and route tags.
+func NewGPXTrack(geom Geometry) GPXTrack {
+	track := GPXTrack{
+		Name: geom.Tags.Find("name"),
+		Desc: geom.Tags.Find("description"),
+		Type: geom.Tags.Find("route"),
+	}
+	for _, lineString := range geom.LineStrings {
+		segment := make([]GPXPoint, len(lineString))
+		for i, c := range lineString {
+			segment[i] = NewGPXPoint(c)
+		}
+		track.Segments = append(track.Segments, segment)
+	}
+	return track
+}
+
+// Trace returns the points of all segments of the track as a trace, which can be matched to the roads with MatchTrace.
+func (t GPXTrack) Trace() []TracePoint {
+	trace := []TracePoint{}
+	for _, segment := range t.Segments {
+		for _, point := range segment {
+			trace = append(trace, TracePoint{point.Coord, point.Time})
+		}
+	}
+	return trace
+}
+
+type gpxExtensionsXML struct {
+	Inner []byte `xml:",innerxml"`
+}
+
+func newGPXExtensionsXML(b []byte) *gpxExtensionsXML {
+	if len(b) == 0 {
+		return nil
+	}
+	return &gpxExtensionsXML{b}
+}
+
+func (e *gpxExtensionsXML) bytes() []byte {
+	if e == nil {
+		return nil
+	}
+	return e.Inner
+}
+
+type gpxMetadataXML struct {
+	Name string `xml:"name,omitempty"`
+	Desc string `xml:"desc,omitempty"`
+	Time string `xml:"time,omitempty"`
+}
+
+type gpxPointXML struct {
+	Lat        float64           `xml:"lat,attr"`
+	Lon        float64           `xml:"lon,attr"`
+	Ele        *float64          `xml:"ele,omitempty"`
+	Time       string            `xml:"time,omitempty"`
+	Name       string            `xml:"name,omitempty"`
+	Desc       string            `xml:"desc,omitempty"`
+	Sym        string            `xml:"sym,omitempty"`
+	Type       string            `xml:"type,omitempty"`
+	Extensions *gpxExtensionsXML `xml:"extensions,omitempty"`
+}
+
+type gpxRouteXML struct {
+	Name       string            `xml:"name,omitempty"`
+	Desc       string            `xml:"desc,omitempty"`
+	Type       string            `xml:"type,omitempty"`
+	Extensions *gpxExtensionsXML `xml:"extensions,omitempty"`
+	Points     []gpxPointXML     `xml:"rtept"`
+}
+
+type gpxSegmentXML struct {
+	Points []gpxPointXML `xml:"trkpt"`
+}
+
+type gpxTrackXML struct {
+	Name       string            `xml:"name,omitempty"`
+	Desc       string            `xml:"desc,omitempty"`
+	Type       string            `xml:"type,omitempty"`
+	Extensions *gpxExtensionsXML `xml:"extensions,omitempty"`
+	Segments   []gpxSegmentXML   `xml:"trkseg"`
+}
+
+func parseGPXTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid GPX time %q", s)
+	}
+	return t, nil
+}
+
+func formatGPXTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func (p gpxPointXML) point() (GPXPoint, error) {
+	t, err := parseGPXTime(p.Time)
+	if err != nil {
+		return GPXPoint{}, err
+	}
+	point := GPXPoint{
+		Coord:      Coord{p.Lon, p.Lat},
+		Elevation:  math.NaN(),
+		Time:       t,
+		Name:       p.Name,
+		Desc:       p.Desc,
+		Symbol:     p.Sym,
+		Type:       p.Type,
+		Extensions: p.Extensions.bytes(),
+	}
+	if p.Ele != nil {
+		point.Elevation = *p.Ele
+	}
+	return point, nil
+}
+
+func newGPXPointXML(p GPXPoint) gpxPointXML {
+	point := gpxPointXML{
+		Lat:        p.Coord.Y,
+		Lon:        p.Coord.X,
+		Time:       formatGPXTime(p.Time),
+		Name:       p.Name,
+		Desc:       p.Desc,
+		Sym:        p.Symbol,
+		Type:       p.Type,
+		Extensions: newGPXExtensionsXML(p.Extensions),
+	}
+	if !math.IsNaN(p.Elevation) {
+		point.Ele = &p.Elevation
+	}
+	return point
+}
+
+func gpxPoints(points []gpxPointXML) ([]GPXPoint, error) {
+	ps := make([]GPXPoint, len(points))
+	for i, p := range points {
+		var err error
+		if ps[i], err = p.point(); err != nil {
+			return nil, err
+		}
+	}
+	return ps, nil
+}
+
+// ParseGPX parses a GPX 1.1 file while streaming, calling the functions for each waypoint, route, and track, which may be nil. Only a single route or track is kept in memory at a time. It returns the creator, the namespaces declared on the root element by prefix, and the metadata of the file.
+func ParseGPX(r io.Reader, waypointFn func(GPXPoint), routeFn func(GPXRoute), trackFn func(GPXTrack)) (string, map[string]string, GPXMetadata, error) {
+	decoder := xml.NewDecoder(r)
+	creator, namespaces, metadata := "", map[string]string{}, GPXMetadata{}
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			if depth == 0 {
+				return "", nil, GPXMetadata{}, fmt.Errorf("missing gpx element")
+			}
+			return creator, namespaces, metadata, nil
+		} else if err != nil {
+			return "", nil, GPXMetadata{}, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				if t.Name.Local != "gpx" {
+					return "", nil, GPXMetadata{}, fmt.Errorf("unexpected root element %v", t.Name.Local)
+				}
+				for _, attr := range t.Attr {
+					if attr.Name.Space == "xmlns" {
+						namespaces[attr.Name.Local] = attr.Value
+					} else if attr.Name.Space == "" && attr.Name.Local == "creator" {
+						creator = attr.Value
+					}
+				}
+				depth++
+				continue
+			}
+
+			switch t.Name.Local {
+			case "metadata":
+				var m gpxMetadataXML
+				if err := decoder.DecodeElement(&m, &t); err != nil {
+					return "", nil, GPXMetadata{}, err
+				}
+				metadata.Name, metadata.Desc = m.Name, m.Desc
+				if metadata.Time, err = parseGPXTime(m.Time); err != nil {
+					return "", nil, GPXMetadata{}, err
+				}
+			case "wpt":
+				var p gpxPointXML
+				if err := decoder.DecodeElement(&p, &t); err != nil {
+					return "", nil, GPXMetadata{}, err
+				}
+				point, err := p.point()
+				if err != nil {
+					return "", nil, GPXMetadata{}, err
+				}
+				if waypointFn != nil {
+					waypointFn(point)
+				}
+			case "rte":
+				var rte gpxRouteXML
+				if err := decoder.DecodeElement(&rte, &t); err != nil {
+					return "", nil, GPXMetadata{}, err
+				}
+				points, err := gpxPoints(rte.Points)
+				if err != nil {
+					return "", nil, GPXMetadata{}, err
+				}
+				if routeFn != nil {
+					routeFn(GPXRoute{rte.Name, rte.Desc, rte.Type, rte.Extensions.bytes(), points})
+				}
+			case "trk":
+				var trk gpxTrackXML
+				if err := decoder.DecodeElement(&trk, &t); err != nil {
+					return "", nil, GPXMetadata{}, err
+				}
+				track := GPXTrack{trk.Name, trk.Desc, trk.Type, trk.Extensions.bytes(), nil}
+				for _, segment := range trk.Segments {
+					points, err := gpxPoints(segment.Points)
+					if err != nil {
+						return "", nil, GPXMetadata{}, err
+					}
+					track.Segments = append(track.Segments, points)
+				}
+				if trackFn != nil {
+					trackFn(track)
+				}
+			default:
+				if err := decoder.Skip(); err != nil {
+					return "", nil, GPXMetadata{}, err
+				}
+			}
+		}
+	}
+}
+
+// ReadGPX reads a GPX 1.1 file into memory, see ParseGPX.
+func ReadGPX(r io.Reader) (*GPX, error) {
+	gpx := &GPX{}
+	waypointFn := func(point GPXPoint) {
+		gpx.Waypoints = append(gpx.Waypoints, point)
+	}
+	routeFn := func(route GPXRoute) {
+		gpx.Routes = append(gpx.Routes, route)
+	}
+	trackFn := func(track GPXTrack) {
+		gpx.Tracks = append(gpx.Tracks, track)
+	}
+	var err error
+	if gpx.Creator, gpx.Namespaces, gpx.Metadata, err = ParseGPX(r, waypointFn, routeFn, trackFn); err != nil {
+		return nil, err
+	}
+	return gpx, nil
+}
+
+// GPXWriter writes a GPX 1.1 file while streaming. The metadata, waypoints, routes, and tracks must be written in that order, and Close must be called to finish the file. Namespaces maps prefixes to URIs that are declared on the root element, such as those used by extensions, and must be set before writing.
+type GPXWriter struct {
+	Namespaces map[string]string
+
+	w       *bufio.Writer
+	encoder *xml.Encoder
+	state   int // 0 is before the header, 1 metadata, 2 waypoints, 3 routes, 4 tracks, 5 closed
+	creator string
+}
+
+// NewGPXWriter returns a new GPX writer with the given creator, or github.com/tdewolff/geo if empty.
+func NewGPXWriter(w io.Writer, creator string) *GPXWriter {
+	if creator == "" {
+		creator = "github.com/tdewolff/geo"
+	}
+	bw := bufio.NewWriter(w)
+	encoder := xml.NewEncoder(bw)
+	encoder.Indent("  ", "  ")
+	return &GPXWriter{
+		w:       bw,
+		encoder: encoder,
+		creator: creator,
+	}
+}
+
+func (w *GPXWriter) advance(state int) error {
+	if state < w.state || w.state == 5 {
+		return fmt.Errorf("GPX elements must be written in the order metadata, waypoints, routes, tracks")
+	}
+	if w.state == 0 {
+		if _, err := w.w.WriteString(xml.Header + "<gpx version=\"1.1\" creator=\""); err != nil {
+			return err
+		} else if err := xml.EscapeText(w.w, []byte(w.creator)); err != nil {
+			return err
+		} else if _, err := w.w.WriteString("\" xmlns=\"" + gpxNamespace + "\""); err != nil {
+			return err
+		}
+		for _, prefix := range slices.Sorted(maps.Keys(w.Namespaces)) {
+			if _, err := w.w.WriteString(" xmlns:" + prefix + "=\""); err != nil {
+				return err
+			} else if err := xml.EscapeText(w.w, []byte(w.Namespaces[prefix])); err != nil {
+				return err
+			} else if err := w.w.WriteByte('"'); err != nil {
+				return err
+			}
+		}
+		if err := w.w.WriteByte('>'); err != nil {
+			return err
+		}
+	}
+	w.state = state
+	return nil
+}
+
+// WriteMetadata writes the metadata, which must be written before anything else.
+func (w *GPXWriter) WriteMetadata(metadata GPXMetadata) error {
+	if err := w.advance(1); err != nil {
+		return err
+	} else if w.state == 1 && metadata != (GPXMetadata{}) {
+		m := gpxMetadataXML{metadata.Name, metadata.Desc, formatGPXTime(metadata.Time)}
+		return w.encoder.EncodeElement(m, xml.StartElement{Name: xml.Name{Local: "metadata"}})
+	}
+	return nil
+}
+
+// WriteWaypoint writes a waypoint.
+func (w *GPXWriter) WriteWaypoint(point GPXPoint) error {
+	if err := w.advance(2); err != nil {
+		return err
+	}
+	return w.encoder.EncodeElement(newGPXPointXML(point), xml.StartElement{Name: xml.Name{Local: "wpt"}})
+}
+
+// WriteRoute writes a route.
+func (w *GPXWriter) WriteRoute(route GPXRoute) error {
+	if err := w.advance(3); err != nil {
+		return err
+	}
+	rte := gpxRouteXML{route.Name, route.Desc, route.Type, newGPXExtensionsXML(route.Extensions), nil}
+	for _, point := range route.Points {
+		rte.Points = append(rte.Points, newGPXPointXML(point))
+	}
+	return w.encoder.EncodeElement(rte, xml.StartElement{Name: xml.Name{Local: "rte"}})
+}
+
+// WriteTrack writes a track.
+func (w *GPXWriter) WriteTrack(track GPXTrack) error {
+	if err := w.advance(4); err != nil {
+		return err
+	}
+	trk := gpxTrackXML{track.Name, track.Desc, track.Type, newGPXExtensionsXML(track.Extensions), nil}
+	for _, segment := range track.Segments {
+		seg := gpxSegmentXML{}
+		for _, point := range segment {
+			seg.Points = append(seg.Points, newGPXPointXML(point))
+		}
+		trk.Segments = append(trk.Segments, seg)
+	}
+	return w.encoder.EncodeElement(trk, xml.StartElement{Name: xml.Name{Local: "trk"}})
+}
+
+// Close finishes the file and flushes the output.
+func (w *GPXWriter) Close() error {
+	if err := w.advance(5); err != nil {
+		return err
+	} else if err := w.encoder.Flush(); err != nil {
+		return err
+	} else if _, err := w.w.WriteString("\n</gpx>\n"); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// WriteGPX writes the GPX content as a GPX 1.1 file.
+func WriteGPX(w io.Writer, gpx *GPX) error {
+	writer := NewGPXWriter(w, gpx.Creator)
+	writer.Namespaces = gpx.Namespaces
+	if err := writer.WriteMetadata(gpx.Metadata); err != nil {
+		return err
+	}
+	for _, point := range gpx.Waypoints {
+		if err := writer.WriteWaypoint(point); err != nil {
+			return err
+		}
+	}
+	for _, route := range gpx.Routes {
+		if err := writer.WriteRoute(route); err != nil {
+			return err
+		}
+	}
+	for _, track := range gpx.Tracks {
+		if err := writer.WriteTrack(track); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
@@ -0,0 +1,156 @@
+package osm
+
+import (
+	"bytes"
+	"encoding/xml"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <metadata><name>Walk</name><time>2024-05-01T10:00:00Z</time></metadata>
+  <wpt lat="53.21" lon="6.57"><ele>4.5</ele><name>Start</name><sym>Flag</sym></wpt>
+  <rte><name>Route</name><rtept lat="53.21" lon="6.57"/><rtept lat="53.22" lon="6.58"/></rte>
+  <trk>
+    <name>Track</name>
+    <extensions><color>red</color></extensions>
+    <trkseg>
+      <trkpt lat="53.21" lon="6.57"><ele>4.5</ele><time>2024-05-01T10:00:00Z</time></trkpt>
+      <trkpt lat="53.22" lon="6.58"><time>2024-05-01T10:01:00Z</time><extensions><hr>120</hr></extensions></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="53.23" lon="6.59"/>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func testGPXContent(t *testing.T, gpx *GPX) {
+	t.Helper()
+	t0 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	if gpx.Metadata.Name != "Walk" || !gpx.Metadata.Time.Equal(t0) {
+		t.Errorf("wrong metadata %v", gpx.Metadata)
+	}
+	if len(gpx.Waypoints) != 1 || gpx.Waypoints[0].Coord != (Coord{6.57, 53.21}) || gpx.Waypoints[0].Elevation != 4.5 || gpx.Waypoints[0].Name != "Start" || gpx.Waypoints[0].Symbol != "Flag" {
+		t.Errorf("wrong waypoints %v", gpx.Waypoints)
+	}
+	if len(gpx.Routes) != 1 || gpx.Routes[0].Name != "Route" || len(gpx.Routes[0].Points) != 2 || !math.IsNaN(gpx.Routes[0].Points[1].Elevation) {
+		t.Errorf("wrong routes %v", gpx.Routes)
+	}
+	if len(gpx.Tracks) != 1 || len(gpx.Tracks[0].Segments) != 2 || len(gpx.Tracks[0].Segments[0]) != 2 || len(gpx.Tracks[0].Segments[1]) != 1 {
+		t.Fatalf("wrong tracks %v", gpx.Tracks)
+	}
+	track := gpx.Tracks[0]
+	if track.Name != "Track" || string(track.Extensions) != "<color>red</color>" {
+		t.Errorf("wrong track %v %s", track.Name, track.Extensions)
+	}
+	if p := track.Segments[0][1]; p.Coord != (Coord{6.58, 53.22}) || !p.Time.Equal(t0.Add(time.Minute)) || string(p.Extensions) != "<hr>120</hr>" {
+		t.Errorf("wrong track point %v", p)
+	}
+}
+
+func TestReadGPX(t *testing.T) {
+	gpx, err := ReadGPX(strings.NewReader(testGPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gpx.Creator != "test" {
+		t.Errorf("wrong creator %v", gpx.Creator)
+	}
+	testGPXContent(t, gpx)
+
+	trace := gpx.Tracks[0].Trace()
+	if len(trace) != 3 || trace[2].Coord != (Coord{6.59, 53.23}) || !trace[2].Time.IsZero() {
+		t.Errorf("wrong trace %v", trace)
+	}
+
+	if _, err := ReadGPX(strings.NewReader(`<kml></kml>`)); err == nil {
+		t.Errorf("expected error for wrong root element")
+	}
+	if _, err := ReadGPX(strings.NewReader(`<gpx><wpt lat="1" lon="2"><time>noon</time></wpt></gpx>`)); err == nil {
+		t.Errorf("expected error for invalid time")
+	}
+}
+
+func TestWriteGPX(t *testing.T) {
+	gpx, err := ReadGPX(strings.NewReader(testGPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := WriteGPX(&b, gpx); err != nil {
+		t.Fatal(err)
+	}
+	gpx2, err := ReadGPX(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGPXContent(t, gpx2)
+
+	// elements must be written in order
+	w := NewGPXWriter(&b, "")
+	if err := w.WriteTrack(GPXTrack{}); err != nil {
+		t.Error(err)
+	} else if err := w.WriteWaypoint(NewGPXPoint(Coord{})); err == nil {
+		t.Errorf("expected error for waypoint after track")
+	}
+
+	geom := Geometry{
+		Type:        RelationType,
+		LineStrings: [][]Coord{{{6.57, 53.21}, {6.58, 53.22}}},
+		Tags:        Tags{{"name", "Pieterpad"}, {"route", "hiking"}},
+	}
+	track := NewGPXTrack(geom)
+	if track.Name != "Pieterpad" || track.Type != "hiking" || len(track.Segments) != 1 || len(track.Segments[0]) != 2 || !math.IsNaN(track.Segments[0][0].Elevation) {
+		t.Errorf("wrong track %v", track)
+	}
+	b.Reset()
+	if err := WriteGPX(&b, &GPX{Tracks: []GPXTrack{track}}); err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(b.String(), "<ele>") || strings.Contains(b.String(), "<metadata>") {
+		t.Errorf("unexpected elevation or metadata in %v", b.String())
+	}
+}
+
+func TestGPXNamespaces(t *testing.T) {
+	const ns = "http://www.garmin.com/xmlschemas/TrackPointExtension/v1"
+	gpx, err := ReadGPX(strings.NewReader(`<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1" xmlns:gpxtpx="` + ns + `">
+  <trk><trkseg><trkpt lat="53.21" lon="6.57"><extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>120</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions></trkpt></trkseg></trk>
+</gpx>`))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(gpx.Namespaces) != 1 || gpx.Namespaces["gpxtpx"] != ns {
+		t.Errorf("wrong namespaces %v", gpx.Namespaces)
+	}
+
+	var b bytes.Buffer
+	if err := WriteGPX(&b, gpx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the extension must resolve to the same namespace
+	type hr struct {
+		XMLName xml.Name
+		Value   int `xml:",chardata"`
+	}
+	var ext struct {
+		HR hr `xml:"http://www.garmin.com/xmlschemas/TrackPointExtension/v1 TrackPointExtension>hr"`
+	}
+	decoder := xml.NewDecoder(&b)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			t.Fatal(err)
+		} else if start, ok := token.(xml.StartElement); ok && start.Name.Local == "extensions" {
+			if err := decoder.DecodeElement(&ext, &start); err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	if ext.HR.XMLName.Space != ns || ext.HR.Value != 120 {
+		t.Errorf("wrong extension %v", ext.HR)
+	}
+}
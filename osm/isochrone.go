@@ -0,0 +1,167 @@
+package osm
+
+import (
+	"container/heap"
+	"math"
+	"strconv"
+)
+
+// earthRadius is the mean radius of the Earth in meters, used for local projections.
+const earthRadius = 6371008.8
+
+// IsochroneOptions are options for Isochrones.
+type IsochroneOptions struct {
+	// CellSize is the size in meters of the grid cells used to trace the polygons, 25 meters if zero.
+	CellSize float64
+
+	// Buffer is the distance in meters around the reached roads that is considered reachable, 50 meters if zero.
+	Buffer float64
+}
+
+// localProjection is an equirectangular projection in meters around an origin, which is accurate for small areas.
+type localProjection struct {
+	origin Coord
+	kx, ky float64
+}
+
+func newLocalProjection(origin Coord) localProjection {
+	ky := earthRadius * math.Pi / 180.0
+	return localProjection{origin, ky * math.Cos(origin.Y*math.Pi/180.0), ky}
+}
+
+func (p localProjection) forward(c Coord) Coord {
+	return Coord{(c.X - p.origin.X) * p.kx, (c.Y - p.origin.Y) * p.ky}
+}
+
+func (p localProjection) inverse(c Coord) Coord {
+	return Coord{p.origin.X + c.X/p.kx, p.origin.Y + c.Y/p.ky}
+}
+
+// Isochrones returns the areas that are reachable from c within each of the travel times in seconds. A Dijkstra search from the position on the nearest edge is bounded by the largest time, and the reached roads are sampled with their arrival time and buffered on a grid. The boundaries of the areas are traced with marching squares. Each geometry has the polygons of one travel time and a tag isochrone=<seconds> without a type or ID, and is empty if nothing is reachable. It returns nil if the graph has no edges.
+func (g *Graph) Isochrones(c Coord, times []float64, opts *IsochroneOptions) []Geometry {
+	if opts == nil {
+		opts = &IsochroneOptions{}
+	}
+	cellSize, buffer := opts.CellSize, opts.Buffer
+	if cellSize <= 0.0 {
+		cellSize = 25.0
+	}
+	if buffer <= 0.0 {
+		buffer = 50.0
+	}
+	source, ok := g.Snap(c)
+	if !ok {
+		return nil
+	}
+	maxTime := 0.0
+	for _, t := range times {
+		maxTime = max(maxTime, t)
+	}
+
+	// bounded Dijkstra search
+	dist := map[uint32]float64{}
+	queue := chHeap{}
+	for i, e := range source.Edges {
+		v, t := g.Targets[e], (1.0-source.Fractions[i])*float64(g.Times[e])
+		if d, ok := dist[v]; t <= maxTime && (!ok || t < d) {
+			dist[v] = t
+			heap.Push(&queue, chHeapItem{v, t})
+		}
+	}
+	var settled []uint32
+	for 0 < queue.Len() {
+		item := heap.Pop(&queue).(chHeapItem)
+		if dist[item.v] < item.dist {
+			continue
+		}
+		settled = append(settled, item.v)
+		start, end := g.Edges(int(item.v))
+		for e := start; e < end; e++ {
+			w, t := g.Targets[e], item.dist+float64(g.Times[e])
+			if d, ok := dist[w]; t <= maxTime && (!ok || t < d) {
+				dist[w] = t
+				heap.Push(&queue, chHeapItem{w, t})
+			}
+		}
+	}
+
+	// sample the reached parts of the edges with their arrival time
+	proj := newLocalProjection(source.Coord)
+	type sample struct {
+		Coord
+		time float64
+	}
+	samples := []sample{{proj.forward(source.Coord), 0.0}}
+	sampleEdge := func(e int, from, start float64) {
+		coords := g.EdgeCoords(e)
+		for i := range coords {
+			coords[i] = proj.forward(coords[i])
+		}
+		length := 0.0
+		for i := 1; i < len(coords); i++ {
+			length += math.Hypot(coords[i].X-coords[i-1].X, coords[i].Y-coords[i-1].Y)
+		}
+		n := int(math.Ceil((1.0-from)*length/(0.5*cellSize))) + 1
+		for k := 0; k < n; k++ {
+			t := from
+			if 1 < n {
+				t += (1.0 - from) * float64(k) / float64(n-1)
+			}
+			time := start + (t-from)*float64(g.Times[e])
+			if maxTime < time {
+				break
+			}
+			samples = append(samples, sample{lineInterpolate(coords, t), time})
+		}
+	}
+	for i, e := range source.Edges {
+		sampleEdge(e, source.Fractions[i], 0.0)
+	}
+	for _, v := range settled {
+		start, end := g.Edges(int(v))
+		for e := start; e < end; e++ {
+			sampleEdge(e, 0.0, dist[v])
+		}
+	}
+
+	// buffer the samples on a grid, where each grid point has the earliest arrival time of the samples within the buffer distance
+	bounds := Bounds{{math.Inf(1), math.Inf(1)}, {math.Inf(-1), math.Inf(-1)}}
+	for _, s := range samples {
+		bounds = bounds.union(Bounds{s.Coord, s.Coord})
+	}
+	origin := Coord{bounds[0].X - buffer - cellSize, bounds[0].Y - buffer - cellSize}
+	nx := int(math.Ceil((bounds[1].X-origin.X+buffer+cellSize)/cellSize)) + 1
+	ny := int(math.Ceil((bounds[1].Y-origin.Y+buffer+cellSize)/cellSize)) + 1
+	grid := make([]float64, nx*ny)
+	for i := range grid {
+		grid[i] = math.Inf(1)
+	}
+	r := int(math.Ceil(buffer / cellSize))
+	for _, s := range samples {
+		ci, cj := int(math.Round((s.X-origin.X)/cellSize)), int(math.Round((s.Y-origin.Y)/cellSize))
+		for j := max(0, cj-r); j <= min(ny-1, cj+r); j++ {
+			for i := max(0, ci-r); i <= min(nx-1, ci+r); i++ {
+				p := Coord{origin.X + float64(i)*cellSize, origin.Y + float64(j)*cellSize}
+				if math.Hypot(p.X-s.X, p.Y-s.Y) <= buffer && s.time < grid[j*nx+i] {
+					grid[j*nx+i] = s.time
+				}
+			}
+		}
+	}
+
+	geoms := make([]Geometry, len(times))
+	for k, t := range times {
+		rings := isoRings(grid, nx, ny, t)
+		for _, ring := range rings {
+			for i, p := range ring {
+				ring[i] = proj.inverse(Coord{origin.X + p.X*cellSize, origin.Y + p.Y*cellSize})
+			}
+		}
+		polygons, _ := nestRings(rings)
+		geoms[k] = Geometry{
+			Polygons: polygons,
+			Tags:     Tags{{"isochrone", strconv.FormatFloat(t, 'f', -1, 64)}},
+		}
+	}
+	return geoms
+}
@@ -0,0 +1,95 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+)
+
+func TestIsoRings(t *testing.T) {
+	rings := isoRings([]float64{
+		10, 10, 10,
+		10, 0, 10,
+		10, 10, 10,
+	}, 3, 3, 5.0)
+	if len(rings) != 1 || len(rings[0]) != 5 || ringArea(rings[0]) != 0.5 {
+		t.Errorf("expected one CCW diamond with area 0.5, got %v", rings)
+	}
+
+	// saddle with the centre above the level
+	rings = isoRings([]float64{
+		0, 10,
+		10, 0,
+	}, 2, 2, 5.0)
+	if len(rings) != 2 {
+		t.Errorf("expected two rings, got %v", rings)
+	}
+	rings = isoRings([]float64{
+		0, 10,
+		10, 0,
+	}, 2, 2, 6.0)
+	if len(rings) != 1 {
+		t.Errorf("expected one ring, got %v", rings)
+	}
+
+	// hole and values along the border of the grid
+	rings = isoRings([]float64{
+		0, 0, 0, 0,
+		0, 9, 9, 0,
+		0, 9, math.NaN(), 0,
+		0, 0, 0, 0,
+	}, 4, 4, 5.0)
+	polygons, _ := nestRings(rings)
+	if len(polygons) != 1 || len(polygons[0].Holes) != 1 {
+		t.Errorf("expected one polygon with one hole, got %v", polygons)
+	} else if outer, hole := ringArea(polygons[0].Outer), ringArea(polygons[0].Holes[0]); outer != 15.5 || hole < -4.0 || -1.0 < hole {
+		t.Errorf("expected outer area 15.5 and hole area between -4 and -1, got %v and %v", outer, hole)
+	}
+}
+
+func TestIsochrones(t *testing.T) {
+	nodes := []Node{
+		{ID: 1, Lon: 0.0, Lat: 0.0},
+		{ID: 2, Lon: 0.1, Lat: 0.0},
+		{ID: 3, Lon: 0.05, Lat: 0.1},
+	}
+	ways := []Way{
+		{ID: 1, Refs: []uint64{1, 2}, Tags: Tags{{"highway", "residential"}}},
+		{ID: 2, Refs: []uint64{2, 3}, Tags: Tags{{"highway", "residential"}}},
+	}
+	b := writeTestPBF(t, nodes, ways, nil)
+
+	z := NewParser(bytes.NewReader(b))
+	g, err := z.ExtractGraph(context.Background(), CarProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 30 km/h along a road with 1 km/0.008983° at the equator
+	geoms := g.Isochrones(Coord{0.05, 0.001}, []float64{60.0, 120.0}, nil)
+	if len(geoms) != 2 || geoms[0].Tags.Find("isochrone") != "60" || geoms[1].Tags.Find("isochrone") != "120" {
+		t.Fatalf("wrong isochrones %v", geoms)
+	}
+	for i, reach := range []float64{550.0, 1050.0} { // including the buffer
+		if len(geoms[i].Polygons) != 1 || len(geoms[i].Polygons[0].Holes) != 0 {
+			t.Errorf("%v: expected one polygon, got %v", i, geoms[i].Polygons)
+			continue
+		}
+		bounds := geoms[i].Bounds()
+		proj := newLocalProjection(Coord{0.05, 0.0})
+		p0, p1 := proj.forward(bounds[0]), proj.forward(bounds[1])
+		if 25.0 < math.Abs(p0.X+reach) || 25.0 < math.Abs(p1.X-reach) {
+			t.Errorf("%v: expected reach of %v m, got %v to %v", i, reach, p0.X, p1.X)
+		}
+		if 25.0 < math.Abs(p0.Y+50.0) || 25.0 < math.Abs(p1.Y-50.0) {
+			t.Errorf("%v: expected buffer of 50 m, got %v to %v", i, p0.Y, p1.Y)
+		}
+		if !geoms[i].Contains(Coord{0.05, 0.0}) || geoms[i].Contains(Coord{0.05, 0.002}) {
+			t.Errorf("%v: wrong containment", i)
+		}
+	}
+	if g.Isochrones(Coord{0.05, 0.001}, []float64{0.0}, nil)[0].Polygons != nil {
+		t.Errorf("expected no polygons for zero travel time")
+	}
+}
@@ -0,0 +1,301 @@
+package osm
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+)
+
+// ringCentroid returns the signed area and the centroid of a closed ring. Coordinates are taken relative to the first coordinate to improve precision.
+func ringCentroid(ring []Coord) (float64, Coord) {
+	if len(ring) == 0 {
+		return 0.0, Coord{}
+	}
+	o := ring[0]
+	a, cx, cy := 0.0, 0.0, 0.0
+	for i := 0; i+1 < len(ring); i++ {
+		p, q := ring[i].Sub(o), ring[i+1].Sub(o)
+		cross := p.X*q.Y - q.X*p.Y
+		a += cross
+		cx += (p.X + q.X) * cross
+		cy += (p.Y + q.Y) * cross
+	}
+	if a == 0.0 {
+		return 0.0, o
+	}
+	return a / 2.0, Coord{o.X + cx/(3.0*a), o.Y + cy/(3.0*a)}
+}
+
+// polygonCentroid returns the area and the area-weighted centroid of the polygon, where holes are subtracted irrespective of their orientation.
+func polygonCentroid(polygon PolygonWithHoles) (float64, Coord) {
+	area, centroid := ringCentroid(polygon.Outer)
+	area = math.Abs(area)
+	cx, cy := area*centroid.X, area*centroid.Y
+	for _, hole := range polygon.Holes {
+		a, c := ringCentroid(hole)
+		a = math.Abs(a)
+		area -= a
+		cx -= a * c.X
+		cy -= a * c.Y
+	}
+	if area <= 0.0 {
+		return 0.0, centroid
+	}
+	return area, Coord{cx / area, cy / area}
+}
+
+// Centroid returns the area-weighted centroid of the polygon, which may lie outside of the polygon for concave shapes or shapes with holes.
+func (polygon PolygonWithHoles) Centroid() Coord {
+	_, centroid := polygonCentroid(polygon)
+	return centroid
+}
+
+// PointOnSurface returns a point that is guaranteed to lie in the interior of the polygon. It takes the widest interior interval of a horizontal line through the middle of the polygon, avoiding its vertices, and returns its midpoint. A degenerate polygon returns its first coordinate.
+func (polygon PolygonWithHoles) PointOnSurface() Coord {
+	if len(polygon.Outer) == 0 {
+		return Coord{}
+	}
+
+	// choose a scan line halfway between two vertex latitudes closest to the middle
+	bounds := ringBounds(polygon.Outer)
+	mid := (bounds[0].Y + bounds[1].Y) / 2.0
+	below, above := bounds[0].Y, bounds[1].Y
+	for _, ring := range append([][]Coord{polygon.Outer}, polygon.Holes...) {
+		for _, c := range ring {
+			if c.Y <= mid && below < c.Y {
+				below = c.Y
+			} else if mid < c.Y && c.Y < above {
+				above = c.Y
+			}
+		}
+	}
+	if below == above {
+		return polygon.Outer[0]
+	}
+	y := (below + above) / 2.0
+
+	var xs []float64
+	for _, ring := range append([][]Coord{polygon.Outer}, polygon.Holes...) {
+		for i := 0; i+1 < len(ring); i++ {
+			p, q := ring[i], ring[i+1]
+			if (p.Y <= y) != (q.Y <= y) {
+				xs = append(xs, p.X+(y-p.Y)/(q.Y-p.Y)*(q.X-p.X))
+			}
+		}
+	}
+	slices.Sort(xs)
+	best, width := polygon.Outer[0], 0.0
+	for i := 0; i+1 < len(xs); i += 2 {
+		if width < xs[i+1]-xs[i] {
+			best, width = Coord{(xs[i] + xs[i+1]) / 2.0, y}, xs[i+1]-xs[i]
+		}
+	}
+	return best
+}
+
+// polygonDistance returns the distance from the coordinate to the closest edge of the polygon, which is negative if the coordinate is outside of the polygon.
+func polygonDistance(c Coord, polygon PolygonWithHoles) float64 {
+	inside := false
+	dist := math.Inf(1)
+	for _, ring := range append([][]Coord{polygon.Outer}, polygon.Holes...) {
+		if pointInRing(c, ring) {
+			inside = !inside
+		}
+		for i := 0; i+1 < len(ring); i++ {
+			dist = math.Min(dist, segmentDistance(c, ring[i], ring[i+1]))
+		}
+	}
+	if !inside {
+		return -dist
+	}
+	return dist
+}
+
+type labelCell struct {
+	c    Coord   // centre
+	h    float64 // half size
+	d    float64 // distance to polygon
+	dMax float64 // maximum distance within cell
+}
+
+func newLabelCell(c Coord, h float64, polygon PolygonWithHoles) labelCell {
+	d := polygonDistance(c, polygon)
+	return labelCell{c, h, d, d + h*math.Sqrt2}
+}
+
+type labelHeap []labelCell
+
+func (h labelHeap) Len() int           { return len(h) }
+func (h labelHeap) Less(i, j int) bool { return h[i].dMax > h[j].dMax }
+func (h labelHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *labelHeap) Push(x any)        { *h = append(*h, x.(labelCell)) }
+func (h *labelHeap) Pop() any {
+	item := (*h)[len(*h)-1]
+	*h = (*h)[:len(*h)-1]
+	return item
+}
+
+// poleOfInaccessibility returns the pole of inaccessibility and its distance to the closest edge.
+func poleOfInaccessibility(polygon PolygonWithHoles, tolerance float64) (Coord, float64) {
+	if len(polygon.Outer) == 0 {
+		return Coord{}, 0.0
+	}
+	bounds := ringBounds(polygon.Outer)
+	size := math.Min(bounds.W(), bounds.H())
+	if size == 0.0 {
+		return polygon.Outer[0], 0.0
+	}
+	tolerance = math.Max(tolerance, size*1e-12)
+
+	// cover the polygon with cells
+	h := size / 2.0
+	cells := &labelHeap{}
+	for x := bounds[0].X; x < bounds[1].X; x += size {
+		for y := bounds[0].Y; y < bounds[1].Y; y += size {
+			heap.Push(cells, newLabelCell(Coord{x + h, y + h}, h, polygon))
+		}
+	}
+
+	// start with the centroid or the point on surface, whichever is further from the edges
+	best := newLabelCell(polygon.Centroid(), 0.0, polygon)
+	if cell := newLabelCell(polygon.PointOnSurface(), 0.0, polygon); best.d < cell.d {
+		best = cell
+	}
+	for 0 < cells.Len() {
+		cell := heap.Pop(cells).(labelCell)
+		if best.d < cell.d {
+			best = cell
+		}
+		if cell.dMax-best.d <= tolerance {
+			continue
+		}
+		h := cell.h / 2.0
+		heap.Push(cells, newLabelCell(Coord{cell.c.X - h, cell.c.Y - h}, h, polygon))
+		heap.Push(cells, newLabelCell(Coord{cell.c.X + h, cell.c.Y - h}, h, polygon))
+		heap.Push(cells, newLabelCell(Coord{cell.c.X - h, cell.c.Y + h}, h, polygon))
+		heap.Push(cells, newLabelCell(Coord{cell.c.X + h, cell.c.Y + h}, h, polygon))
+	}
+	return best.c, best.d
+}
+
+// PoleOfInaccessibility returns the point in the interior of the polygon that is furthest from its edges, within the given tolerance, using the polylabel algorithm. This is usually the best position for a label.
+func (polygon PolygonWithHoles) PoleOfInaccessibility(tolerance float64) Coord {
+	c, _ := poleOfInaccessibility(polygon, tolerance)
+	return c
+}
+
+// Centroid returns the centroid of the geometry. For polygons it is the area-weighted centroid, otherwise for line strings it is the length-weighted centroid, and otherwise it is the mean of the points. It returns the zero coordinate for an empty geometry.
+func (g Geometry) Centroid() Coord {
+	area, cx, cy := 0.0, 0.0, 0.0
+	for _, polygon := range g.Polygons {
+		a, c := polygonCentroid(polygon)
+		area += a
+		cx += a * c.X
+		cy += a * c.Y
+	}
+	if 0.0 < area {
+		return Coord{cx / area, cy / area}
+	}
+
+	length := 0.0
+	cx, cy = 0.0, 0.0
+	for _, line := range g.LineStrings {
+		for i := 0; i+1 < len(line); i++ {
+			l := math.Hypot(line[i+1].X-line[i].X, line[i+1].Y-line[i].Y)
+			length += l
+			cx += l * (line[i].X + line[i+1].X) / 2.0
+			cy += l * (line[i].Y + line[i+1].Y) / 2.0
+		}
+	}
+	if 0.0 < length {
+		return Coord{cx / length, cy / length}
+	}
+
+	n := 0
+	cx, cy = 0.0, 0.0
+	for _, c := range g.Points {
+		cx += c.X
+		cy += c.Y
+		n++
+	}
+	if n == 0 {
+		for _, coords := range g.LineStrings {
+			if 0 < len(coords) {
+				return coords[0]
+			}
+		}
+		for _, polygon := range g.Polygons {
+			if 0 < len(polygon.Outer) {
+				return polygon.Outer[0]
+			}
+		}
+		return Coord{}
+	}
+	return Coord{cx / float64(n), cy / float64(n)}
+}
+
+// largestPolygon returns the index of the polygon with the largest area, or -1 if there are no polygons.
+func (g Geometry) largestPolygon() int {
+	best, area := -1, -1.0
+	for i, polygon := range g.Polygons {
+		if a, _ := polygonCentroid(polygon); area < a {
+			best, area = i, a
+		}
+	}
+	return best
+}
+
+// PointOnSurface returns a point that lies on the geometry. For polygons it lies in the interior of the largest polygon, otherwise for line strings it lies halfway along the longest line string, and otherwise it is the point closest to the centroid. It returns the zero coordinate for an empty geometry.
+func (g Geometry) PointOnSurface() Coord {
+	if i := g.largestPolygon(); i != -1 {
+		return g.Polygons[i].PointOnSurface()
+	}
+
+	var longest []Coord
+	length := -1.0
+	for _, line := range g.LineStrings {
+		l := 0.0
+		for i := 0; i+1 < len(line); i++ {
+			l += math.Hypot(line[i+1].X-line[i].X, line[i+1].Y-line[i].Y)
+		}
+		if 0 < len(line) && length < l {
+			longest, length = line, l
+		}
+	}
+	if longest != nil {
+		half := length / 2.0
+		for i := 0; i+1 < len(longest); i++ {
+			p, q := longest[i], longest[i+1]
+			l := math.Hypot(q.X-p.X, q.Y-p.Y)
+			if half <= l && 0.0 < l {
+				t := half / l
+				return Coord{p.X + t*(q.X-p.X), p.Y + t*(q.Y-p.Y)}
+			}
+			half -= l
+		}
+		return longest[0]
+	}
+
+	centroid := g.Centroid()
+	best, dist := Coord{}, math.Inf(1)
+	for _, c := range g.Points {
+		if d := math.Hypot(c.X-centroid.X, c.Y-centroid.Y); d < dist {
+			best, dist = c, d
+		}
+	}
+	return best
+}
+
+// PoleOfInaccessibility returns the point in the interior of the polygons that is furthest from their edges, within the given tolerance. For geometries without polygons it returns the PointOnSurface.
+func (g Geometry) PoleOfInaccessibility(tolerance float64) Coord {
+	best, dist := Coord{}, math.Inf(-1)
+	for _, polygon := range g.Polygons {
+		if c, d := poleOfInaccessibility(polygon, tolerance); dist < d {
+			best, dist = c, d
+		}
+	}
+	if math.IsInf(dist, -1) {
+		return g.PointOnSurface()
+	}
+	return best
+}
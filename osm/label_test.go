@@ -0,0 +1,58 @@
+package osm
+
+import (
+	"math"
+	"testing"
+)
+
+// uShape is a concave polygon whose centroid lies outside of it.
+var uShape = PolygonWithHoles{
+	Outer: []Coord{{0, 0}, {10, 0}, {10, 10}, {8, 10}, {8, 2}, {2, 2}, {2, 10}, {0, 10}, {0, 0}},
+}
+
+func TestCentroid(t *testing.T) {
+	square := PolygonWithHoles{
+		Outer: []Coord{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}},
+		Holes: [][]Coord{{{2, 0}, {2, 4}, {4, 4}, {4, 0}, {2, 0}}}, // right half
+	}
+	if c := square.Centroid(); c != (Coord{1, 2}) {
+		t.Errorf("expected centroid (1,2), got %v", c)
+	}
+
+	g := Geometry{LineStrings: [][]Coord{{{0, 0}, {2, 0}}, {{0, 1}, {0, 3}, {0, 4}}}}
+	if c := g.Centroid(); c != (Coord{0.4, 1.5}) {
+		t.Errorf("expected centroid (0.4,1.5), got %v", c)
+	}
+	g = Geometry{Points: []Coord{{0, 0}, {2, 4}}}
+	if c := g.Centroid(); c != (Coord{1, 2}) {
+		t.Errorf("expected centroid (1,2), got %v", c)
+	}
+}
+
+func TestPointOnSurface(t *testing.T) {
+	if c := uShape.Centroid(); 0.0 < polygonDistance(c, uShape) {
+		t.Errorf("expected centroid %v outside of the polygon", c)
+	}
+	if c := uShape.PointOnSurface(); polygonDistance(c, uShape) <= 0.0 {
+		t.Errorf("expected point %v inside the polygon", c)
+	}
+
+	g := Geometry{LineStrings: [][]Coord{{{0, 0}, {1, 0}}, {{0, 0}, {0, 2}, {4, 2}}}}
+	if c := g.PointOnSurface(); c != (Coord{1, 2}) {
+		t.Errorf("expected point halfway (1,2), got %v", c)
+	}
+}
+
+func TestPoleOfInaccessibility(t *testing.T) {
+	c := uShape.PoleOfInaccessibility(0.01)
+	if d := polygonDistance(c, uShape); d < 1.0-0.01 {
+		t.Errorf("expected distance of at least 1, got %v at %v", d, c)
+	}
+
+	rect := PolygonWithHoles{Outer: []Coord{{0, 0}, {10, 0}, {10, 4}, {0, 4}, {0, 0}}}
+	g := Geometry{Polygons: []PolygonWithHoles{uShape, rect}}
+	c = g.PoleOfInaccessibility(0.001)
+	if d := polygonDistance(c, rect); math.Abs(d-2.0) > 0.001 {
+		t.Errorf("expected pole in the rectangle at distance 2, got %v at %v", d, c)
+	}
+}
@@ -0,0 +1,477 @@
+package osm
+
+import (
+	"container/heap"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TracePoint is a GPS measurement, where the time is zero if unknown.
+type TracePoint struct {
+	Coord Coord
+	Time  time.Time
+}
+
+// ReadTraceCSV reads a GPS trace from CSV with a header. Columns named lat or latitude and lon, lng, or longitude are required, and a column named time, timestamp, or datetime is optional. Times are in RFC 3339, in the format 2006-01-02 15:04:05 in UTC, or are seconds since the Unix epoch.
+func ReadTraceCSV(r io.Reader) ([]TracePoint, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing CSV header")
+	} else if err != nil {
+		return nil, err
+	}
+	lonColumn, latColumn, timeColumn := -1, -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "lon", "lng", "longitude":
+			lonColumn = i
+		case "lat", "latitude":
+			latColumn = i
+		case "time", "timestamp", "datetime":
+			timeColumn = i
+		}
+	}
+	if lonColumn == -1 || latColumn == -1 {
+		return nil, fmt.Errorf("missing longitude or latitude column")
+	}
+
+	trace := []TracePoint{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		lon, err := strconv.ParseFloat(strings.TrimSpace(record[lonColumn]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude on line %v: %w", line, err)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(record[latColumn]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude on line %v: %w", line, err)
+		}
+		point := TracePoint{Coord: Coord{lon, lat}}
+		if timeColumn != -1 {
+			if point.Time, err = parseTraceTime(strings.TrimSpace(record[timeColumn])); err != nil {
+				return nil, fmt.Errorf("invalid time on line %v: %w", line, err)
+			}
+		}
+		trace = append(trace, point)
+	}
+	return trace, nil
+}
+
+func parseTraceTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	} else if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	} else if t, err := time.Parse(time.DateTime, s); err == nil {
+		return t, nil
+	}
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown time format %q", s)
+	}
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+}
+
+// MatchOptions are options for MatchTrace.
+type MatchOptions struct {
+	// Radius is the distance in meters around a trace point within which roads are candidates, 50 meters if zero.
+	Radius float64
+
+	// Sigma is the standard deviation in meters of the GPS noise, 4.07 meters if zero as found by Newson & Krumm.
+	Sigma float64
+
+	// Beta is the scale in meters of the difference between the route distance and the great circle distance of consecutive trace points, 5 meters if zero.
+	Beta float64
+
+	// MaxCandidates is the maximum number of nearest candidates per trace point, 8 if zero.
+	MaxCandidates int
+}
+
+// MatchedPoint is a trace point with its matched position on the routing graph.
+type MatchedPoint struct {
+	TracePoint
+	Matched    bool    // false if there are no roads within the radius
+	Snapped    Coord   // matched position on the edge
+	Edge       int     // edge of the graph
+	WayID      uint64  // way of the edge
+	Distance   float64 // distance in meters between the trace point and the matched position
+	Confidence float64 // posterior probability of the matched position among the candidates of the trace point
+}
+
+// MatchedNode is a vertex of the routing graph on the matched path, which are the nodes where ways meet or end, with its time interpolated by distance between the trace points.
+type MatchedNode struct {
+	ID    uint64
+	Coord Coord
+	Time  time.Time
+}
+
+// MatchedWay is a part of the matched path on a single way.
+type MatchedWay struct {
+	ID         uint64
+	Start, End time.Time
+	Confidence float64 // lowest confidence of the trace points around the way
+}
+
+// Match is the result of matching a trace to the routing graph. The matched path is broken where consecutive trace points cannot be connected, in which case the nodes and ways continue after a gap.
+type Match struct {
+	Points []MatchedPoint
+	Nodes  []MatchedNode
+	Ways   []MatchedWay
+}
+
+// matchCandidate is a position on an edge, where edges lists all edges that run over the position in the same direction, since vertices are duplicated by turn restrictions.
+type matchCandidate struct {
+	edges    []int
+	fraction float64
+	coord    Coord
+	distance float64
+	emission float64 // log probability
+}
+
+// matchTransition is the route between two candidates.
+type matchTransition struct {
+	prob   float64 // log probability
+	length float64
+	edges  []int // edges after the edge of the first candidate, up to and including the edge of the second candidate
+	from   int   // edge of the first candidate
+}
+
+// matchCandidates returns the positions on the edges within the radius of c, in order of increasing distance, where tree is the R-tree over the edges.
+func (g *Graph) matchCandidates(tree *RTree, c Coord, radius, sigma float64, k int) []matchCandidate {
+	dy := radius / (earthRadius * math.Pi / 180.0)
+	dx := dy / math.Max(math.Cos(c.Y*math.Pi/180.0), 1e-6)
+	type candidateKey struct {
+		way    uint64
+		a, b   uint64
+		length float32
+	}
+	index := map[candidateKey]int{}
+	candidates := []matchCandidate{}
+	tree.Search(Bounds{{c.X - dx, c.Y - dy}, {c.X + dx, c.Y + dy}}, func(e int) bool {
+		key := candidateKey{g.WayIDs[e], g.NodeIDs[g.Source(e)], g.NodeIDs[g.Targets[e]], g.Lengths[e]}
+		if i, ok := index[key]; ok {
+			candidates[i].edges = append(candidates[i].edges, e)
+			return true
+		}
+		p, along, length := projectLine(g.EdgeCoords(e), c)
+		if d := c.Distance(p); d <= radius {
+			t := 0.0
+			if length != 0.0 {
+				t = along / length
+			}
+			index[key] = len(candidates)
+			candidates = append(candidates, matchCandidate{
+				edges:    []int{e},
+				fraction: t,
+				coord:    p,
+				distance: d,
+				emission: -0.5*(d/sigma)*(d/sigma) - math.Log(math.Sqrt(2.0*math.Pi)*sigma),
+			})
+		}
+		return true
+	})
+	slices.SortStableFunc(candidates, func(a, b matchCandidate) int {
+		return compareFloat(a.distance, b.distance)
+	})
+	for i := range candidates {
+		slices.Sort(candidates[i].edges)
+	}
+	if k < len(candidates) {
+		candidates = candidates[:k]
+	}
+	return candidates
+}
+
+// matchRoutes returns the shortest routes by length from candidate a to each candidate in bs, up to the given length, where unreachable candidates have an infinite length.
+func (g *Graph) matchRoutes(a matchCandidate, bs []matchCandidate, maxLength float64) []matchTransition {
+	type label struct {
+		length float64
+		edge   int // edge to the vertex, or the edge of the first candidate for the start of the search
+		start  bool
+	}
+	labels := map[uint32]label{}
+	queue := chHeap{}
+	for _, e := range a.edges {
+		v, l := g.Targets[e], (1.0-a.fraction)*float64(g.Lengths[e])
+		if prev, ok := labels[v]; !ok || l < prev.length {
+			labels[v] = label{l, e, true}
+			heap.Push(&queue, chHeapItem{v, l})
+		}
+	}
+	for 0 < queue.Len() {
+		item := heap.Pop(&queue).(chHeapItem)
+		if labels[item.v].length < item.dist {
+			continue
+		}
+		start, end := g.Edges(int(item.v))
+		for e := start; e < end; e++ {
+			w, l := g.Targets[e], item.dist+float64(g.Lengths[e])
+			if prev, ok := labels[w]; l <= maxLength && (!ok || l < prev.length) {
+				labels[w] = label{l, e, false}
+				heap.Push(&queue, chHeapItem{w, l})
+			}
+		}
+	}
+
+	transitions := make([]matchTransition, len(bs))
+	for j, b := range bs {
+		transitions[j] = matchTransition{length: math.Inf(1), from: a.edges[0]}
+		for _, e := range a.edges {
+			if slices.Contains(b.edges, e) && a.fraction <= b.fraction {
+				transitions[j].length = (b.fraction - a.fraction) * float64(g.Lengths[e])
+				transitions[j].from = e
+				break
+			}
+		}
+		for _, f := range b.edges {
+			lab, ok := labels[uint32(g.Source(f))]
+			if !ok {
+				continue
+			}
+			if l := lab.length + b.fraction*float64(g.Lengths[f]); l < transitions[j].length {
+				edges := []int{f}
+				for v := uint32(g.Source(f)); ; {
+					lab := labels[v]
+					if lab.start {
+						transitions[j].from = lab.edge
+						break
+					}
+					edges = append(edges, lab.edge)
+					v = uint32(g.Source(lab.edge))
+				}
+				slices.Reverse(edges)
+				transitions[j].length = l
+				transitions[j].edges = edges
+			}
+		}
+	}
+	return transitions
+}
+
+// MatchTrace matches a GPS trace to the routing graph using a hidden Markov model after Newson & Krumm (2009). Candidates are the positions on the edges near each trace point, with a Gaussian emission probability by distance, and transitions have an exponential probability by the difference between the route distance over the graph and the great circle distance. The most likely sequence is found with the Viterbi algorithm, and confidences are the posterior probabilities from the forward-backward algorithm. Trace points without candidates are not matched, and the model restarts where no route connects consecutive trace points.
+func (g *Graph) MatchTrace(trace []TracePoint, opts *MatchOptions) Match {
+	if opts == nil {
+		opts = &MatchOptions{}
+	}
+	radius, sigma, beta, k := opts.Radius, opts.Sigma, opts.Beta, opts.MaxCandidates
+	if radius <= 0.0 {
+		radius = 50.0
+	}
+	if sigma <= 0.0 {
+		sigma = 4.07
+	}
+	if beta <= 0.0 {
+		beta = 5.0
+	}
+	if k <= 0 {
+		k = 8
+	}
+
+	match := Match{Points: make([]MatchedPoint, len(trace))}
+	steps := []int{} // trace points with candidates
+	candidates := [][]matchCandidate{}
+	tree := g.edgeTree()
+	for i, point := range trace {
+		match.Points[i].TracePoint = point
+		if cs := g.matchCandidates(tree, point.Coord, radius, sigma, k); 0 < len(cs) {
+			steps = append(steps, i)
+			candidates = append(candidates, cs)
+		}
+	}
+	if len(steps) == 0 {
+		return match
+	}
+
+	// transitions[t][a][b] is the transition from candidate a of step t-1 to candidate b of step t
+	transitions := make([][][]matchTransition, len(steps))
+	breaks := make([]bool, len(steps)) // the model restarts at step t
+	breaks[0] = true
+	for t := 1; t < len(steps); t++ {
+		p, q := trace[steps[t-1]].Coord, trace[steps[t]].Coord
+		gcDist := p.Distance(q)
+		maxLength := 2.0*gcDist + 2.0*radius
+		transitions[t] = make([][]matchTransition, len(candidates[t-1]))
+		breaks[t] = true
+		for a, ca := range candidates[t-1] {
+			transitions[t][a] = g.matchRoutes(ca, candidates[t], maxLength)
+			for b := range transitions[t][a] {
+				tr := &transitions[t][a][b]
+				tr.prob = math.Inf(-1)
+				if !math.IsInf(tr.length, 1) {
+					tr.prob = -math.Abs(tr.length-gcDist)/beta - math.Log(beta)
+					breaks[t] = false
+				}
+			}
+		}
+	}
+
+	// forward pass of Viterbi and forward algorithms, and backward pass of backward algorithm
+	viterbi := make([][]float64, len(steps))
+	backpointers := make([][]int, len(steps))
+	alpha := make([][]float64, len(steps))
+	for t, cs := range candidates {
+		viterbi[t] = make([]float64, len(cs))
+		backpointers[t] = make([]int, len(cs))
+		alpha[t] = make([]float64, len(cs))
+		reachable := false
+		for b, cb := range cs {
+			backpointers[t][b] = -1
+			if breaks[t] {
+				continue
+			}
+			best, sum := math.Inf(-1), []float64{}
+			for a := range candidates[t-1] {
+				prob := transitions[t][a][b].prob
+				if v := viterbi[t-1][a] + prob; best < v {
+					best, backpointers[t][b] = v, a
+				}
+				sum = append(sum, alpha[t-1][a]+prob)
+			}
+			viterbi[t][b], alpha[t][b] = best+cb.emission, logSumExp(sum)+cb.emission
+			reachable = reachable || !math.IsInf(best, -1)
+		}
+		if !reachable {
+			breaks[t] = true
+			for b, cb := range cs {
+				viterbi[t][b], alpha[t][b], backpointers[t][b] = cb.emission, cb.emission, -1
+			}
+		}
+	}
+	beta2 := make([][]float64, len(steps))
+	for t := len(steps) - 1; 0 <= t; t-- {
+		beta2[t] = make([]float64, len(candidates[t]))
+		if t == len(steps)-1 || breaks[t+1] {
+			continue // zero log probability
+		}
+		for a := range candidates[t] {
+			sum := []float64{}
+			for b, cb := range candidates[t+1] {
+				sum = append(sum, transitions[t+1][a][b].prob+cb.emission+beta2[t+1][b])
+			}
+			beta2[t][a] = logSumExp(sum)
+		}
+	}
+
+	// backtrack each chain from its last step
+	chosen := make([]int, len(steps))
+	for t := len(steps) - 1; 0 <= t; t-- {
+		if t == len(steps)-1 || breaks[t+1] {
+			chosen[t] = 0
+			for b := range viterbi[t] {
+				if viterbi[t][chosen[t]] < viterbi[t][b] {
+					chosen[t] = b
+				}
+			}
+		} else {
+			chosen[t] = backpointers[t+1][chosen[t+1]]
+		}
+	}
+	confidences := make([]float64, len(steps))
+	for t := len(steps) - 1; 0 <= t; t-- {
+		// normalize over the candidates of the step within its chain
+		sum := []float64{}
+		for b := range candidates[t] {
+			sum = append(sum, alpha[t][b]+beta2[t][b])
+		}
+		c := chosen[t]
+		confidences[t] = math.Exp(alpha[t][c] + beta2[t][c] - logSumExp(sum))
+	}
+
+	for t, i := range steps {
+		c := candidates[t][chosen[t]]
+		edge := c.edges[0]
+		if t+1 < len(steps) && !breaks[t+1] {
+			edge = transitions[t+1][chosen[t]][chosen[t+1]].from
+		} else if 0 < t && !breaks[t] {
+			if edges := transitions[t][chosen[t-1]][chosen[t]].edges; 0 < len(edges) {
+				edge = edges[len(edges)-1]
+			} else {
+				edge = transitions[t][chosen[t-1]][chosen[t]].from
+			}
+		}
+		match.Points[i].Matched = true
+		match.Points[i].Snapped = c.coord
+		match.Points[i].Edge = edge
+		match.Points[i].WayID = g.WayIDs[edge]
+		match.Points[i].Distance = c.distance
+		match.Points[i].Confidence = confidences[t]
+	}
+
+	// nodes and ways along the matched path
+	addWay := func(id uint64, start, end time.Time, confidence float64, gap bool) {
+		if n := len(match.Ways); !gap && 0 < n && match.Ways[n-1].ID == id {
+			match.Ways[n-1].End = end
+			match.Ways[n-1].Confidence = math.Min(match.Ways[n-1].Confidence, confidence)
+		} else {
+			match.Ways = append(match.Ways, MatchedWay{id, start, end, confidence})
+		}
+	}
+	for t := 0; t < len(steps); t++ {
+		point := match.Points[steps[t]]
+		if t+1 == len(steps) || breaks[t+1] {
+			// single point or end of chain
+			if breaks[t] {
+				addWay(point.WayID, point.Time, point.Time, point.Confidence, true)
+			}
+			continue
+		}
+		next := match.Points[steps[t+1]]
+		tr := transitions[t+1][chosen[t]][chosen[t+1]]
+		confidence := math.Min(point.Confidence, next.Confidence)
+		interpolate := func(d float64) time.Time {
+			if tr.length == 0.0 || point.Time.IsZero() || next.Time.IsZero() {
+				return point.Time
+			}
+			return point.Time.Add(time.Duration(d / tr.length * float64(next.Time.Sub(point.Time))))
+		}
+
+		start := point.Time
+		d := (1.0 - candidates[t][chosen[t]].fraction) * float64(g.Lengths[tr.from])
+		if len(tr.edges) == 0 {
+			addWay(g.WayIDs[tr.from], start, next.Time, confidence, breaks[t])
+			continue
+		}
+		edge := tr.from
+		for k, e := range tr.edges {
+			end := interpolate(d)
+			addWay(g.WayIDs[edge], start, end, confidence, breaks[t] && k == 0)
+			v := uint32(g.Source(e))
+			match.Nodes = append(match.Nodes, MatchedNode{g.NodeIDs[v], g.Coords[v], end})
+			edge, start = e, end
+			d += float64(g.Lengths[e])
+		}
+		addWay(g.WayIDs[edge], start, next.Time, confidence, false)
+	}
+	return match
+}
+
+// logSumExp returns the logarithm of the sum of the exponentials of the values.
+func logSumExp(values []float64) float64 {
+	m := math.Inf(-1)
+	for _, v := range values {
+		m = math.Max(m, v)
+	}
+	if math.IsInf(m, -1) {
+		return m
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += math.Exp(v - m)
+	}
+	return m + math.Log(sum)
+}
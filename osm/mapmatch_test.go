@@ -0,0 +1,118 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadTraceCSV(t *testing.T) {
+	trace, err := ReadTraceCSV(strings.NewReader("Time,Latitude,Longitude\n2024-05-01T12:00:00Z,53.2,6.5\n1714564805,53.3,6.6\n2024-05-01 12:00:10,53.4,6.7\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []TracePoint{
+		{Coord{6.5, 53.2}, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{Coord{6.6, 53.3}, time.Date(2024, 5, 1, 12, 0, 5, 0, time.UTC)},
+		{Coord{6.7, 53.4}, time.Date(2024, 5, 1, 12, 0, 10, 0, time.UTC)},
+	}
+	if len(trace) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, trace)
+	}
+	for i := range trace {
+		if trace[i].Coord != expected[i].Coord || !trace[i].Time.Equal(expected[i].Time) {
+			t.Errorf("expected %v, got %v", expected[i], trace[i])
+		}
+	}
+
+	if _, err := ReadTraceCSV(strings.NewReader("x,y\n1,2\n")); err == nil {
+		t.Errorf("expected error for missing columns")
+	}
+	if _, err := ReadTraceCSV(strings.NewReader("lat,lon\n53.2,6.5\n53.2,east\n")); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected error on line 3, got %v", err)
+	}
+}
+
+func TestMatchTrace(t *testing.T) {
+	nodes := []Node{}
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			nodes = append(nodes, Node{ID: uint64(1 + y*3 + x), Lon: 0.01 * float64(x), Lat: 0.01 * float64(y)})
+		}
+	}
+	residential := Tags{{"highway", "residential"}}
+	ways := []Way{
+		{ID: 1, Refs: []uint64{1, 2, 3}, Tags: residential},
+		{ID: 2, Refs: []uint64{4, 5, 6}, Tags: residential},
+		{ID: 3, Refs: []uint64{7, 8, 9}, Tags: residential},
+		{ID: 4, Refs: []uint64{1, 4, 7}, Tags: residential},
+		{ID: 5, Refs: []uint64{2, 5, 8}, Tags: residential},
+		{ID: 6, Refs: []uint64{3, 6, 9}, Tags: residential},
+	}
+	b := writeTestPBF(t, nodes, ways, nil)
+
+	z := NewParser(bytes.NewReader(b))
+	g, err := z.ExtractGraph(context.Background(), CarProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// east along way 1 and north along way 6 with a few meters of noise, and one point far off
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	trace := []TracePoint{}
+	for i := 0; i < 20; i++ {
+		noise := 0.00004 * float64(i%3-1)
+		c := Coord{0.002*float64(i) + 0.0005, noise}
+		if 0.02 < c.X {
+			c = Coord{0.02 + noise, c.X - 0.02}
+		}
+		trace = append(trace, TracePoint{c, start.Add(time.Duration(i) * 10 * time.Second)})
+	}
+	trace = slices.Insert(trace, 10, TracePoint{Coord{0.005, 0.005}, start.Add(95 * time.Second)})
+
+	match := g.MatchTrace(trace, nil)
+	if len(match.Points) != len(trace) {
+		t.Fatalf("expected %v points, got %v", len(trace), len(match.Points))
+	}
+	for i, point := range match.Points {
+		if i == 10 {
+			if point.Matched {
+				t.Errorf("expected point far off to be unmatched, got %v", point)
+			}
+			continue
+		}
+		if !point.Matched || 10.0 < point.Distance || point.Confidence < 0.5 {
+			t.Errorf("%v: wrong match %+v", i, point)
+		}
+	}
+
+	wayIDs := []uint64{}
+	for _, way := range match.Ways {
+		wayIDs = append(wayIDs, way.ID)
+	}
+	if !slices.Equal(wayIDs, []uint64{1, 6}) {
+		t.Errorf("expected ways [1 6], got %v", wayIDs)
+	}
+	nodeIDs := []uint64{}
+	for i, node := range match.Nodes {
+		nodeIDs = append(nodeIDs, node.ID)
+		if 0 < i && node.Time.Before(match.Nodes[i-1].Time) {
+			t.Errorf("node times are not increasing: %v", match.Nodes)
+		}
+	}
+	if !slices.Equal(nodeIDs, []uint64{2, 3, 6}) {
+		t.Errorf("expected nodes [2 3 6], got %v", nodeIDs)
+	}
+	if 0 < len(match.Ways) && (!match.Ways[0].Start.Equal(start) || !match.Ways[len(match.Ways)-1].End.Equal(trace[len(trace)-1].Time)) {
+		t.Errorf("wrong way times %v", match.Ways)
+	}
+	if 1 < len(match.Nodes) {
+		// node 3 at 0.02 is reached at 97.5 s
+		if node := match.Nodes[1]; node.Time.Sub(start) < 95*time.Second || 100*time.Second < node.Time.Sub(start) {
+			t.Errorf("expected node 3 at 97.5s, got %v", node.Time.Sub(start))
+		}
+	}
+}
@@ -0,0 +1,514 @@
+package osm
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// PolygonWithHoles is a polygon consisting of an outer ring and zero or more inner rings (holes). All rings are closed, that is the last coordinate equals the first. The outer ring is CCW oriented and holes are CW oriented.
+type PolygonWithHoles struct {
+	Outer []Coord
+	Holes [][]Coord
+}
+
+// ProblemType is the type of problem encountered while assembling geometries.
+type ProblemType int
+
+const (
+	UnclosedRing ProblemType = iota
+	SelfIntersection
+	DuplicateSegment
+	RoleMismatch
+	CoastlineGap
+	ReversedCoastline
+	MissingWay
+	MissingNode
+	DegeneratePolygon
+	RecursionDepthExceeded
+	InvalidCoordinate
+	TooFewPoints
+	RepeatedPoint
+	HoleOutsideShell
+	NestedHoles
+	NestedShells
+)
+
+func (t ProblemType) String() string {
+	switch t {
+	case UnclosedRing:
+		return "unclosed ring"
+	case SelfIntersection:
+		return "self-intersection"
+	case DuplicateSegment:
+		return "duplicate segment"
+	case RoleMismatch:
+		return "role mismatch"
+	case CoastlineGap:
+		return "coastline gap"
+	case ReversedCoastline:
+		return "reversed coastline"
+	case MissingWay:
+		return "missing way"
+	case MissingNode:
+		return "missing node"
+	case DegeneratePolygon:
+		return "degenerate polygon"
+	case RecursionDepthExceeded:
+		return "recursion depth exceeded"
+	case InvalidCoordinate:
+		return "invalid coordinate"
+	case TooFewPoints:
+		return "too few points"
+	case RepeatedPoint:
+		return "repeated point"
+	case HoleOutsideShell:
+		return "hole outside shell"
+	case NestedHoles:
+		return "nested holes"
+	case NestedShells:
+		return "nested shells"
+	}
+	return fmt.Sprintf("ProblemType(%d)", int(t))
+}
+
+// Problem is a problem encountered while assembling geometries, such as an unclosed ring in a multipolygon relation, a missing member, or a gap in the coastline.
+type Problem struct {
+	Type       ProblemType
+	ObjectType Type   // type of the object with the problem
+	ID         uint64 // ID of the object with the problem
+	Role       string // role of the object in the relation
+	Relation   uint64 // ID of the relation the problem was found in, or zero
+	Ref        uint64 // ID of the missing way or node, or zero
+	Coord      Coord  // location of the problem, or zero if unknown
+}
+
+func (p Problem) String() string {
+	var sb strings.Builder
+	sb.WriteString(p.Type.String())
+	if p.Ref != 0 {
+		fmt.Fprintf(&sb, " %v", p.Ref)
+		if p.Role != "" {
+			fmt.Fprintf(&sb, " with role %v", p.Role)
+		}
+		fmt.Fprintf(&sb, " in %v %v", p.ObjectType, p.ID)
+	} else if p.Role != "" {
+		fmt.Fprintf(&sb, " in %v %v %v", p.Role, p.ObjectType, p.ID)
+	} else {
+		fmt.Fprintf(&sb, " in %v %v", p.ObjectType, p.ID)
+	}
+	if p.Relation != 0 && (p.ObjectType != RelationType || p.Relation != p.ID) {
+		fmt.Fprintf(&sb, " of relation %v", p.Relation)
+	}
+	if p.Ref == 0 || p.Coord != (Coord{}) {
+		fmt.Fprintf(&sb, " at %v,%v", p.Coord.X, p.Coord.Y)
+	}
+	return sb.String()
+}
+
+// ProblemFunc is called for each problem encountered.
+type ProblemFunc func(Problem)
+
+// memberWay is a way that is a member of a relation.
+type memberWay struct {
+	relationWay
+	Role string
+}
+
+// wayChain is a sequence of connected ways.
+type wayChain struct {
+	Coords      []Coord
+	First, Last uint64 // IDs of first and last node
+	Ways        []int  // indices into the member ways
+}
+
+func (c wayChain) closed() bool {
+	return c.First == c.Last
+}
+
+func (c *wayChain) reverse() {
+	c.Coords = reverseOrientation(c.Coords)
+	c.First, c.Last = c.Last, c.First
+	slices.Reverse(c.Ways)
+}
+
+// joinCoords appends b to a, where the last coordinate of a and the first coordinate of b belong to the same node. Spikes that occur due to clipping are removed.
+func joinCoords(a, b []Coord) []Coord {
+	if 0 < len(a) && 0 < len(b) && a[len(a)-1] == b[0] {
+		b = b[1:]
+	}
+	for 1 < len(a) && 0 < len(b) && a[len(a)-2] == b[0] {
+		// avoid overlapping segments
+		a = a[:len(a)-1]
+		b = b[1:]
+	}
+	return append(a, b...)
+}
+
+// joinWays connects all ways by their endpoints into chains, reversing ways where needed. Closed chains are returned first.
+func joinWays(ways []memberWay) []wayChain {
+	var chains []wayChain
+	ends := map[uint64][]int{} // open chains by endpoint node ID
+	removeEnd := func(id uint64, k int) {
+		ks := ends[id]
+		if i := slices.Index(ks, k); i != -1 {
+			ks = append(ks[:i], ks[i+1:]...)
+		}
+		if len(ks) == 0 {
+			delete(ends, id)
+		} else {
+			ends[id] = ks
+		}
+	}
+	popEnd := func(id uint64) (wayChain, bool) {
+		ks := ends[id]
+		if len(ks) == 0 {
+			return wayChain{}, false
+		}
+		k := ks[0]
+		c := chains[k]
+		removeEnd(c.First, k)
+		removeEnd(c.Last, k)
+		chains[k].Coords = nil // mark as consumed
+		return c, true
+	}
+
+	for i, way := range ways {
+		if len(way.Coords) < 2 {
+			continue
+		}
+		c := wayChain{
+			Coords: way.Coords[:len(way.Coords):len(way.Coords)],
+			First:  way.First,
+			Last:   way.Last,
+			Ways:   []int{i},
+		}
+		for !c.closed() {
+			if d, ok := popEnd(c.Last); ok {
+				if d.Last == c.Last {
+					d.reverse()
+				}
+				c.Coords = joinCoords(c.Coords, d.Coords)
+				c.Last = d.Last
+				c.Ways = append(c.Ways, d.Ways...)
+			} else if d, ok := popEnd(c.First); ok {
+				if d.First == c.First {
+					d.reverse()
+				}
+				d.Coords = joinCoords(d.Coords[:len(d.Coords):len(d.Coords)], c.Coords)
+				d.Last = c.Last
+				d.Ways = append(d.Ways, c.Ways...)
+				c = d
+			} else {
+				break
+			}
+		}
+		chains = append(chains, c)
+		if !c.closed() {
+			k := len(chains) - 1
+			ends[c.First] = append(ends[c.First], k)
+			ends[c.Last] = append(ends[c.Last], k)
+		}
+	}
+
+	// remove consumed chains and put closed chains first
+	j := 0
+	for _, c := range chains {
+		if c.Coords != nil {
+			chains[j] = c
+			j++
+		}
+	}
+	chains = chains[:j]
+	slices.SortStableFunc(chains, func(a, b wayChain) int {
+		if a.closed() == b.closed() {
+			return 0
+		} else if a.closed() {
+			return -1
+		}
+		return 1
+	})
+	return chains
+}
+
+// closeRing makes sure the ring's last coordinate equals the first.
+func closeRing(coords []Coord) []Coord {
+	if 0 < len(coords) && coords[0] != coords[len(coords)-1] {
+		coords = append(coords[:len(coords):len(coords)], coords[0])
+	}
+	return coords
+}
+
+// splitRing splits a closed ring at coordinates that are visited more than once, such as for rings that touch themselves.
+func splitRing(coords []Coord) [][]Coord {
+	seen := make(map[Coord]int, len(coords))
+	var rings [][]Coord
+	stack := make([]Coord, 0, len(coords))
+	for _, c := range coords[:len(coords)-1] {
+		if i, ok := seen[c]; ok {
+			// close the loop from the previous visit
+			ring := append(slices.Clone(stack[i:]), c)
+			for _, d := range stack[i+1:] {
+				delete(seen, d)
+			}
+			stack = stack[:i+1]
+			rings = append(rings, ring)
+			continue
+		}
+		seen[c] = len(stack)
+		stack = append(stack, c)
+	}
+	rings = append(rings, append(stack, stack[0]))
+	return rings
+}
+
+// ringInRing returns true if ring a lies within ring b. It tests a few points along ring a and takes the majority, which makes it robust for touching rings.
+func ringInRing(a, b []Coord) bool {
+	n := len(a) - 1
+	inside := 0
+	for _, k := range []int{0, n / 3, 2 * n / 3} {
+		p, q := a[k], a[k+1]
+		if pointInRing(Coord{(p.X + q.X) / 2.0, (p.Y + q.Y) / 2.0}, b) {
+			inside++
+		}
+	}
+	return 2 <= inside
+}
+
+// degenerateRing returns true if the ring has no area, that is when it has fewer than three distinct coordinates or when all coordinates are collinear.
+func degenerateRing(coords []Coord) bool {
+	if len(coords) < 4 {
+		return true
+	}
+	a, b := coords[0], coords[0]
+	for _, c := range coords[1:] {
+		if b == a {
+			b = c
+		} else if ab, ac := b.Sub(a), c.Sub(a); ab.X*ac.Y-ab.Y*ac.X != 0.0 {
+			return false
+		}
+	}
+	return true
+}
+
+// inBounds returns true if all coordinates lie strictly within the bounds.
+func inBounds(bounds Bounds, coords []Coord) bool {
+	for _, coord := range coords {
+		if cohenSutherlandOutcode(bounds, coord) != 0b0000 {
+			return false
+		}
+	}
+	return true
+}
+
+func ringBounds(coords []Coord) Bounds {
+	b := Bounds{{math.Inf(1), math.Inf(1)}, {math.Inf(-1), math.Inf(-1)}}
+	for _, c := range coords {
+		b[0].X, b[0].Y = math.Min(b[0].X, c.X), math.Min(b[0].Y, c.Y)
+		b[1].X, b[1].Y = math.Max(b[1].X, c.X), math.Max(b[1].Y, c.Y)
+	}
+	return b
+}
+
+func (b Bounds) containsBounds(a Bounds) bool {
+	return b[0].X <= a[0].X && a[1].X <= b[1].X && b[0].Y <= a[0].Y && a[1].Y <= b[1].Y
+}
+
+// nestRings decides the nesting of closed rings by geometry, irrespective of their orientation. Rings at even depth are outer rings and rings at odd depth are holes of their parent. Rings are reoriented so that outer rings are CCW and holes are CW. It returns the depth of each ring.
+func nestRings(rings [][]Coord) ([]PolygonWithHoles, []int) {
+	type ringItem struct {
+		index  int
+		area   float64
+		bounds Bounds
+		parent int
+		depth  int
+		poly   int
+	}
+	items := make([]ringItem, len(rings))
+	for i, ring := range rings {
+		items[i] = ringItem{
+			index:  i,
+			area:   math.Abs(ringArea(ring)),
+			bounds: ringBounds(ring),
+			parent: -1,
+		}
+	}
+	slices.SortStableFunc(items, func(a, b ringItem) int {
+		if a.area < b.area {
+			return 1
+		} else if b.area < a.area {
+			return -1
+		}
+		return 0
+	})
+
+	var polygons []PolygonWithHoles
+	depths := make([]int, len(rings))
+	for i := range items {
+		for j := i - 1; 0 <= j; j-- {
+			if items[j].bounds.containsBounds(items[i].bounds) && ringInRing(rings[items[i].index], rings[items[j].index]) {
+				items[i].parent = j
+				items[i].depth = items[j].depth + 1
+				break
+			}
+		}
+
+		ring := rings[items[i].index]
+		depths[items[i].index] = items[i].depth
+		if items[i].depth%2 == 0 {
+			if !isCCW(ring) {
+				ring = reverseOrientation(ring)
+			}
+			items[i].poly = len(polygons)
+			polygons = append(polygons, PolygonWithHoles{Outer: ring})
+		} else {
+			if isCCW(ring) {
+				ring = reverseOrientation(ring)
+			}
+			poly := items[items[i].parent].poly
+			polygons[poly].Holes = append(polygons[poly].Holes, ring)
+		}
+	}
+	return polygons, depths
+}
+
+// ringArea returns the signed area of a closed ring, which is positive for CCW rings.
+func ringArea(coords []Coord) float64 {
+	a := 0.0
+	for i := 0; i+1 < len(coords); i++ {
+		a += coords[i].X*coords[i+1].Y - coords[i].Y*coords[i+1].X
+	}
+	return a / 2.0
+}
+
+type ringSegment struct {
+	a, b Coord
+	way  uint64
+}
+
+// segmentsCross returns true if both segments intersect in their interiors, and returns the intersection point.
+func segmentsCross(p0, p1, q0, q1 Coord) (Coord, bool) {
+	pd, qd := p1.Sub(p0), q1.Sub(q0)
+	denom := pd.X*qd.Y - pd.Y*qd.X
+	if denom == 0.0 {
+		return Coord{}, false
+	}
+	d := q0.Sub(p0)
+	t := (d.X*qd.Y - d.Y*qd.X) / denom
+	u := (d.X*pd.Y - d.Y*pd.X) / denom
+	if t <= 0.0 || 1.0 <= t || u <= 0.0 || 1.0 <= u {
+		return Coord{}, false
+	}
+	return Coord{p0.X + t*pd.X, p0.Y + t*pd.Y}, true
+}
+
+// ringProblems finds self-intersections and duplicate segments in and between the rings. Segments that lie on or outside the bounds are artefacts of clipping and are ignored.
+func ringProblems(rings [][]Coord, ways []memberWay, bounds Bounds) []Problem {
+	wayIDs := map[Coord]uint64{}
+	for _, way := range ways {
+		for _, c := range way.Coords {
+			if _, ok := wayIDs[c]; !ok {
+				wayIDs[c] = way.ID
+			}
+		}
+	}
+
+	var problems []Problem
+	var segments []ringSegment
+	for _, ring := range rings {
+		for j := 0; j+1 < len(ring); j++ {
+			a, b := ring[j], ring[j+1]
+			if cohenSutherlandOutcode(bounds, a) != 0 && cohenSutherlandOutcode(bounds, b) != 0 {
+				continue
+			}
+			segments = append(segments, ringSegment{a, b, wayIDs[a]})
+		}
+	}
+
+	// duplicate segments
+	seen := make(map[[2]Coord]bool, len(segments))
+	for _, s := range segments {
+		key := [2]Coord{s.a, s.b}
+		if s.b.X < s.a.X || s.b.X == s.a.X && s.b.Y < s.a.Y {
+			key = [2]Coord{s.b, s.a}
+		}
+		if seen[key] {
+			problems = append(problems, Problem{Type: DuplicateSegment, ObjectType: WayType, ID: s.way, Coord: s.a})
+		}
+		seen[key] = true
+	}
+
+	// self-intersections using a sweep over the X-axis
+	slices.SortFunc(segments, func(s, t ringSegment) int {
+		if x0, x1 := math.Min(s.a.X, s.b.X), math.Min(t.a.X, t.b.X); x0 < x1 {
+			return -1
+		} else if x1 < x0 {
+			return 1
+		}
+		return 0
+	})
+	var active []ringSegment
+	for _, s := range segments {
+		minX := math.Min(s.a.X, s.b.X)
+		j := 0
+		for _, t := range active {
+			if minX <= math.Max(t.a.X, t.b.X) {
+				active[j] = t
+				j++
+			}
+		}
+		active = active[:j]
+		for _, t := range active {
+			if math.Max(s.a.Y, s.b.Y) < math.Min(t.a.Y, t.b.Y) || math.Max(t.a.Y, t.b.Y) < math.Min(s.a.Y, s.b.Y) {
+				continue
+			} else if c, ok := segmentsCross(s.a, s.b, t.a, t.b); ok {
+				problems = append(problems, Problem{Type: SelfIntersection, ObjectType: WayType, ID: s.way, Coord: c})
+			}
+		}
+		active = append(active, s)
+	}
+	return problems
+}
+
+// assembleMultipolygon builds rings from all member ways of a multipolygon relation, irrespective of their roles, and decides the nesting of rings by geometry. Ways that cannot be closed are returned as line strings. Problems such as unclosed rings, degenerate rings, self-intersections, duplicate segments, and roles that do not match the geometry are reported.
+func assembleMultipolygon(ways []memberWay, bounds Bounds) ([]PolygonWithHoles, [][]Coord, []Problem) {
+	var problems []Problem
+	var lines [][]Coord
+	var rings [][]Coord
+	var ringWays [][]int
+	for _, chain := range joinWays(ways) {
+		if !chain.closed() {
+			first, last := ways[chain.Ways[0]], ways[chain.Ways[len(chain.Ways)-1]]
+			problems = append(problems, Problem{Type: UnclosedRing, ObjectType: WayType, ID: first.ID, Role: first.Role, Coord: chain.Coords[0]})
+			problems = append(problems, Problem{Type: UnclosedRing, ObjectType: WayType, ID: last.ID, Role: last.Role, Coord: chain.Coords[len(chain.Coords)-1]})
+			lines = append(lines, chain.Coords)
+			continue
+		}
+		for _, ring := range splitRing(closeRing(chain.Coords)) {
+			if !degenerateRing(ring) {
+				rings = append(rings, ring)
+				ringWays = append(ringWays, chain.Ways)
+			} else if inBounds(bounds, ring) {
+				// ignore rings that have been collapsed by clipping
+				first := ways[chain.Ways[0]]
+				problems = append(problems, Problem{Type: DegeneratePolygon, ObjectType: WayType, ID: first.ID, Role: first.Role, Coord: ring[0]})
+			}
+		}
+	}
+	problems = append(problems, ringProblems(rings, ways, bounds)...)
+
+	polygons, depths := nestRings(rings)
+	for i, ring := range rings {
+		expected := "outer"
+		if depths[i]%2 == 1 {
+			expected = "inner"
+		}
+		for _, w := range ringWays[i] {
+			if role := ways[w].Role; (role == "outer" || role == "inner") && role != expected {
+				problems = append(problems, Problem{Type: RoleMismatch, ObjectType: WayType, ID: ways[w].ID, Role: role, Coord: ring[0]})
+			}
+		}
+	}
+	return polygons, lines, problems
+}
@@ -0,0 +1,171 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strconv"
+	"testing"
+)
+
+var testBounds = Bounds{{-100.0, -100.0}, {100.0, 100.0}}
+
+func testWay(id uint64, role string, first, last uint64, coords ...Coord) memberWay {
+	return memberWay{relationWay{ID: id, Coords: coords, First: first, Last: last}, role}
+}
+
+func TestJoinWays(t *testing.T) {
+	// square split into three ways, the second of which is reversed
+	chains := joinWays([]memberWay{
+		testWay(1, "", 1, 2, Coord{0, 0}, Coord{10, 0}),
+		testWay(2, "", 3, 2, Coord{10, 10}, Coord{10, 0}),
+		testWay(3, "", 3, 1, Coord{10, 10}, Coord{0, 10}, Coord{0, 0}),
+		testWay(4, "", 5, 6, Coord{20, 20}, Coord{30, 30}),
+	})
+	if len(chains) != 2 {
+		t.Fatalf("expected 2 chains, got %v", chains)
+	}
+	if !chains[0].closed() || len(chains[0].Coords) != 5 || len(chains[0].Ways) != 3 {
+		t.Errorf("expected closed chain of three ways: %v", chains[0])
+	}
+	if chains[1].closed() {
+		t.Errorf("expected open chain: %v", chains[1])
+	}
+}
+
+func TestAssembleMultipolygon(t *testing.T) {
+	ways := []memberWay{
+		// outer
+		testWay(1, "outer", 1, 1, Coord{0, 0}, Coord{10, 0}, Coord{10, 10}, Coord{0, 10}, Coord{0, 0}),
+		// hole with wrong role
+		testWay(2, "outer", 2, 2, Coord{2, 2}, Coord{8, 2}, Coord{8, 8}, Coord{2, 8}, Coord{2, 2}),
+		// island in the hole
+		testWay(3, "inner", 3, 3, Coord{4, 4}, Coord{6, 4}, Coord{6, 6}, Coord{4, 6}, Coord{4, 4}),
+		// separate polygon
+		testWay(4, "outer", 4, 4, Coord{20, 0}, Coord{20, 10}, Coord{30, 10}, Coord{20, 0}),
+	}
+	polygons, lines, problems := assembleMultipolygon(ways, testBounds)
+	if len(lines) != 0 {
+		t.Errorf("expected no line strings: %v", lines)
+	}
+	if len(polygons) != 3 {
+		t.Fatalf("expected 3 polygons, got %v", polygons)
+	}
+	area := 0.0
+	for _, polygon := range polygons {
+		if !isCCW(polygon.Outer) {
+			t.Errorf("outer ring must be CCW: %v", polygon.Outer)
+		}
+		area += ringArea(polygon.Outer)
+		for _, hole := range polygon.Holes {
+			if isCCW(hole) {
+				t.Errorf("hole must be CW: %v", hole)
+			}
+			area += ringArea(hole)
+		}
+	}
+	if math.Abs(area-(100.0-36.0+4.0+50.0)) > 1e-9 {
+		t.Errorf("wrong total area %v", area)
+	}
+	if len(polygons[0].Holes) != 1 {
+		t.Errorf("expected the largest polygon to have one hole: %v", polygons[0])
+	}
+
+	mismatches := 0
+	for _, problem := range problems {
+		if problem.Type == RoleMismatch {
+			mismatches++
+		} else {
+			t.Errorf("unexpected problem: %v", problem)
+		}
+	}
+	if mismatches != 2 {
+		t.Errorf("expected two role mismatches: %v", problems)
+	}
+}
+
+func TestAssembleMultipolygonProblems(t *testing.T) {
+	ways := []memberWay{
+		// bow-tie
+		testWay(1, "outer", 1, 1, Coord{0, 0}, Coord{10, 10}, Coord{10, 0}, Coord{0, 10}, Coord{0, 0}),
+		// unclosed
+		testWay(2, "outer", 2, 3, Coord{20, 0}, Coord{30, 0}, Coord{30, 10}),
+	}
+	_, lines, problems := assembleMultipolygon(ways, testBounds)
+	if len(lines) != 1 {
+		t.Errorf("expected one line string: %v", lines)
+	}
+	types := map[ProblemType]int{}
+	for _, problem := range problems {
+		types[problem.Type]++
+	}
+	if types[UnclosedRing] != 2 || types[SelfIntersection] != 1 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestExtractProblems(t *testing.T) {
+	// span several blocks so that the parser reuses its buffers
+	relations := make([]Relation, 2*maxBlockObjects+1)
+	for i := range relations {
+		id := uint64(i + 1)
+		relations[i] = Relation{ID: id, Members: []Member{{Type: WayType, ID: id, Role: "role" + strconv.Itoa(i+1)}}}
+	}
+	b := writeTestPBF(t, nil, nil, relations)
+
+	var problems []Problem
+	z := NewParser(bytes.NewReader(b))
+	z.Workers = 1
+	opts := &ExtractOptions{ProblemFunc: func(problem Problem) {
+		problems = append(problems, problem)
+	}}
+	if err := z.ExtractFunc(context.Background(), testBounds, nil, opts, func(Class, Geometry) {}); err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != len(relations) {
+		t.Fatalf("expected %v problems, got %v", len(relations), len(problems))
+	}
+	for _, problem := range problems {
+		if problem.Type != MissingWay || problem.Role != "role"+strconv.FormatUint(problem.Ref, 10) {
+			t.Errorf("wrong problem %v", problem)
+			break
+		}
+	}
+}
+
+func TestSplitRing(t *testing.T) {
+	// figure eight touching at (5,5)
+	rings := splitRing([]Coord{{0, 0}, {5, 5}, {10, 0}, {10, 10}, {5, 5}, {0, 10}, {0, 0}})
+	if len(rings) != 2 || len(rings[0]) != 4 || len(rings[1]) != 4 {
+		t.Errorf("expected two rings: %v", rings)
+	}
+}
+
+func TestAssembleMultipolygonDegenerate(t *testing.T) {
+	ways := []memberWay{
+		testWay(1, "outer", 1, 1, Coord{0, 0}, Coord{5, 0}, Coord{10, 0}, Coord{0, 0}),
+	}
+	polygons, _, problems := assembleMultipolygon(ways, testBounds)
+	if len(polygons) != 0 {
+		t.Errorf("expected no polygons: %v", polygons)
+	}
+	if len(problems) != 1 || problems[0].Type != DegeneratePolygon || problems[0].ID != 1 {
+		t.Errorf("expected degenerate polygon: %v", problems)
+	}
+}
+
+func TestProblemString(t *testing.T) {
+	tests := []struct {
+		problem Problem
+		s       string
+	}{
+		{Problem{Type: UnclosedRing, ObjectType: WayType, ID: 2, Role: "outer", Relation: 1, Coord: Coord{3, 4}}, "unclosed ring in outer way 2 of relation 1 at 3,4"},
+		{Problem{Type: MissingWay, ObjectType: RelationType, ID: 1, Role: "inner", Relation: 1, Ref: 5}, "missing way 5 with role inner in relation 1"},
+		{Problem{Type: MissingNode, ObjectType: WayType, ID: 2, Ref: 7, Coord: Coord{3, 4}}, "missing node 7 in way 2 at 3,4"},
+	}
+	for _, tt := range tests {
+		if s := tt.problem.String(); s != tt.s {
+			t.Errorf("expected %q, got %q", tt.s, s)
+		}
+	}
+}
@@ -98,6 +98,18 @@ const (
 	RelationType
 )
 
+func (t Type) String() string {
+	switch t {
+	case NodeType:
+		return "node"
+	case WayType:
+		return "way"
+	case RelationType:
+		return "relation"
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
 type Member struct {
 	Type Type
 	ID   uint64
@@ -600,7 +612,11 @@ func (z *Parser) stringTable(block Block, buffers *buffers) error {
 			if n == 0 || math.MaxInt < size || len(buf) < i+int(size) {
 				return fmt.Errorf("invalid string in StringTable")
 			}
-			buffers.stringTable = append(buffers.stringTable, unsafe.String(&buf[i], size))
+			if size == 0 {
+				buffers.stringTable = append(buffers.stringTable, "")
+			} else {
+				buffers.stringTable = append(buffers.stringTable, unsafe.String(&buf[i], size))
+			}
 			i += int(size)
 		} else {
 			n := skipField(buf[i:], wireType)
@@ -1139,10 +1155,15 @@ func (z *Parser) relations(block Block, buffers *buffers, buf []byte, fn Relatio
 
 // Parse parses the data and calls the object callback functions for each object. If callback functions are nil it will skip that object type, which is more efficient. Be aware that you need to call `Own` on an object if you which to retain their data after the function call; by default the memory is reused. Note that it will automatically seek to the start of the reader.
 func (z *Parser) Parse(ctx context.Context, nodeFunc NodeFunc, wayFunc WayFunc, relationFunc RelationFunc) error {
-	workers := z.Workers
+	return z.parse(ctx, z.Workers, nodeFunc, wayFunc, relationFunc)
+}
+
+// parse is like Parse but uses the given number of workers, where a single worker calls the callback functions in file order.
+func (z *Parser) parse(ctx context.Context, workers int, nodeFunc NodeFunc, wayFunc WayFunc, relationFunc RelationFunc) error {
 	if workers < 1 {
 		workers = runtime.GOMAXPROCS(0)
-	} else if _, err := z.r.Seek(0, io.SeekStart); err != nil {
+	}
+	if _, err := z.r.Seek(0, io.SeekStart); err != nil {
 		return err
 	}
 	z.pos = 0
@@ -1156,7 +1177,7 @@ func (z *Parser) Parse(ctx context.Context, nodeFunc NodeFunc, wayFunc WayFunc,
 	// decompress and parse Blobs
 	errs := []error{}
 	muErr := sync.Mutex{}
-	blobs := make(chan Blob, z.Workers*2)
+	blobs := make(chan Blob, workers*2)
 	for i := 0; i < workers; i++ {
 		go func() {
 			defer wg.Done()
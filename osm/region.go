@@ -0,0 +1,583 @@
+package osm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Region is a clipping region used for extraction, which is either a Bounds or a PolygonRegion. Bounds may be wrapped to cross the antimeridian.
+type Region interface {
+	// Bounds returns the bounding box of the region.
+	Bounds() Bounds
+
+	// Contains returns true if the coordinate is within or on the region.
+	Contains(Coord) bool
+
+	// clipLineString clips a line string that has already been clipped to the bounding box.
+	clipLineString([]Coord) [][]Coord
+
+	// clipRing clips a closed ring that has already been clipped to the bounding box. The returned rings have the same orientation as the input ring, unless they are holes introduced by the region.
+	clipRing([]Coord) [][]Coord
+}
+
+// Bounds returns itself so that Bounds satisfies the Region interface.
+func (b Bounds) Bounds() Bounds {
+	return b
+}
+
+func (b Bounds) clipLineString(coords []Coord) [][]Coord {
+	if !b.IsWrapped() {
+		return [][]Coord{coords}
+	}
+
+	// clip to both sides of the antimeridian
+	var lines [][]Coord
+	for _, part := range b.wrappedParts() {
+		if !anyInBounds(part, coords) {
+			continue
+		} else if line := clipBounds(part, coords); 1 < len(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func (b Bounds) clipRing(coords []Coord) [][]Coord {
+	if !b.IsWrapped() {
+		return [][]Coord{coords}
+	}
+
+	// clip to both sides of the antimeridian, rings surrounding a side become that side
+	var rings [][]Coord
+	for _, part := range b.wrappedParts() {
+		if ring := closeRing(clipBounds(part, coords)); 3 < len(ring) && ringArea(ring) != 0.0 {
+			rings = append(rings, ring)
+		}
+	}
+	return rings
+}
+
+// anyInBounds returns true if any coordinate is strictly inside the bounds.
+func anyInBounds(bounds Bounds, coords []Coord) bool {
+	for _, c := range coords {
+		if cohenSutherlandOutcode(bounds, c) == 0b0000 {
+			return true
+		}
+	}
+	return false
+}
+
+type regionEdge struct {
+	ring, index int
+}
+
+// PolygonRegion is a clipping region consisting of one or more rings, such as loaded from an Osmosis .poly file or a GeoJSON (multi)polygon. Outer rings are CCW oriented and holes are CW oriented, and a coordinate is within the region using the non-zero winding rule.
+type PolygonRegion struct {
+	Rings [][]Coord // closed rings, the last coordinate equals the first
+
+	bounds Bounds
+	bandH  float64
+	bands  [][]regionEdge // edges per horizontal band to speed up intersection and containment tests
+}
+
+// NewPolygonRegion returns a new clipping region from outer rings and holes. Rings are closed automatically, split where they cross the antimeridian, and reoriented so that outer rings are CCW and holes are CW.
+func NewPolygonRegion(outers, holes [][]Coord) *PolygonRegion {
+	r := &PolygonRegion{}
+	for i, rings := range [][][]Coord{outers, holes} {
+		for _, ring := range rings {
+			if len(ring) < 3 {
+				continue
+			} else if ring[0] != ring[len(ring)-1] {
+				ring = append(ring[:len(ring):len(ring)], ring[0])
+			}
+			if len(ring) < 4 {
+				continue
+			}
+			for _, ring := range splitRingAntimeridian(ring) {
+				if isCCW(ring) != (i == 0) {
+					ring = reverseOrientation(ring)
+				}
+				r.Rings = append(r.Rings, ring)
+			}
+		}
+	}
+	r.index()
+	return r
+}
+
+func (r *PolygonRegion) index() {
+	r.bounds = Bounds{{math.Inf(1), math.Inf(1)}, {math.Inf(-1), math.Inf(-1)}}
+	n := 0
+	for _, ring := range r.Rings {
+		for _, c := range ring {
+			r.bounds[0].X = math.Min(r.bounds[0].X, c.X)
+			r.bounds[0].Y = math.Min(r.bounds[0].Y, c.Y)
+			r.bounds[1].X = math.Max(r.bounds[1].X, c.X)
+			r.bounds[1].Y = math.Max(r.bounds[1].Y, c.Y)
+		}
+		n += len(ring) - 1
+	}
+	if n == 0 {
+		r.bounds = Bounds{}
+		return
+	}
+
+	numBands := max(1, min(int(math.Sqrt(float64(n))), 4096))
+	r.bandH = r.bounds.H() / float64(numBands)
+	if r.bandH == 0.0 {
+		numBands, r.bandH = 1, 1.0
+	}
+	r.bands = make([][]regionEdge, numBands)
+	for i, ring := range r.Rings {
+		for j := 0; j+1 < len(ring); j++ {
+			b0, b1 := r.band(ring[j].Y), r.band(ring[j+1].Y)
+			if b1 < b0 {
+				b0, b1 = b1, b0
+			}
+			for b := b0; b <= b1; b++ {
+				r.bands[b] = append(r.bands[b], regionEdge{i, j})
+			}
+		}
+	}
+}
+
+func (r *PolygonRegion) band(y float64) int {
+	b := int((y - r.bounds[0].Y) / r.bandH)
+	return max(0, min(b, len(r.bands)-1))
+}
+
+// Bounds returns the bounding box of the region.
+func (r *PolygonRegion) Bounds() Bounds {
+	return r.bounds
+}
+
+// Contains returns true if the coordinate is within or on the region.
+func (r *PolygonRegion) Contains(c Coord) bool {
+	if len(r.bands) == 0 || !r.bounds.Contains(c) {
+		return false
+	}
+	winding := 0
+	for _, e := range r.bands[r.band(c.Y)] {
+		p, q := r.Rings[e.ring][e.index], r.Rings[e.ring][e.index+1]
+		if p.Y <= c.Y && c.Y < q.Y {
+			if cross := (q.X-p.X)*(c.Y-p.Y) - (c.X-p.X)*(q.Y-p.Y); 0.0 < cross {
+				winding++
+			} else if cross == 0.0 {
+				return true // on edge
+			}
+		} else if q.Y <= c.Y && c.Y < p.Y {
+			if cross := (q.X-p.X)*(c.Y-p.Y) - (c.X-p.X)*(q.Y-p.Y); cross < 0.0 {
+				winding--
+			} else if cross == 0.0 {
+				return true // on edge
+			}
+		}
+	}
+	return winding != 0
+}
+
+// regionCrossing is an intersection between a segment of the subject and an edge of the region.
+type regionCrossing struct {
+	Coord
+	i     int     // subject segment index
+	t     float64 // position along subject segment
+	ring  int     // region ring index
+	enter bool    // subject enters the region
+}
+
+// crossings returns all intersections between the subject and the region's edges, ordered along the subject.
+func (r *PolygonRegion) crossings(coords []Coord) []regionCrossing {
+	var crossings []regionCrossing
+	for i := 0; i+1 < len(coords); i++ {
+		p0, p1 := coords[i], coords[i+1]
+		pd := p1.Sub(p0)
+		y0, y1 := math.Min(p0.Y, p1.Y), math.Max(p0.Y, p1.Y)
+		if y1 < r.bounds[0].Y || r.bounds[1].Y < y0 || math.Max(p0.X, p1.X) < r.bounds[0].X || r.bounds[1].X < math.Min(p0.X, p1.X) {
+			continue
+		}
+		b0, b1 := r.band(y0), r.band(y1)
+		for b := b0; b <= b1; b++ {
+			for _, e := range r.bands[b] {
+				q0, q1 := r.Rings[e.ring][e.index], r.Rings[e.ring][e.index+1]
+				if r.band(math.Max(y0, math.Min(q0.Y, q1.Y))) != b {
+					continue // report each pair only once
+				}
+				qd := q1.Sub(q0)
+				denom := pd.X*qd.Y - pd.Y*qd.X
+				if denom == 0.0 {
+					continue // parallel or collinear
+				}
+				d := q0.Sub(p0)
+				t := (d.X*qd.Y - d.Y*qd.X) / denom
+				u := (d.X*pd.Y - d.Y*pd.X) / denom
+				if t < 0.0 || 1.0 <= t || u < 0.0 || 1.0 <= u {
+					continue
+				}
+				crossings = append(crossings, regionCrossing{
+					Coord: Coord{p0.X + t*pd.X, p0.Y + t*pd.Y},
+					i:     i,
+					t:     t,
+					ring:  e.ring,
+					enter: denom < 0.0,
+				})
+			}
+		}
+	}
+	slices.SortFunc(crossings, func(a, b regionCrossing) int {
+		if a.i != b.i {
+			return a.i - b.i
+		} else if a.t < b.t {
+			return -1
+		} else if b.t < a.t {
+			return 1
+		}
+		return 0
+	})
+	return crossings
+}
+
+func (r *PolygonRegion) clipLineString(coords []Coord) [][]Coord {
+	if len(coords) < 2 {
+		return nil
+	}
+	crossings := r.crossings(coords)
+	if len(crossings) == 0 {
+		if r.Contains(coords[0]) && r.Contains(coords[len(coords)-1]) {
+			return [][]Coord{coords}
+		}
+		return nil
+	}
+
+	var lines [][]Coord
+	var line []Coord
+	inside := !crossings[0].enter
+	if inside {
+		line = append(line, coords[0])
+	}
+	k := 0
+	for i := 0; i+1 < len(coords); i++ {
+		for ; k < len(crossings) && crossings[k].i == i; k++ {
+			if crossings[k].enter {
+				line = []Coord{crossings[k].Coord}
+			} else if line != nil {
+				line = append(line, crossings[k].Coord)
+				if 1 < len(line) {
+					lines = append(lines, line)
+				}
+				line = nil
+			}
+			inside = crossings[k].enter
+		}
+		if inside && line != nil && line[len(line)-1] != coords[i+1] {
+			line = append(line, coords[i+1])
+		}
+	}
+	if line != nil && 1 < len(line) {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (r *PolygonRegion) clipRing(coords []Coord) [][]Coord {
+	if len(coords) < 4 {
+		return nil
+	} else if !isCCW(coords) {
+		rings := r.clipRing(reverseOrientation(coords))
+		for i := range rings {
+			rings[i] = reverseOrientation(rings[i])
+		}
+		return rings
+	}
+
+	var rings [][]Coord
+	crossings := r.crossings(coords)
+
+	// add region rings that are not intersected but lie within the subject
+	crossed := make([]bool, len(r.Rings))
+	for _, c := range crossings {
+		crossed[c.ring] = true
+	}
+	for i, ring := range r.Rings {
+		if !crossed[i] && pointInRing(ring[0], coords) {
+			rings = append(rings, slices.Clone(ring))
+		}
+	}
+	if len(crossings) == 0 {
+		if r.Contains(coords[0]) {
+			rings = append(rings, coords)
+		}
+		return rings
+	}
+
+	return append(rings, r.closeLineStrings(r.clipRingLineStrings(coords))...)
+}
+
+// clipRingLineStrings returns the parts of a closed ring within the region, which start and end on the region's boundary. The first and last part are joined if the ring starts within the region.
+func (r *PolygonRegion) clipRingLineStrings(coords []Coord) [][]Coord {
+	lines := r.clipLineString(coords)
+	if 1 < len(lines) && lines[0][0] == coords[0] && lines[len(lines)-1][len(lines[len(lines)-1])-1] == coords[0] {
+		lines[0] = append(lines[len(lines)-1], lines[0][1:]...)
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// regionPos is a position along the region's boundary.
+type regionPos struct {
+	ring int
+	pos  float64 // edge index plus the position along the edge
+}
+
+// locate returns the position of a coordinate along the nearest edge of the region's boundary.
+func (r *PolygonRegion) locate(c Coord) regionPos {
+	best, dist := regionPos{-1, 0.0}, math.Inf(1)
+	for _, e := range r.bands[r.band(c.Y)] {
+		p, q := r.Rings[e.ring][e.index], r.Rings[e.ring][e.index+1]
+		d := q.Sub(p)
+		t := 0.0
+		if l := d.X*d.X + d.Y*d.Y; l != 0.0 {
+			t = math.Max(0.0, math.Min(1.0, ((c.X-p.X)*d.X+(c.Y-p.Y)*d.Y)/l))
+		}
+		dx, dy := p.X+t*d.X-c.X, p.Y+t*d.Y-c.Y
+		if dist2 := dx*dx + dy*dy; dist2 < dist {
+			best, dist = regionPos{e.ring, float64(e.index) + t}, dist2
+		}
+	}
+	if best.ring != -1 && float64(len(r.Rings[best.ring])-1) <= best.pos {
+		best.pos = 0.0 // last vertex equals the first
+	}
+	return best
+}
+
+// closeLineStrings closes line strings that start and end on the region's boundary into rings. From the end of each line string it follows the region's ring, in the direction of the ring, to the start of the next line string. Line strings must have the interior on their left side, such as the land side of coastlines. Line strings that cannot be closed are dropped.
+func (r *PolygonRegion) closeLineStrings(lines [][]Coord) [][]Coord {
+	if len(r.bands) == 0 {
+		return nil
+	}
+	starts := make([]regionPos, len(lines))
+	ends := make([]regionPos, len(lines))
+	for i, line := range lines {
+		starts[i] = r.locate(line[0])
+		ends[i] = r.locate(line[len(line)-1])
+	}
+
+	var rings [][]Coord
+	used := make([]bool, len(lines))
+	for start := range lines {
+		if used[start] {
+			continue
+		}
+		var ring []Coord
+		k, closed := start, false
+		for range lines {
+			used[k] = true
+			ring = append(ring, lines[k]...)
+
+			// find the next start along the region's ring
+			e := ends[k]
+			next, dist := -1, math.Inf(1)
+			m := float64(len(r.Rings[e.ring]) - 1)
+			for i, s := range starts {
+				if s.ring == e.ring && (!used[i] || i == start) {
+					d := s.pos - e.pos
+					if d < 0.0 {
+						d += m
+					}
+					if d < dist {
+						next, dist = i, d
+					}
+				}
+			}
+			if next == -1 {
+				break
+			}
+
+			// follow the region's ring
+			regionRing := r.Rings[e.ring]
+			j0, j1 := int(e.pos), int(starts[next].pos)
+			if j0 != j1 || starts[next].pos < e.pos {
+				for j := j0; ; {
+					j = (j + 1) % (len(regionRing) - 1)
+					ring = append(ring, regionRing[j])
+					if j == j1 {
+						break
+					}
+				}
+			}
+			if next == start {
+				closed = true
+				break
+			}
+			k = next
+		}
+		if closed {
+			ring = append(ring, ring[0])
+			if ring = removeDuplicateCoords(ring); 3 < len(ring) {
+				rings = append(rings, ring)
+			}
+		}
+	}
+	return rings
+}
+
+// removeDuplicateCoords removes consecutive duplicate coordinates in-place.
+func removeDuplicateCoords(coords []Coord) []Coord {
+	if len(coords) < 2 {
+		return coords
+	}
+	j := 1
+	for i := 1; i < len(coords); i++ {
+		if coords[i] != coords[j-1] {
+			coords[j] = coords[i]
+			j++
+		}
+	}
+	return coords[:j]
+}
+
+// pointInRing returns true if the coordinate lies within the closed ring using the even-odd rule.
+func pointInRing(c Coord, ring []Coord) bool {
+	inside := false
+	for i := 0; i+1 < len(ring); i++ {
+		p, q := ring[i], ring[i+1]
+		if (p.Y <= c.Y) != (q.Y <= c.Y) && c.X < p.X+(c.Y-p.Y)/(q.Y-p.Y)*(q.X-p.X) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// ParsePoly parses an Osmosis polygon filter file (.poly) and returns the clipping region. Sections whose name starts with an exclamation mark are holes.
+func ParsePoly(r io.Reader) (*PolygonRegion, error) {
+	var outers, holes [][]Coord
+	scanner := bufio.NewScanner(r)
+	line := 0
+	section := ""
+	var ring []Coord
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 || text == "" {
+			// name of the polygon
+			continue
+		} else if section == "" {
+			if text == "END" {
+				return NewPolygonRegion(outers, holes), scanner.Err()
+			}
+			section = text
+			ring = ring[:0:0]
+			continue
+		} else if text == "END" {
+			if strings.HasPrefix(section, "!") {
+				holes = append(holes, ring)
+			} else {
+				outers = append(outers, ring)
+			}
+			section = ""
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid coordinate on line %v", line)
+		}
+		x, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude on line %v: %w", line, err)
+		}
+		y, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude on line %v: %w", line, err)
+		}
+		ring = append(ring, Coord{x, y})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("unexpected end of polygon file")
+}
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Geometries  []geoJSON       `json:"geometries"`
+	Features    []geoJSON       `json:"features"`
+}
+
+// ParseGeoJSONRegion parses a GeoJSON Polygon or MultiPolygon, or a Feature, FeatureCollection, or GeometryCollection containing those, and returns the clipping region.
+func ParseGeoJSONRegion(r io.Reader) (*PolygonRegion, error) {
+	var obj geoJSON
+	if err := json.NewDecoder(r).Decode(&obj); err != nil {
+		return nil, err
+	}
+
+	var outers, holes [][]Coord
+	var add func(geoJSON) error
+	add = func(obj geoJSON) error {
+		switch obj.Type {
+		case "Polygon":
+			var polygon [][][2]float64
+			if err := json.Unmarshal(obj.Coordinates, &polygon); err != nil {
+				return fmt.Errorf("invalid Polygon: %w", err)
+			}
+			outers, holes = appendGeoJSONPolygon(outers, holes, polygon)
+		case "MultiPolygon":
+			var polygons [][][][2]float64
+			if err := json.Unmarshal(obj.Coordinates, &polygons); err != nil {
+				return fmt.Errorf("invalid MultiPolygon: %w", err)
+			}
+			for _, polygon := range polygons {
+				outers, holes = appendGeoJSONPolygon(outers, holes, polygon)
+			}
+		case "Feature":
+			if obj.Geometry != nil {
+				return add(*obj.Geometry)
+			}
+		case "FeatureCollection":
+			for _, feature := range obj.Features {
+				if err := add(feature); err != nil {
+					return err
+				}
+			}
+		case "GeometryCollection":
+			for _, geometry := range obj.Geometries {
+				if err := add(geometry); err != nil {
+					return err
+				}
+			}
+		case "Point", "MultiPoint", "LineString", "MultiLineString":
+			// ignore
+		default:
+			return fmt.Errorf("unknown GeoJSON type %v", obj.Type)
+		}
+		return nil
+	}
+	if err := add(obj); err != nil {
+		return nil, err
+	} else if len(outers) == 0 {
+		return nil, fmt.Errorf("GeoJSON has no polygons")
+	}
+	return NewPolygonRegion(outers, holes), nil
+}
+
+func appendGeoJSONPolygon(outers, holes [][]Coord, polygon [][][2]float64) ([][]Coord, [][]Coord) {
+	for i, positions := range polygon {
+		ring := make([]Coord, len(positions))
+		for j, pos := range positions {
+			ring[j] = Coord{pos[0], pos[1]}
+		}
+		if i == 0 {
+			outers = append(outers, ring)
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+	return outers, holes
+}
@@ -0,0 +1,120 @@
+package osm
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const testPoly = `square
+1
+   0.0 0.0
+   10.0 0.0
+   10.0 10.0
+   0.0 10.0
+END
+!2
+   4.0 4.0
+   6.0 4.0
+   6.0 6.0
+   4.0 6.0
+END
+END
+`
+
+func TestParsePoly(t *testing.T) {
+	region, err := ParsePoly(strings.NewReader(testPoly))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(region.Rings) != 2 {
+		t.Fatalf("expected 2 rings, got %v", len(region.Rings))
+	}
+	if !isCCW(region.Rings[0]) || isCCW(region.Rings[1]) {
+		t.Errorf("outer ring must be CCW and hole must be CW")
+	}
+	if region.Bounds() != (Bounds{{0.0, 0.0}, {10.0, 10.0}}) {
+		t.Errorf("wrong bounds %v", region.Bounds())
+	}
+
+	tests := []struct {
+		c      Coord
+		inside bool
+	}{
+		{Coord{1.0, 1.0}, true},
+		{Coord{5.0, 5.0}, false},
+		{Coord{11.0, 5.0}, false},
+		{Coord{0.0, 5.0}, true},
+		{Coord{3.0, 5.0}, true},
+	}
+	for _, tt := range tests {
+		if inside := region.Contains(tt.c); inside != tt.inside {
+			t.Errorf("Contains(%v) = %v, expected %v", tt.c, inside, tt.inside)
+		}
+	}
+}
+
+func TestParseGeoJSONRegion(t *testing.T) {
+	region, err := ParseGeoJSONRegion(strings.NewReader(`{"type":"Feature","geometry":{"type":"MultiPolygon","coordinates":[[[[0,0],[0,1],[1,1],[1,0],[0,0]]],[[[2,0],[3,0],[3,1],[2,1],[2,0]]]]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(region.Rings) != 2 || !isCCW(region.Rings[0]) || !isCCW(region.Rings[1]) {
+		t.Errorf("expected two CCW rings")
+	}
+	if !region.Contains(Coord{2.5, 0.5}) || region.Contains(Coord{1.5, 0.5}) {
+		t.Errorf("wrong containment")
+	}
+}
+
+func TestPolygonRegionClipLineString(t *testing.T) {
+	region, _ := ParsePoly(strings.NewReader(testPoly))
+	lines := region.clipLineString([]Coord{{-5.0, 5.0}, {15.0, 5.0}})
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 line strings, got %v", lines)
+	}
+	if lines[0][0] != (Coord{0.0, 5.0}) || lines[0][1] != (Coord{4.0, 5.0}) {
+		t.Errorf("wrong first line string %v", lines[0])
+	}
+	if lines[1][0] != (Coord{6.0, 5.0}) || lines[1][1] != (Coord{10.0, 5.0}) {
+		t.Errorf("wrong second line string %v", lines[1])
+	}
+}
+
+func TestPolygonRegionClipRing(t *testing.T) {
+	region, _ := ParsePoly(strings.NewReader(testPoly))
+
+	// ring covering the right half of the region including half the hole
+	rings := region.clipRing([]Coord{{5.0, -5.0}, {15.0, -5.0}, {15.0, 15.0}, {5.0, 15.0}, {5.0, -5.0}})
+	area := 0.0
+	for _, ring := range rings {
+		if ring[0] != ring[len(ring)-1] {
+			t.Errorf("ring not closed: %v", ring)
+		}
+		area += ringArea(ring)
+	}
+	if math.Abs(area-48.0) > 1e-9 {
+		t.Errorf("expected area 48, got %v: %v", area, rings)
+	}
+
+	// ring containing the hole entirely
+	rings = region.clipRing([]Coord{{3.0, 3.0}, {7.0, 3.0}, {7.0, 7.0}, {3.0, 7.0}, {3.0, 3.0}})
+	area = 0.0
+	for _, ring := range rings {
+		area += ringArea(ring)
+	}
+	if len(rings) != 2 || math.Abs(area-12.0) > 1e-9 {
+		t.Errorf("expected two rings with area 12, got %v: %v", area, rings)
+	}
+
+	// hole ring is returned with CW orientation
+	rings = region.clipRing([]Coord{{-1.0, -1.0}, {-1.0, 1.0}, {1.0, 1.0}, {1.0, -1.0}, {-1.0, -1.0}})
+	if len(rings) != 1 || isCCW(rings[0]) || math.Abs(ringArea(rings[0])+1.0) > 1e-9 {
+		t.Errorf("expected one CW ring with area -1, got %v", rings)
+	}
+
+	// ring outside the region
+	if rings = region.clipRing([]Coord{{20.0, 20.0}, {21.0, 20.0}, {21.0, 21.0}, {20.0, 20.0}}); len(rings) != 0 {
+		t.Errorf("expected no rings, got %v", rings)
+	}
+}
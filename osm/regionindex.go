@@ -0,0 +1,145 @@
+package osm
+
+import (
+	"math"
+	"slices"
+)
+
+// GridRegions splits the bounds into a regular grid of nx by ny cells and returns them as regions for ExtractMany, row by row starting at the minimum coordinate. Wrapped bounds give cells that may wrap as well.
+func GridRegions(bounds Bounds, nx, ny int) []Region {
+	if nx < 1 || ny < 1 {
+		return nil
+	}
+	w, h := bounds.W()/float64(nx), bounds.H()/float64(ny)
+	regions := make([]Region, 0, nx*ny)
+	for j := 0; j < ny; j++ {
+		y0, y1 := bounds[0].Y+float64(j)*h, bounds[0].Y+float64(j+1)*h
+		if j == ny-1 {
+			y1 = bounds[1].Y
+		}
+		for i := 0; i < nx; i++ {
+			x0, x1 := bounds[0].X+float64(i)*w, bounds[0].X+float64(i+1)*w
+			if i == nx-1 {
+				x1 = bounds[1].X
+			}
+			if bounds.IsWrapped() {
+				x0, x1 = wrapLongitude(x0), wrapLongitude(x1)
+			}
+			regions = append(regions, Bounds{{x0, y0}, {x1, y1}})
+		}
+	}
+	return regions
+}
+
+// union returns the bounds that contain both bounds, neither may be wrapped.
+func (b Bounds) union(a Bounds) Bounds {
+	return Bounds{
+		{math.Min(b[0].X, a[0].X), math.Min(b[0].Y, a[0].Y)},
+		{math.Max(b[1].X, a[1].X), math.Max(b[1].Y, a[1].Y)},
+	}
+}
+
+// overlaps returns true if the bounds intersect or touch, neither may be wrapped.
+func (b Bounds) overlaps(a Bounds) bool {
+	return b[0].X <= a[1].X && a[0].X <= b[1].X && b[0].Y <= a[1].Y && a[0].Y <= b[1].Y
+}
+
+// regionIndex is a uniform grid over the bounds of regions to find the regions that may intersect a bounding box.
+type regionIndex struct {
+	regions []Region
+	bounds  []Bounds // bounds per region, wrapped bounds span all longitudes
+	extent  Bounds   // union of all bounds
+
+	nx, ny       int
+	cellW, cellH float64
+	cells        [][]int // region indices per cell
+}
+
+func newRegionIndex(regions []Region) *regionIndex {
+	idx := &regionIndex{
+		regions: regions,
+		bounds:  make([]Bounds, len(regions)),
+		extent:  Bounds{{math.Inf(1), math.Inf(1)}, {math.Inf(-1), math.Inf(-1)}},
+	}
+	for i, region := range regions {
+		b := region.Bounds()
+		if b.IsWrapped() {
+			// the region clips to both sides of the antimeridian
+			b[0].X, b[1].X = math.Nextafter(-180.0, math.Inf(-1)), math.Nextafter(180.0, math.Inf(1))
+		}
+		idx.bounds[i] = b
+		idx.extent = idx.extent.union(b)
+	}
+
+	n := max(1, min(int(math.Sqrt(float64(len(regions)))), 1024))
+	idx.nx, idx.ny = n, n
+	idx.cellW, idx.cellH = idx.extent.W()/float64(n), idx.extent.H()/float64(n)
+	if !(0.0 < idx.cellW && idx.cellW < math.Inf(1)) {
+		idx.nx, idx.cellW = 1, 1.0
+	}
+	if !(0.0 < idx.cellH && idx.cellH < math.Inf(1)) {
+		idx.ny, idx.cellH = 1, 1.0
+	}
+	idx.cells = make([][]int, idx.nx*idx.ny)
+	for i, region := range regions {
+		parts := []Bounds{idx.bounds[i]}
+		if b := region.Bounds(); b.IsWrapped() {
+			wrapped := b.wrappedParts()
+			parts = wrapped[:]
+		}
+		for _, part := range parts {
+			x0, y0 := idx.cell(part[0])
+			x1, y1 := idx.cell(part[1])
+			for y := y0; y <= y1; y++ {
+				for x := x0; x <= x1; x++ {
+					cell := &idx.cells[y*idx.nx+x]
+					if len(*cell) == 0 || (*cell)[len(*cell)-1] != i {
+						*cell = append(*cell, i)
+					}
+				}
+			}
+		}
+	}
+	return idx
+}
+
+func (idx *regionIndex) cell(c Coord) (int, int) {
+	x, y := 0, 0
+	if 1 < idx.nx {
+		x = max(0, min(int((c.X-idx.extent[0].X)/idx.cellW), idx.nx-1))
+	}
+	if 1 < idx.ny {
+		y = max(0, min(int((c.Y-idx.extent[0].Y)/idx.cellH), idx.ny-1))
+	}
+	return x, y
+}
+
+// query calls fn once for each region whose bounds intersect or touch b.
+func (idx *regionIndex) query(b Bounds, fn func(int)) {
+	if !idx.extent.overlaps(b) {
+		return
+	}
+	x0, y0 := idx.cell(b[0])
+	x1, y1 := idx.cell(b[1])
+	if x0 == x1 && y0 == y1 {
+		for _, i := range idx.cells[y0*idx.nx+x0] {
+			if idx.bounds[i].overlaps(b) {
+				fn(i)
+			}
+		}
+		return
+	}
+
+	var candidates []int
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			candidates = append(candidates, idx.cells[y*idx.nx+x]...)
+		}
+	}
+	slices.Sort(candidates)
+	for _, i := range slices.Compact(candidates) {
+		if idx.bounds[i].overlaps(b) {
+			fn(i)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestGridRegions(t *testing.T) {
+	regions := GridRegions(Bounds{{170.0, 0.0}, {-170.0, 10.0}}, 2, 2)
+	expected := []Region{
+		Bounds{{170.0, 0.0}, {180.0, 5.0}},
+		Bounds{{180.0, 0.0}, {-170.0, 5.0}},
+		Bounds{{170.0, 5.0}, {180.0, 10.0}},
+		Bounds{{180.0, 5.0}, {-170.0, 10.0}},
+	}
+	if !reflect.DeepEqual(regions, expected) {
+		t.Errorf("expected %v, got %v", expected, regions)
+	}
+}
+
+func TestRegionIndex(t *testing.T) {
+	regions := GridRegions(Bounds{{0.0, 0.0}, {10.0, 10.0}}, 5, 5)
+	regions = append(regions, Bounds{{170.0, 0.0}, {-170.0, 10.0}})
+	idx := newRegionIndex(regions)
+
+	query := func(b Bounds) []int {
+		var found []int
+		idx.query(b, func(i int) {
+			found = append(found, i)
+		})
+		return found
+	}
+	if found := query(Bounds{{1.0, 1.0}, {1.0, 1.0}}); !slices.Equal(found, []int{0}) {
+		t.Errorf("wrong regions for point: %v", found)
+	}
+	if found := query(Bounds{{1.0, 1.0}, {3.0, 1.0}}); !slices.Equal(found, []int{0, 1}) {
+		t.Errorf("wrong regions for line: %v", found)
+	}
+	if found := query(Bounds{{175.0, 5.0}, {175.0, 5.0}}); !slices.Equal(found, []int{25}) {
+		t.Errorf("wrong regions for wrapped region: %v", found)
+	}
+	if found := query(Bounds{{20.0, 20.0}, {30.0, 30.0}}); len(found) != 0 {
+		t.Errorf("expected no regions: %v", found)
+	}
+}
+
+func TestExtractMany(t *testing.T) {
+	b := writeTestPBF(t, testNodes, testWays, testRelations)
+	regions := GridRegions(Bounds{{-10.0, -10.0}, {30.0, 10.0}}, 2, 2)
+	opts := &ExtractOptions{Ordered: true}
+
+	many := make([][]Geometry, len(regions))
+	z := NewParser(bytes.NewReader(b))
+	err := z.ExtractManyFunc(context.Background(), regions, nil, opts, func(i int, class Class, geom Geometry) {
+		many[i] = append(many[i], geom)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	for i, region := range regions {
+		var single []Geometry
+		z := NewParser(bytes.NewReader(b))
+		err := z.ExtractFunc(context.Background(), region, nil, opts, func(class Class, geom Geometry) {
+			single = append(single, geom)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(many[i], single) {
+			t.Errorf("region %v: expected %v, got %v", i, single, many[i])
+		}
+		n += len(single)
+	}
+	if n == 0 {
+		t.Errorf("expected geometries")
+	}
+}
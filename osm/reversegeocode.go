@@ -0,0 +1,134 @@
+package osm
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+)
+
+// AdminArea is an administrative boundary, such as a country (level 2), province (level 4), or municipality (level 8).
+type AdminArea struct {
+	ID       uint64
+	Level    int // admin_level
+	Name     string
+	Tags     Tags
+	Polygons []PolygonWithHoles
+}
+
+// NameIn returns the name in the given language, such as "en" for name:en, or the default name if not available.
+func (a AdminArea) NameIn(lang string) string {
+	if name := a.Tags.Find("name:" + lang); name != "" {
+		return name
+	}
+	return a.Name
+}
+
+// adminLevel returns the admin_level of boundary=administrative tags, or zero otherwise.
+func adminLevel(tags Tags) int {
+	if tags.Find("boundary") != "administrative" {
+		return 0
+	}
+	level, err := strconv.Atoi(tags.Find("admin_level"))
+	if err != nil || level < 1 {
+		return 0
+	}
+	return level
+}
+
+// ExtractAdminAreas extracts all boundary=administrative relations with an admin_level within the region, using the same passes as Extract. Boundaries are assembled into polygons and clipped to the region, and boundaries that cannot be closed are skipped.
+func (z *Parser) ExtractAdminAreas(ctx context.Context, region Region) ([]AdminArea, error) {
+	filter := func(typ Type, id uint64, tags Tags) Class {
+		if typ == RelationType && adminLevel(tags) != 0 {
+			return 1
+		}
+		return 0
+	}
+
+	areas := []AdminArea{}
+	fn := func(_ Class, geom Geometry) {
+		if len(geom.Polygons) == 0 {
+			return
+		}
+		areas = append(areas, AdminArea{
+			ID:       geom.ID,
+			Level:    adminLevel(geom.Tags),
+			Name:     geom.Tags.Find("name"),
+			Tags:     geom.Tags,
+			Polygons: geom.Polygons,
+		})
+	}
+	if err := z.ExtractFunc(ctx, region, filter, &ExtractOptions{Ordered: true}, fn); err != nil {
+		return nil, err
+	}
+	return areas, nil
+}
+
+// ReverseGeocoder finds the administrative areas that contain a coordinate, using an R-tree over the areas.
+type ReverseGeocoder struct {
+	areas []AdminArea
+	tree  *RTree
+}
+
+// NewReverseGeocoder returns a reverse geocoder for the administrative areas, which are ordered by level and by decreasing size so that lookups return the hierarchy from the largest to the smallest area.
+func NewReverseGeocoder(areas []AdminArea) *ReverseGeocoder {
+	sizes := make(map[uint64]float64, len(areas))
+	for _, area := range areas {
+		size := 0.0
+		for _, polygon := range area.Polygons {
+			a, _ := polygonCentroid(polygon)
+			size += a
+		}
+		sizes[area.ID] = size
+	}
+	areas = slices.Clone(areas)
+	slices.SortStableFunc(areas, func(a, b AdminArea) int {
+		if c := cmp.Compare(a.Level, b.Level); c != 0 {
+			return c
+		}
+		return cmp.Compare(sizes[b.ID], sizes[a.ID])
+	})
+
+	bounds := make([]Bounds, len(areas))
+	for i, area := range areas {
+		bounds[i] = Geometry{Polygons: area.Polygons}.Bounds()
+	}
+	return &ReverseGeocoder{
+		areas: areas,
+		tree:  NewRTreeFromBounds(bounds, DefaultRTreeNodeSize),
+	}
+}
+
+// Areas returns all administrative areas, ordered by level and by decreasing size.
+func (g *ReverseGeocoder) Areas() []AdminArea {
+	return g.areas
+}
+
+// Lookup returns the administrative areas that contain c, ordered by level from the country down to the smallest area. Areas at the same level are ordered by decreasing size.
+func (g *ReverseGeocoder) Lookup(c Coord) []AdminArea {
+	indices := []int{}
+	g.tree.Search(Bounds{c, c}, func(i int) bool {
+		if (Geometry{Polygons: g.areas[i].Polygons}).Contains(c) {
+			indices = append(indices, i)
+		}
+		return true
+	})
+	slices.Sort(indices)
+
+	areas := make([]AdminArea, 0, len(indices))
+	for _, i := range indices {
+		areas = append(areas, g.areas[i])
+	}
+	return areas
+}
+
+// LookupLevel returns the administrative area at the given level that contains c. If there are multiple, the smallest is returned.
+func (g *ReverseGeocoder) LookupLevel(c Coord, level int) (AdminArea, bool) {
+	areas := g.Lookup(c)
+	for i := len(areas) - 1; 0 <= i; i-- {
+		if areas[i].Level == level {
+			return areas[i], true
+		}
+	}
+	return AdminArea{}, false
+}
@@ -0,0 +1,79 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+)
+
+func adminTags(level, name string) Tags {
+	tags := Tags{{"boundary", "administrative"}, {"name", name}}
+	if level != "" {
+		tags = append(tags, Tag{"admin_level", level})
+	}
+	return tags
+}
+
+func TestReverseGeocoder(t *testing.T) {
+	nodes := []Node{
+		{ID: 1, Lon: 0.0, Lat: 0.0},
+		{ID: 2, Lon: 10.0, Lat: 0.0},
+		{ID: 3, Lon: 10.0, Lat: 10.0},
+		{ID: 4, Lon: 0.0, Lat: 10.0},
+		{ID: 5, Lon: 5.0, Lat: 0.0},
+		{ID: 6, Lon: 5.0, Lat: 10.0},
+		{ID: 7, Lon: 5.0, Lat: 5.0},
+		{ID: 8, Lon: 0.0, Lat: 5.0},
+	}
+	ways := []Way{
+		{ID: 1, Refs: []uint64{1, 2, 3, 4, 1}},
+		{ID: 2, Refs: []uint64{1, 5, 6, 4, 1}},
+		{ID: 3, Refs: []uint64{1, 5, 7, 8, 1}},
+		{ID: 4, Refs: []uint64{5, 2, 3, 6, 5}, Tags: adminTags("4", "East")}, // ways are ignored
+	}
+	relations := []Relation{
+		{ID: 1, Members: []Member{{WayType, 3, "outer"}}, Tags: adminTags("8", "Town")},
+		{ID: 2, Members: []Member{{WayType, 2, "outer"}}, Tags: adminTags("4", "West")},
+		{ID: 3, Members: []Member{{WayType, 1, "outer"}}, Tags: append(adminTags("2", "Land"), Tag{"name:en", "Country"})},
+		{ID: 4, Members: []Member{{WayType, 1, "outer"}}, Tags: adminTags("", "Unknown")},
+	}
+	b := writeTestPBF(t, nodes, ways, relations)
+
+	z := NewParser(bytes.NewReader(b))
+	areas, err := z.ExtractAdminAreas(context.Background(), WorldBounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(areas) != 3 {
+		t.Fatalf("expected 3 areas, got %v", len(areas))
+	}
+	g := NewReverseGeocoder(areas)
+
+	names := func(areas []AdminArea) []string {
+		names := []string{}
+		for _, area := range areas {
+			names = append(names, area.Name)
+		}
+		return names
+	}
+	if found := names(g.Lookup(Coord{2.0, 2.0})); !slices.Equal(found, []string{"Land", "West", "Town"}) {
+		t.Errorf("expected Land, West, Town, got %v", found)
+	}
+	if found := names(g.Lookup(Coord{2.0, 7.0})); !slices.Equal(found, []string{"Land", "West"}) {
+		t.Errorf("expected Land, West, got %v", found)
+	}
+	if found := names(g.Lookup(Coord{7.0, 7.0})); !slices.Equal(found, []string{"Land"}) {
+		t.Errorf("expected Land, got %v", found)
+	}
+	if found := g.Lookup(Coord{20.0, 7.0}); len(found) != 0 {
+		t.Errorf("expected no areas, got %v", names(found))
+	}
+
+	if area, ok := g.LookupLevel(Coord{2.0, 2.0}, 2); !ok || area.Name != "Land" || area.NameIn("en") != "Country" || area.NameIn("fr") != "Land" {
+		t.Errorf("expected country Land, got %v %v", area.Name, ok)
+	}
+	if _, ok := g.LookupLevel(Coord{7.0, 7.0}, 8); ok {
+		t.Errorf("expected no municipality")
+	}
+}
@@ -0,0 +1,691 @@
+package osm
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Profile is a routing profile that decides which highways are accessible, in which direction, and at what speed.
+type Profile struct {
+	Name         string
+	Speeds       map[string]float64 // speed in km/h per highway type, other highways are inaccessible
+	DefaultSpeed float64            // speed in km/h for other highways that are explicitly accessible by an access tag
+	Access       []string           // access keys from generic to specific, the most specific key that is set decides
+	Oneway       bool               // respect oneway tags, roundabouts, and motorways
+	OnewayKey    string             // key that overrides oneway, such as oneway:bicycle
+	MaxSpeed     bool               // use maxspeed tags as the speed, otherwise they only limit the speed
+	Restrictions bool               // apply turn restrictions
+	Vehicle      string             // vehicle type for restriction:<vehicle> and except tags of turn restrictions
+}
+
+// CarProfile is the routing profile for cars.
+var CarProfile = Profile{
+	Name: "car",
+	Speeds: map[string]float64{
+		"motorway":       110.0,
+		"motorway_link":  60.0,
+		"trunk":          90.0,
+		"trunk_link":     50.0,
+		"primary":        70.0,
+		"primary_link":   40.0,
+		"secondary":      60.0,
+		"secondary_link": 40.0,
+		"tertiary":       50.0,
+		"tertiary_link":  30.0,
+		"unclassified":   40.0,
+		"residential":    30.0,
+		"living_street":  10.0,
+		"service":        15.0,
+		"road":           30.0,
+	},
+	DefaultSpeed: 20.0,
+	Access:       []string{"access", "vehicle", "motor_vehicle", "motorcar"},
+	Oneway:       true,
+	MaxSpeed:     true,
+	Restrictions: true,
+	Vehicle:      "motorcar",
+}
+
+// BikeProfile is the routing profile for bicycles.
+var BikeProfile = Profile{
+	Name: "bike",
+	Speeds: map[string]float64{
+		"primary":        18.0,
+		"primary_link":   18.0,
+		"secondary":      18.0,
+		"secondary_link": 18.0,
+		"tertiary":       18.0,
+		"tertiary_link":  18.0,
+		"unclassified":   18.0,
+		"residential":    18.0,
+		"living_street":  12.0,
+		"service":        15.0,
+		"road":           15.0,
+		"cycleway":       20.0,
+		"track":          12.0,
+		"path":           12.0,
+	},
+	DefaultSpeed: 10.0,
+	Access:       []string{"access", "vehicle", "bicycle"},
+	Oneway:       true,
+	OnewayKey:    "oneway:bicycle",
+	Restrictions: true,
+	Vehicle:      "bicycle",
+}
+
+// FootProfile is the routing profile for pedestrians.
+var FootProfile = Profile{
+	Name: "foot",
+	Speeds: map[string]float64{
+		"primary":        5.0,
+		"primary_link":   5.0,
+		"secondary":      5.0,
+		"secondary_link": 5.0,
+		"tertiary":       5.0,
+		"tertiary_link":  5.0,
+		"unclassified":   5.0,
+		"residential":    5.0,
+		"living_street":  5.0,
+		"service":        5.0,
+		"road":           5.0,
+		"pedestrian":     5.0,
+		"footway":        5.0,
+		"path":           5.0,
+		"track":          5.0,
+		"steps":          2.0,
+	},
+	DefaultSpeed: 5.0,
+	Access:       []string{"access", "foot"},
+	Vehicle:      "foot",
+}
+
+// access returns whether the way is accessible, and whether that was explicitly tagged.
+func (p *Profile) access(tags Tags) (bool, bool) {
+	allowed, explicit := true, false
+	for _, key := range p.Access {
+		switch tags.Find(key) {
+		case "no", "private", "agricultural", "forestry", "delivery", "discouraged":
+			allowed, explicit = false, true
+		case "yes", "designated", "permissive", "destination", "customers":
+			allowed, explicit = true, true
+		}
+	}
+	return allowed, explicit
+}
+
+// parseMaxSpeed parses a maxspeed value in km/h or mph.
+func parseMaxSpeed(s string) (float64, bool) {
+	s, _, _ = strings.Cut(s, ";")
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0.0, false
+	}
+	speed, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		num, unit, ok := strings.Cut(fields[0], "mph")
+		if speed, err = strconv.ParseFloat(num, 64); !ok || unit != "" || err != nil {
+			return 0.0, false
+		}
+		return speed * 1.609344, 0.0 < speed
+	} else if 1 < len(fields) && fields[1] == "mph" {
+		speed *= 1.609344
+	}
+	return speed, 0.0 < speed
+}
+
+// Speed returns the speed in km/h on a way with the given tags, or zero if it is inaccessible.
+func (p *Profile) Speed(tags Tags) float64 {
+	highway := tags.Find("highway")
+	if highway == "" || tags.Find("area") == "yes" {
+		return 0.0
+	}
+	allowed, explicit := p.access(tags)
+	if !allowed {
+		return 0.0
+	}
+	speed, ok := p.Speeds[highway]
+	if !ok {
+		if !explicit {
+			return 0.0
+		}
+		speed = p.DefaultSpeed
+	}
+	if maxSpeed, ok := parseMaxSpeed(tags.Find("maxspeed")); ok {
+		if p.MaxSpeed {
+			speed = maxSpeed
+		} else {
+			speed = min(speed, maxSpeed)
+		}
+	}
+	return speed
+}
+
+// Direction returns whether a way with the given tags can be traversed forward and backward.
+func (p *Profile) Direction(tags Tags) (bool, bool) {
+	if !p.Oneway {
+		return true, true
+	}
+	oneway := tags.Find("oneway")
+	if p.OnewayKey != "" {
+		if s := tags.Find(p.OnewayKey); s != "" {
+			oneway = s
+		}
+	}
+	switch oneway {
+	case "yes", "true", "1":
+		return true, false
+	case "-1", "reverse":
+		return false, true
+	case "no", "false", "0":
+		return true, true
+	}
+	if tags.Find("junction") == "roundabout" || tags.Find("highway") == "motorway" {
+		return true, false
+	}
+	return true, true
+}
+
+// turnRestriction is a restriction relation from a way, over a via node or via ways, to a way. It either forbids the turn (no_*) or forbids all other turns (only_*).
+type turnRestriction struct {
+	from, to uint64
+	viaNode  uint64
+	viaWays  []uint64
+	only     bool
+}
+
+// turnRestriction returns the turn restriction of the relation if it applies to the profile.
+func (p *Profile) turnRestriction(relation Relation) (turnRestriction, bool) {
+	tags := relation.Tags
+	if tags.Find("type") != "restriction" {
+		return turnRestriction{}, false
+	}
+	value := tags.Find("restriction:" + p.Vehicle)
+	if value == "" {
+		value = tags.Find("restriction")
+	}
+	if slices.Contains(strings.Split(tags.Find("except"), ";"), p.Vehicle) {
+		return turnRestriction{}, false
+	}
+
+	r := turnRestriction{}
+	if strings.HasPrefix(value, "only_") {
+		r.only = true
+	} else if !strings.HasPrefix(value, "no_") {
+		return turnRestriction{}, false
+	}
+	froms, tos := 0, 0
+	for _, member := range relation.Members {
+		switch {
+		case member.Role == "from" && member.Type == WayType:
+			r.from = member.ID
+			froms++
+		case member.Role == "to" && member.Type == WayType:
+			r.to = member.ID
+			tos++
+		case member.Role == "via" && member.Type == NodeType:
+			r.viaNode = member.ID
+		case member.Role == "via" && member.Type == WayType:
+			r.viaWays = append(r.viaWays, member.ID)
+		}
+	}
+	if froms != 1 || tos != 1 || (r.viaNode == 0) == (len(r.viaWays) == 0) {
+		return turnRestriction{}, false
+	}
+	return r, true
+}
+
+// Graph is a directed routing graph in compressed sparse row (CSR) format. Vertices are the nodes where ways end or meet, and edges are the parts of ways between them. Turn restrictions are applied by duplicating vertices: an edge that is the start of a restriction leads to a copy of its target vertex that has only the allowed outgoing edges. Copies share the node ID and coordinate of the original vertex.
+type Graph struct {
+	Coords  []Coord  // coordinate per vertex
+	NodeIDs []uint64 // node ID per vertex
+
+	Offsets      []uint32  // outgoing edges of vertex v are Offsets[v] up to Offsets[v+1]
+	Targets      []uint32  // target vertex per edge
+	Lengths      []float32 // length in meters per edge
+	Times        []float32 // travel time in seconds per edge
+	WayIDs       []uint64  // way ID per edge
+	ShapeOffsets []uint32  // intermediate coordinates of edge e are Shapes[ShapeOffsets[e]:ShapeOffsets[e+1]]
+	Shapes       []Coord
+
+	edges *edgeIndex // nil for graphs that are not built by ExtractGraph or LoadContractionHierarchy
+}
+
+// edgeIndex is the R-tree over the edges of a graph, which is built on first use. It is held by pointer so that the graph can be copied.
+type edgeIndex struct {
+	once sync.Once
+	tree *RTree
+}
+
+// NumVertices returns the number of vertices.
+func (g *Graph) NumVertices() int {
+	return len(g.Coords)
+}
+
+// NumEdges returns the number of edges.
+func (g *Graph) NumEdges() int {
+	return len(g.Targets)
+}
+
+// Edges returns the range of the outgoing edges of vertex v.
+func (g *Graph) Edges(v int) (int, int) {
+	return int(g.Offsets[v]), int(g.Offsets[v+1])
+}
+
+// Source returns the source vertex of edge e.
+func (g *Graph) Source(e int) int {
+	return sort.Search(len(g.Coords), func(v int) bool {
+		return e < int(g.Offsets[v+1])
+	})
+}
+
+// EdgeCoords returns the coordinates of edge e including its source and target.
+func (g *Graph) EdgeCoords(e int) []Coord {
+	shape := g.Shapes[g.ShapeOffsets[e]:g.ShapeOffsets[e+1]]
+	coords := make([]Coord, 0, len(shape)+2)
+	coords = append(coords, g.Coords[g.Source(e)])
+	coords = append(coords, shape...)
+	return append(coords, g.Coords[g.Targets[e]])
+}
+
+// Snap is a position on the routing graph. A position on a two-way road lies on the edges in both directions.
+type Snap struct {
+	Coord     Coord     // position on the edges
+	Distance  float64   // distance in meters to the snapped coordinate
+	Edges     []int     // edges of the graph that run over the position
+	Fractions []float64 // position along each edge as a fraction of its length
+}
+
+// edgeTree returns the R-tree over the edges, which is built on first use. Graphs that were constructed otherwise build it on every call.
+func (g *Graph) edgeTree() *RTree {
+	build := func() *RTree {
+		bounds := make([]Bounds, g.NumEdges())
+		for e := range bounds {
+			bounds[e] = ringBounds(g.EdgeCoords(e))
+		}
+		return NewRTreeFromBounds(bounds, DefaultRTreeNodeSize)
+	}
+	if g.edges == nil {
+		return build()
+	}
+	g.edges.once.Do(func() {
+		g.edges.tree = build()
+	})
+	return g.edges.tree
+}
+
+// Snap returns the position on the nearest edge to c. For graphs returned by ExtractGraph or LoadContractionHierarchy the R-tree over the edges is built on first use and kept, so that the graph must not be modified afterwards. It returns false if the graph has no edges.
+func (g *Graph) Snap(c Coord) (Snap, bool) {
+	tree := g.edgeTree()
+	nearest := tree.Nearest(c, 1, func(e int) float64 {
+		return lineDistance(g.EdgeCoords(e), c)
+	})
+	if len(nearest) == 0 {
+		return Snap{}, false
+	}
+	e := nearest[0]
+	coords := g.EdgeCoords(e)
+	p, along, length := projectLine(coords, c)
+	t := 0.0
+	if length != 0.0 {
+		t = along / length
+	}
+
+	snap := Snap{Coord: p, Distance: c.Distance(p)}
+	reversed := reverseOrientation(coords)
+	tree.Search(ringBounds(coords), func(f int) bool {
+		if g.WayIDs[f] != g.WayIDs[e] || g.Lengths[f] != g.Lengths[e] {
+			return true
+		}
+		if coords2 := g.EdgeCoords(f); slices.Equal(coords2, coords) {
+			snap.Edges = append(snap.Edges, f)
+			snap.Fractions = append(snap.Fractions, t)
+		} else if slices.Equal(coords2, reversed) {
+			snap.Edges = append(snap.Edges, f)
+			snap.Fractions = append(snap.Fractions, 1.0-t)
+		}
+		return true
+	})
+	return snap, true
+}
+
+type graphEdge struct {
+	from, to uint32
+	length   float64
+	time     float64
+	way      uint64
+	shape    []Coord
+}
+
+// graphSegment is the part of a way between two vertices with its forward and backward edges, or -1 if not traversable.
+type graphSegment struct {
+	a, b              uint32
+	forward, backward int
+}
+
+// restrictionNode is a node in the trie of edge sequences that start a turn restriction. Its vertex is the copy of the target of its edge when arriving over the sequence.
+type restrictionNode struct {
+	edge      int
+	vertex    uint32
+	children  map[int]*restrictionNode
+	forbidden []int
+	only      []int // nil if any turn is allowed
+}
+
+func (n *restrictionNode) child(edge int) *restrictionNode {
+	if n.children == nil {
+		n.children = map[int]*restrictionNode{}
+	}
+	c, ok := n.children[edge]
+	if !ok {
+		c = &restrictionNode{edge: edge}
+		n.children[edge] = c
+	}
+	return c
+}
+
+func (n *restrictionNode) allows(edge int) bool {
+	return !slices.Contains(n.forbidden, edge) && (n.only == nil || slices.Contains(n.only, edge))
+}
+
+// ExtractGraph builds a routing graph for the profile from all accessible highway ways. Ways are split at the nodes shared with other ways, oneway tags and roundabouts set the direction of the edges, and the speed depends on the highway type and maxspeed tags. Turn restrictions over a via node or via ways are applied if the profile requires so. Edge lengths are geodesic. This function requires parsing the file three times.
+func (z *Parser) ExtractGraph(ctx context.Context, profile Profile) (*Graph, error) {
+	var mu sync.Mutex
+	var restrictions []turnRestriction
+	if profile.Restrictions {
+		relationFunc := func(relation Relation) {
+			if r, ok := profile.turnRestriction(relation); ok {
+				mu.Lock()
+				restrictions = append(restrictions, r)
+				mu.Unlock()
+			}
+		}
+		if err := z.Parse(ctx, nil, nil, relationFunc); err != nil {
+			return nil, err
+		}
+	}
+
+	type graphWay struct {
+		Way
+		forward, backward bool
+		speed             float64
+	}
+	var ways []graphWay
+	uses := NewUint64Map(8, 0.6) // number of times a node is used, where way endpoints count double
+	wayFunc := func(way Way) {
+		speed := profile.Speed(way.Tags)
+		if speed <= 0.0 || len(way.Refs) < 2 {
+			return
+		}
+		forward, backward := profile.Direction(way.Tags)
+		way.Own()
+		way.Tags = nil
+		mu.Lock()
+		ways = append(ways, graphWay{way, forward, backward, speed})
+		for i, ref := range way.Refs {
+			n, _ := uses.Get(ref)
+			if i == 0 || i == len(way.Refs)-1 {
+				n++
+			}
+			uses.Put(ref, n+1)
+		}
+		mu.Unlock()
+	}
+	if err := z.Parse(ctx, nil, wayFunc, nil); err != nil {
+		return nil, err
+	}
+	for _, r := range restrictions {
+		if r.viaNode != 0 {
+			if n, ok := uses.Get(r.viaNode); ok {
+				uses.Put(r.viaNode, n+2)
+			}
+		}
+	}
+
+	coords := map[uint64]Coord{}
+	nodeFunc := func(node Node) {
+		mu.Lock()
+		if uses.Has(node.ID) {
+			coords[node.ID] = Coord{node.Lon, node.Lat}
+		}
+		mu.Unlock()
+	}
+	if err := z.Parse(ctx, nodeFunc, nil, nil); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(ways, func(a, b graphWay) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	// split ways at vertices into edges
+	g := &Graph{edges: &edgeIndex{}}
+	vertices := map[uint64]uint32{}
+	vertex := func(id uint64) uint32 {
+		v, ok := vertices[id]
+		if !ok {
+			v = uint32(len(g.Coords))
+			vertices[id] = v
+			g.Coords = append(g.Coords, coords[id])
+			g.NodeIDs = append(g.NodeIDs, id)
+		}
+		return v
+	}
+	var edges []graphEdge
+	waySegments := map[uint64][]graphSegment{}
+	for _, way := range ways {
+		var segments []graphSegment
+		var shape []Coord
+		var prev Coord
+		start, length := -1, 0.0
+		for _, ref := range way.Refs {
+			coord, ok := coords[ref]
+			if !ok {
+				continue // missing node
+			}
+			if start != -1 {
+				length += prev.Distance(coord)
+			}
+			prev = coord
+			if n, _ := uses.Get(ref); n < 2 {
+				if start != -1 {
+					shape = append(shape, coord)
+				}
+				continue
+			}
+
+			v := vertex(ref)
+			if start != -1 && (start != int(v) || 0.0 < length) {
+				time := length / (way.speed / 3.6)
+				segment := graphSegment{uint32(start), v, -1, -1}
+				if way.forward {
+					segment.forward = len(edges)
+					edges = append(edges, graphEdge{uint32(start), v, length, time, way.ID, shape})
+				}
+				if way.backward {
+					segment.backward = len(edges)
+					edges = append(edges, graphEdge{v, uint32(start), length, time, way.ID, reverseOrientation(shape)})
+				}
+				segments = append(segments, segment)
+			}
+			start, length, shape = int(v), 0.0, nil
+		}
+		if 0 < len(segments) {
+			waySegments[way.ID] = segments
+		}
+	}
+
+	// resolve turn restrictions into sequences of edges, where the last edge is forbidden or the only one allowed
+	out := make([][]int, len(g.Coords))
+	for e, edge := range edges {
+		out[edge.from] = append(out[edge.from], e)
+	}
+	roots := map[int]*restrictionNode{}
+	for _, r := range restrictions {
+		for _, path := range restrictionPaths(r, edges, waySegments, vertices) {
+			n, ok := roots[path[0]]
+			if !ok {
+				n = &restrictionNode{edge: path[0]}
+				roots[path[0]] = n
+			}
+			for _, e := range path[1 : len(path)-1] {
+				n = n.child(e)
+			}
+			if to := path[len(path)-1]; r.only {
+				n.only = append(n.only, to)
+			} else {
+				n.forbidden = append(n.forbidden, to)
+			}
+		}
+	}
+	applyTurnRestrictions(g, &edges, out, roots)
+
+	// build compressed sparse row format
+	order := make([]int, len(edges))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(edges[a].from, edges[b].from)
+	})
+	g.Offsets = make([]uint32, len(g.Coords)+1)
+	g.Targets = make([]uint32, 0, len(edges))
+	g.Lengths = make([]float32, 0, len(edges))
+	g.Times = make([]float32, 0, len(edges))
+	g.WayIDs = make([]uint64, 0, len(edges))
+	g.ShapeOffsets = make([]uint32, 1, len(edges)+1)
+	for _, e := range order {
+		edge := edges[e]
+		g.Offsets[edge.from+1]++
+		g.Targets = append(g.Targets, edge.to)
+		g.Lengths = append(g.Lengths, float32(edge.length))
+		g.Times = append(g.Times, float32(edge.time))
+		g.WayIDs = append(g.WayIDs, edge.way)
+		g.Shapes = append(g.Shapes, edge.shape...)
+		g.ShapeOffsets = append(g.ShapeOffsets, uint32(len(g.Shapes)))
+	}
+	for v := 1; v < len(g.Offsets); v++ {
+		g.Offsets[v] += g.Offsets[v-1]
+	}
+	return g, nil
+}
+
+// restrictionPaths returns the sequences of edges of a turn restriction, where the last edge is the turn onto the to way. A restriction may result in multiple sequences if the via node is in the middle of the from or to way.
+func restrictionPaths(r turnRestriction, edges []graphEdge, waySegments map[uint64][]graphSegment, vertices map[uint64]uint32) [][]int {
+	// edges of the from way that arrive at a vertex
+	var froms []int
+	for _, segment := range waySegments[r.from] {
+		if 0 <= segment.forward {
+			froms = append(froms, segment.forward)
+		}
+		if 0 <= segment.backward {
+			froms = append(froms, segment.backward)
+		}
+	}
+
+	var paths [][]int
+	for _, from := range froms {
+		path := []int{from}
+		cur := edges[from].to
+		if r.viaNode != 0 {
+			if v, ok := vertices[r.viaNode]; !ok || v != cur {
+				continue
+			}
+		} else {
+			ok := true
+			for _, id := range r.viaWays {
+				segments := waySegments[id]
+				if len(segments) == 0 {
+					ok = false
+					break
+				} else if segments[0].a == cur && segments[0].a != segments[len(segments)-1].b {
+					for _, segment := range segments {
+						path = append(path, segment.forward)
+					}
+					cur = segments[len(segments)-1].b
+				} else if segments[len(segments)-1].b == cur {
+					for i := len(segments) - 1; 0 <= i; i-- {
+						path = append(path, segments[i].backward)
+					}
+					cur = segments[0].a
+				} else {
+					ok = false
+					break
+				}
+			}
+			if !ok || slices.Contains(path, -1) || edges[from].way == r.viaWays[0] {
+				continue
+			}
+		}
+		for _, segment := range waySegments[r.to] {
+			if segment.a == cur && 0 <= segment.forward {
+				paths = append(paths, append(slices.Clone(path), segment.forward))
+			}
+			if segment.b == cur && 0 <= segment.backward {
+				paths = append(paths, append(slices.Clone(path), segment.backward))
+			}
+		}
+	}
+	return paths
+}
+
+// applyTurnRestrictions duplicates the target vertex of each edge sequence in the trie of restrictions. The first edge of a sequence is redirected to its copy, and each copy receives copies of the outgoing edges of the original vertex that are allowed, leading to the copy of the next vertex in the sequence if any. A sequence that ends with an edge that starts another restriction also respects that restriction.
+func applyTurnRestrictions(g *Graph, edges *[]graphEdge, out [][]int, roots map[int]*restrictionNode) {
+	if len(roots) == 0 {
+		return
+	}
+
+	var nodes []*restrictionNode
+	var walk func(*restrictionNode, bool)
+	walk = func(n *restrictionNode, root bool) {
+		v := (*edges)[n.edge].to
+		n.vertex = uint32(len(g.Coords))
+		g.Coords = append(g.Coords, g.Coords[v])
+		g.NodeIDs = append(g.NodeIDs, g.NodeIDs[v])
+		if !root {
+			if r, ok := roots[n.edge]; ok {
+				n.forbidden = append(n.forbidden, r.forbidden...)
+				if n.only == nil {
+					n.only = r.only
+				} else if r.only != nil {
+					n.only = slices.DeleteFunc(n.only, func(e int) bool {
+						return !slices.Contains(r.only, e)
+					})
+				}
+			}
+		}
+		nodes = append(nodes, n)
+		for _, e := range slices.Sorted(maps.Keys(n.children)) {
+			walk(n.children[e], false)
+		}
+	}
+	for _, e := range slices.Sorted(maps.Keys(roots)) {
+		walk(roots[e], true)
+	}
+
+	// the original vertex of each copy must be known before redirecting
+	origins := make([]uint32, len(nodes))
+	for i, n := range nodes {
+		origins[i] = (*edges)[n.edge].to
+	}
+	for _, e := range slices.Sorted(maps.Keys(roots)) {
+		(*edges)[e].to = roots[e].vertex
+	}
+	for i, n := range nodes {
+		for _, e := range out[origins[i]] {
+			if !n.allows(e) {
+				continue
+			}
+			edge := (*edges)[e]
+			edge.from = n.vertex
+			if c, ok := n.children[e]; ok {
+				edge.to = c.vertex
+			}
+			*edges = append(*edges, edge)
+		}
+	}
+}
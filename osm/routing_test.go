@@ -0,0 +1,256 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseMaxSpeed(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected float64
+		ok       bool
+	}{
+		{"50", 50.0, true},
+		{"30 mph", 48.28032, true},
+		{"30mph", 48.28032, true},
+		{"80;60", 80.0, true},
+		{"none", 0.0, false},
+		{"signals", 0.0, false},
+		{"", 0.0, false},
+	}
+	for _, tt := range tests {
+		if speed, ok := parseMaxSpeed(tt.s); ok != tt.ok || 1e-9 < math.Abs(speed-tt.expected) {
+			t.Errorf("%q: expected %v %v, got %v %v", tt.s, tt.expected, tt.ok, speed, ok)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	speeds := []struct {
+		profile  Profile
+		tags     Tags
+		expected float64
+	}{
+		{CarProfile, Tags{{"highway", "residential"}}, 30.0},
+		{CarProfile, Tags{{"highway", "primary"}, {"maxspeed", "50"}}, 50.0},
+		{CarProfile, Tags{{"highway", "residential"}, {"access", "private"}}, 0.0},
+		{CarProfile, Tags{{"highway", "residential"}, {"access", "no"}, {"motor_vehicle", "yes"}}, 30.0},
+		{CarProfile, Tags{{"highway", "footway"}}, 0.0},
+		{CarProfile, Tags{{"highway", "pedestrian"}, {"area", "yes"}}, 0.0},
+		{BikeProfile, Tags{{"highway", "motorway"}}, 0.0},
+		{BikeProfile, Tags{{"highway", "footway"}, {"bicycle", "yes"}}, 10.0},
+		{BikeProfile, Tags{{"highway", "living_street"}, {"maxspeed", "10"}}, 10.0},
+		{FootProfile, Tags{{"highway", "steps"}}, 2.0},
+		{FootProfile, Tags{{"highway", "residential"}, {"foot", "no"}}, 0.0},
+	}
+	for _, tt := range speeds {
+		if speed := tt.profile.Speed(tt.tags); speed != tt.expected {
+			t.Errorf("%s %v: expected speed %v, got %v", tt.profile.Name, tt.tags, tt.expected, speed)
+		}
+	}
+
+	directions := []struct {
+		profile           Profile
+		tags              Tags
+		forward, backward bool
+	}{
+		{CarProfile, Tags{{"highway", "residential"}}, true, true},
+		{CarProfile, Tags{{"highway", "residential"}, {"oneway", "yes"}}, true, false},
+		{CarProfile, Tags{{"highway", "residential"}, {"oneway", "-1"}}, false, true},
+		{CarProfile, Tags{{"highway", "primary"}, {"junction", "roundabout"}}, true, false},
+		{CarProfile, Tags{{"highway", "motorway"}}, true, false},
+		{CarProfile, Tags{{"highway", "motorway"}, {"oneway", "no"}}, true, true},
+		{BikeProfile, Tags{{"highway", "residential"}, {"oneway", "yes"}, {"oneway:bicycle", "no"}}, true, true},
+		{FootProfile, Tags{{"highway", "residential"}, {"oneway", "yes"}}, true, true},
+	}
+	for _, tt := range directions {
+		if forward, backward := tt.profile.Direction(tt.tags); forward != tt.forward || backward != tt.backward {
+			t.Errorf("%s %v: expected %v %v, got %v %v", tt.profile.Name, tt.tags, tt.forward, tt.backward, forward, backward)
+		}
+	}
+}
+
+func testGraph(t *testing.T, profile Profile) *Graph {
+	nodes := []Node{
+		{ID: 1, Lon: 0.0, Lat: 0.0},
+		{ID: 2, Lon: 0.01, Lat: 0.0},
+		{ID: 3, Lon: 0.02, Lat: 0.0},
+		{ID: 4, Lon: 0.01, Lat: 0.01},
+		{ID: 5, Lon: 0.01, Lat: -0.01},
+		{ID: 6, Lon: 0.005, Lat: 0.0},
+		{ID: 7, Lon: 0.03, Lat: 0.0},
+		{ID: 8, Lon: 0.04, Lat: 0.0},
+		{ID: 10, Lon: 1.0, Lat: 0.0},
+		{ID: 11, Lon: 1.01, Lat: 0.0},
+		{ID: 12, Lon: 1.02, Lat: 0.0},
+		{ID: 13, Lon: 1.03, Lat: 0.0},
+		{ID: 14, Lon: 1.02, Lat: 0.01},
+		{ID: 15, Lon: 1.01, Lat: 0.01},
+	}
+	residential := Tags{{"highway", "residential"}}
+	ways := []Way{
+		{ID: 1, Refs: []uint64{1, 6, 2}, Tags: residential},
+		{ID: 2, Refs: []uint64{2, 3}, Tags: residential},
+		{ID: 3, Refs: []uint64{4, 2}, Tags: Tags{{"highway", "residential"}, {"oneway", "yes"}}},
+		{ID: 4, Refs: []uint64{2, 5}, Tags: Tags{{"highway", "primary"}, {"maxspeed", "50"}}},
+		{ID: 5, Refs: []uint64{3, 7}, Tags: Tags{{"highway", "footway"}, {"bicycle", "yes"}}},
+		{ID: 6, Refs: []uint64{7, 8}, Tags: Tags{{"highway", "motorway"}}},
+		{ID: 10, Refs: []uint64{10, 11}, Tags: residential},
+		{ID: 11, Refs: []uint64{11, 12}, Tags: residential},
+		{ID: 12, Refs: []uint64{12, 13}, Tags: residential},
+		{ID: 13, Refs: []uint64{12, 14}, Tags: residential},
+		{ID: 14, Refs: []uint64{15, 11}, Tags: residential},
+	}
+	relations := []Relation{
+		{ID: 1, Members: []Member{{WayType, 3, "from"}, {NodeType, 2, "via"}, {WayType, 4, "to"}}, Tags: Tags{{"type", "restriction"}, {"restriction", "no_left_turn"}}},
+		{ID: 2, Members: []Member{{WayType, 1, "from"}, {NodeType, 2, "via"}, {WayType, 2, "to"}}, Tags: Tags{{"type", "restriction"}, {"restriction", "only_straight_on"}, {"except", "bicycle"}}},
+		{ID: 3, Members: []Member{{WayType, 10, "from"}, {WayType, 11, "via"}, {WayType, 12, "to"}}, Tags: Tags{{"type", "restriction"}, {"restriction", "no_straight_on"}}},
+	}
+	b := writeTestPBF(t, nodes, ways, relations)
+
+	z := NewParser(bytes.NewReader(b))
+	g, err := z.ExtractGraph(context.Background(), profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// graphStep returns the vertex reached from v over an edge to the given node, or -1.
+func graphStep(g *Graph, v int, id uint64) int {
+	if v < 0 {
+		return -1
+	}
+	start, end := g.Edges(v)
+	for e := start; e < end; e++ {
+		if g.NodeIDs[g.Targets[e]] == id {
+			return int(g.Targets[e])
+		}
+	}
+	return -1
+}
+
+// graphPath returns whether the nodes can be traversed in order, starting at the original vertex of the first node.
+func graphPath(g *Graph, ids ...uint64) bool {
+	v := -1
+	for i, id := range g.NodeIDs {
+		if id == ids[0] {
+			v = i
+			break
+		}
+	}
+	for _, id := range ids[1:] {
+		v = graphStep(g, v, id)
+	}
+	return v != -1
+}
+
+func TestExtractGraph(t *testing.T) {
+	g := testGraph(t, CarProfile)
+	if g.NumVertices() != 17 || g.NumEdges() != 26 {
+		t.Errorf("expected 17 vertices and 26 edges, got %v and %v", g.NumVertices(), g.NumEdges())
+	}
+	paths := []struct {
+		ids      []uint64
+		expected bool
+	}{
+		{[]uint64{4, 2}, true},
+		{[]uint64{2, 4}, false},           // oneway
+		{[]uint64{4, 2, 5}, false},        // no_left_turn
+		{[]uint64{4, 2, 3, 2, 5}, true},   // detour
+		{[]uint64{1, 2, 3}, true},         // only_straight_on
+		{[]uint64{1, 2, 5}, false},        // only_straight_on
+		{[]uint64{3, 2, 5}, true},         // unrestricted
+		{[]uint64{3, 7}, false},           // footway
+		{[]uint64{7, 8}, true},            // motorway
+		{[]uint64{8, 7}, false},           // motorway is oneway
+		{[]uint64{10, 11, 12, 13}, false}, // no_straight_on via way
+		{[]uint64{10, 11, 12, 14}, true},
+		{[]uint64{15, 11, 12, 13}, true},
+	}
+	for _, tt := range paths {
+		if ok := graphPath(g, tt.ids...); ok != tt.expected {
+			t.Errorf("car path %v: expected %v, got %v", tt.ids, tt.expected, ok)
+		}
+	}
+
+	for e := 0; e < g.NumEdges(); e++ {
+		from, to := g.NodeIDs[g.Source(e)], g.NodeIDs[g.Targets[e]]
+		if from == 1 && to == 2 {
+			if coords := g.EdgeCoords(e); len(coords) != 3 || coords[1] != (Coord{0.005, 0.0}) {
+				t.Errorf("wrong edge coordinates %v", coords)
+			}
+			if length := (Coord{0.0, 0.0}).Distance(Coord{0.01, 0.0}); 1e-3 < math.Abs(float64(g.Lengths[e])-length) {
+				t.Errorf("expected length %v, got %v", length, g.Lengths[e])
+			}
+		} else if from == 2 && to == 5 {
+			if time := g.Lengths[e] / (50.0 / 3.6); 1e-3 < math.Abs(float64(g.Times[e]-time)) {
+				t.Errorf("expected time %v, got %v", time, g.Times[e])
+			}
+		}
+	}
+
+	g = testGraph(t, BikeProfile)
+	paths = []struct {
+		ids      []uint64
+		expected bool
+	}{
+		{[]uint64{1, 2, 5}, true}, // except bicycle
+		{[]uint64{4, 2, 5}, false},
+		{[]uint64{3, 7}, true},
+		{[]uint64{7, 8}, false},
+	}
+	for _, tt := range paths {
+		if ok := graphPath(g, tt.ids...); ok != tt.expected {
+			t.Errorf("bike path %v: expected %v, got %v", tt.ids, tt.expected, ok)
+		}
+	}
+
+	g = testGraph(t, FootProfile)
+	paths = []struct {
+		ids      []uint64
+		expected bool
+	}{
+		{[]uint64{2, 4}, true},
+		{[]uint64{4, 2, 5}, true},
+		{[]uint64{2, 3, 7}, true},
+		{[]uint64{7, 8}, false},
+	}
+	for _, tt := range paths {
+		if ok := graphPath(g, tt.ids...); ok != tt.expected {
+			t.Errorf("foot path %v: expected %v, got %v", tt.ids, tt.expected, ok)
+		}
+	}
+}
+
+func TestGraphSnap(t *testing.T) {
+	g := testGraph(t, CarProfile)
+	c := Coord{0.015, 0.0001}
+	snap, ok := g.Snap(c)
+	if !ok || snap.Coord != (Coord{0.015, 0.0}) || len(snap.Edges) == 0 {
+		t.Fatalf("wrong snap %v", snap)
+	}
+
+	// copies and graphs constructed from their fields snap equally
+	copied := *g
+	constructed := &Graph{
+		Coords:       g.Coords,
+		NodeIDs:      g.NodeIDs,
+		Offsets:      g.Offsets,
+		Targets:      g.Targets,
+		Lengths:      g.Lengths,
+		Times:        g.Times,
+		WayIDs:       g.WayIDs,
+		ShapeOffsets: g.ShapeOffsets,
+		Shapes:       g.Shapes,
+	}
+	for _, g := range []*Graph{&copied, constructed} {
+		if snap2, _ := g.Snap(c); !reflect.DeepEqual(snap2, snap) {
+			t.Errorf("expected snap %v, got %v", snap, snap2)
+		}
+	}
+}
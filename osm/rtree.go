@@ -0,0 +1,383 @@
+package osm
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// DefaultRTreeNodeSize is the default maximum number of children per R-tree node.
+const DefaultRTreeNodeSize = 16
+
+const (
+	rtreeMagic      = "RTRE"
+	rtreeVersion    = 1
+	rtreeHeaderSize = 16
+	rtreeBoxSize    = 32
+)
+
+// RTree is an immutable R-tree over bounding boxes, packed using the Sort-Tile-Recursive algorithm. It is stored in a single flat little-endian buffer, see Bytes and LoadRTree, so that it can be written to disk and memory mapped instead of being rebuilt. The buffer holds a header, the bounds of all items and nodes level by level starting at the leaves, and for each an item index (leaves) or the position of its first child (nodes).
+type RTree struct {
+	data     []byte
+	n        int   // number of items
+	nodeSize int   // maximum number of children per node
+	levels   []int // end position of each level, leaves first
+}
+
+type rtreeEntry struct {
+	Bounds
+	index uint32
+}
+
+// NewRTree returns an R-tree over the bounding boxes of the geometries, where item i refers to geoms[i].
+func NewRTree(geoms []Geometry) *RTree {
+	bounds := make([]Bounds, len(geoms))
+	for i, geom := range geoms {
+		bounds[i] = geom.Bounds()
+	}
+	return NewRTreeFromBounds(bounds, DefaultRTreeNodeSize)
+}
+
+// NewRTreeFromBounds returns an R-tree over the bounding boxes with at most nodeSize children per node, where item i refers to bounds[i]. Wrapped bounds are indexed as spanning all longitudes.
+func NewRTreeFromBounds(bounds []Bounds, nodeSize int) *RTree {
+	if nodeSize < 2 {
+		nodeSize = DefaultRTreeNodeSize
+	}
+	nodeSize = min(nodeSize, math.MaxUint16)
+
+	entries := make([]rtreeEntry, len(bounds))
+	for i, b := range bounds {
+		if b.IsWrapped() {
+			b[0].X, b[1].X = -180.0, 180.0
+		}
+		entries[i] = rtreeEntry{b, uint32(i)}
+	}
+
+	// pack each level with STR and group consecutive entries into parent nodes, the position of the first child is stored in the parent so that entries of one level may be reordered freely
+	var levels [][]rtreeEntry
+	n := 0
+	for {
+		strSort(entries, nodeSize)
+		levels = append(levels, entries)
+		start := n
+		n += len(entries)
+		if len(entries) <= 1 && 1 < len(levels) {
+			break
+		} else if len(entries) == 0 {
+			break
+		}
+
+		parents := make([]rtreeEntry, 0, (len(entries)+nodeSize-1)/nodeSize)
+		for i := 0; i < len(entries); i += nodeSize {
+			b := entries[i].Bounds
+			for _, entry := range entries[i+1 : min(i+nodeSize, len(entries))] {
+				b = b.union(entry.Bounds)
+			}
+			parents = append(parents, rtreeEntry{b, uint32(start + i)})
+		}
+		entries = parents
+	}
+
+	data := make([]byte, rtreeHeaderSize+n*(rtreeBoxSize+4))
+	copy(data, rtreeMagic)
+	binary.LittleEndian.PutUint16(data[4:], rtreeVersion)
+	binary.LittleEndian.PutUint16(data[6:], uint16(nodeSize))
+	binary.LittleEndian.PutUint32(data[8:], uint32(len(bounds)))
+	binary.LittleEndian.PutUint32(data[12:], uint32(n))
+	pos := 0
+	for _, level := range levels {
+		for _, entry := range level {
+			box := data[rtreeHeaderSize+pos*rtreeBoxSize:]
+			binary.LittleEndian.PutUint64(box[0:], math.Float64bits(entry.Bounds[0].X))
+			binary.LittleEndian.PutUint64(box[8:], math.Float64bits(entry.Bounds[0].Y))
+			binary.LittleEndian.PutUint64(box[16:], math.Float64bits(entry.Bounds[1].X))
+			binary.LittleEndian.PutUint64(box[24:], math.Float64bits(entry.Bounds[1].Y))
+			binary.LittleEndian.PutUint32(data[rtreeHeaderSize+n*rtreeBoxSize+pos*4:], entry.index)
+			pos++
+		}
+	}
+	return &RTree{
+		data:     data,
+		n:        len(bounds),
+		nodeSize: nodeSize,
+		levels:   rtreeLevels(len(bounds), nodeSize),
+	}
+}
+
+// strSort orders the entries by the Sort-Tile-Recursive algorithm: sorted by centre into vertical slices of whole nodes, and by centre within each slice.
+func strSort(entries []rtreeEntry, nodeSize int) {
+	if len(entries) <= nodeSize {
+		return
+	}
+	centre := func(b Bounds) Coord {
+		return Coord{(b[0].X + b[1].X) / 2.0, (b[0].Y + b[1].Y) / 2.0}
+	}
+	slices.SortFunc(entries, func(a, b rtreeEntry) int {
+		return compareFloat(centre(a.Bounds).X, centre(b.Bounds).X)
+	})
+	nodes := (len(entries) + nodeSize - 1) / nodeSize
+	sliceSize := int(math.Ceil(math.Sqrt(float64(nodes)))) * nodeSize
+	for i := 0; i < len(entries); i += sliceSize {
+		slices.SortFunc(entries[i:min(i+sliceSize, len(entries))], func(a, b rtreeEntry) int {
+			return compareFloat(centre(a.Bounds).Y, centre(b.Bounds).Y)
+		})
+	}
+}
+
+// compareFloat compares floats such that NaNs, from empty bounds, sort last.
+func compareFloat(a, b float64) int {
+	if a < b || !math.IsNaN(a) && math.IsNaN(b) {
+		return -1
+	} else if b < a || math.IsNaN(a) && !math.IsNaN(b) {
+		return 1
+	}
+	return 0
+}
+
+// rtreeLevels returns the end position of each level for n items.
+func rtreeLevels(n, nodeSize int) []int {
+	levels := []int{n}
+	for size := n; 1 < size || len(levels) == 1 && 0 < size; {
+		size = (size + nodeSize - 1) / nodeSize
+		levels = append(levels, levels[len(levels)-1]+size)
+	}
+	return levels
+}
+
+// LoadRTree returns the R-tree stored in data as returned by Bytes. The data is used directly and must not be modified, which allows it to be memory mapped.
+func LoadRTree(data []byte) (*RTree, error) {
+	if len(data) < rtreeHeaderSize || string(data[:4]) != rtreeMagic {
+		return nil, fmt.Errorf("invalid R-tree header")
+	} else if version := binary.LittleEndian.Uint16(data[4:]); version != rtreeVersion {
+		return nil, fmt.Errorf("unsupported R-tree version %v", version)
+	}
+	nodeSize := int(binary.LittleEndian.Uint16(data[6:]))
+	n := int(binary.LittleEndian.Uint32(data[8:]))
+	if nodeSize < 2 {
+		return nil, fmt.Errorf("invalid R-tree node size %v", nodeSize)
+	}
+	levels := rtreeLevels(n, nodeSize)
+	if size := levels[len(levels)-1]; int(binary.LittleEndian.Uint32(data[12:])) != size {
+		return nil, fmt.Errorf("invalid number of R-tree nodes")
+	} else if len(data) != rtreeHeaderSize+size*(rtreeBoxSize+4) {
+		return nil, fmt.Errorf("invalid R-tree length")
+	}
+	t := &RTree{
+		data:     data,
+		n:        n,
+		nodeSize: nodeSize,
+		levels:   levels,
+	}
+
+	// leaves refer to items and nodes to their first child on the level below
+	for pos := 0; pos < n; pos++ {
+		if n <= t.index(pos) {
+			return nil, fmt.Errorf("invalid R-tree item index at %v", pos)
+		}
+	}
+	for level := 1; level < len(levels); level++ {
+		start := 0
+		if 1 < level {
+			start = levels[level-2]
+		}
+		for pos := levels[level-1]; pos < levels[level]; pos++ {
+			if child := t.index(pos); child < start || levels[level-1] <= child {
+				return nil, fmt.Errorf("invalid R-tree child position at %v", pos)
+			}
+		}
+	}
+	return t, nil
+}
+
+// Bytes returns the flat buffer of the R-tree, which can be loaded with LoadRTree.
+func (t *RTree) Bytes() []byte {
+	return t.data
+}
+
+// Len returns the number of items.
+func (t *RTree) Len() int {
+	return t.n
+}
+
+func (t *RTree) box(pos int) Bounds {
+	box := t.data[rtreeHeaderSize+pos*rtreeBoxSize:]
+	return Bounds{
+		{math.Float64frombits(binary.LittleEndian.Uint64(box[0:])), math.Float64frombits(binary.LittleEndian.Uint64(box[8:]))},
+		{math.Float64frombits(binary.LittleEndian.Uint64(box[16:])), math.Float64frombits(binary.LittleEndian.Uint64(box[24:]))},
+	}
+}
+
+func (t *RTree) index(pos int) int {
+	size := t.levels[len(t.levels)-1]
+	return int(binary.LittleEndian.Uint32(t.data[rtreeHeaderSize+size*rtreeBoxSize+pos*4:]))
+}
+
+// children returns the range of positions of the children of the node at pos on the given level.
+func (t *RTree) children(pos, level int) (int, int) {
+	start := t.index(pos)
+	return start, min(start+t.nodeSize, t.levels[level-1])
+}
+
+// Search calls fn for each item whose bounds intersect or touch b, until fn returns false. Wrapped bounds are split at the antimeridian.
+func (t *RTree) Search(b Bounds, fn func(int) bool) {
+	if t.n == 0 {
+		return
+	}
+	parts := []Bounds{b}
+	if b.IsWrapped() {
+		wrapped := b.wrappedParts()
+		parts = wrapped[:]
+	}
+
+	type node struct{ pos, level int }
+	var seen map[int]bool // items found in both parts
+	if 1 < len(parts) {
+		seen = map[int]bool{}
+	}
+	for _, b := range parts {
+		stack := []node{{t.levels[len(t.levels)-1] - 1, len(t.levels) - 1}}
+		for 0 < len(stack) {
+			cur := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			start, end := t.children(cur.pos, cur.level)
+			for pos := start; pos < end; pos++ {
+				if !t.box(pos).overlaps(b) {
+					continue
+				} else if 1 < cur.level {
+					stack = append(stack, node{pos, cur.level - 1})
+				} else if i := t.index(pos); !seen[i] {
+					if seen != nil {
+						seen[i] = true
+					}
+					if !fn(i) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// boxDistance returns the planar distance between c and the bounds, which is zero if c lies within.
+func boxDistance(c Coord, b Bounds) float64 {
+	dx := math.Max(0.0, math.Max(b[0].X-c.X, c.X-b[1].X))
+	dy := math.Max(0.0, math.Max(b[0].Y-c.Y, c.Y-b[1].Y))
+	return math.Hypot(dx, dy)
+}
+
+type rtreeItem struct {
+	pos, level int
+	dist       float64
+	exact      bool // dist is the exact distance of an item
+}
+
+type rtreeHeap []rtreeItem
+
+func (h rtreeHeap) Len() int           { return len(h) }
+func (h rtreeHeap) Less(i, j int) bool { return h[i].dist < h[j].dist }
+func (h rtreeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *rtreeHeap) Push(x any)        { *h = append(*h, x.(rtreeItem)) }
+func (h *rtreeHeap) Pop() any {
+	item := (*h)[len(*h)-1]
+	*h = (*h)[:len(*h)-1]
+	return item
+}
+
+// Nearest returns up to k items nearest to c in order of increasing distance. The distance is the planar distance to the item's bounds in degrees, or if dist is not nil, dist(i) for item i which must be no less than the distance to its bounds.
+func (t *RTree) Nearest(c Coord, k int, dist func(int) float64) []int {
+	if t.n == 0 || k <= 0 {
+		return nil
+	}
+
+	items := []int{}
+	queue := &rtreeHeap{{pos: t.levels[len(t.levels)-1] - 1, level: len(t.levels) - 1}}
+	for 0 < queue.Len() {
+		item := heap.Pop(queue).(rtreeItem)
+		if item.level == 0 {
+			if item.exact || dist == nil {
+				if items = append(items, t.index(item.pos)); len(items) == k {
+					break
+				}
+			} else if d := dist(t.index(item.pos)); !math.IsNaN(d) {
+				heap.Push(queue, rtreeItem{item.pos, 0, d, true})
+			}
+			continue
+		}
+		start, end := t.children(item.pos, item.level)
+		for pos := start; pos < end; pos++ {
+			if d := boxDistance(c, t.box(pos)); !math.IsNaN(d) {
+				heap.Push(queue, rtreeItem{pos, item.level - 1, d, false})
+			}
+		}
+	}
+	return items
+}
+
+// Containing returns the indices of the geometries with a polygon that contains c, where the R-tree was built over geoms.
+func (t *RTree) Containing(geoms []Geometry, c Coord) []int {
+	indices := []int{}
+	t.Search(Bounds{c, c}, func(i int) bool {
+		if geoms[i].Contains(c) {
+			indices = append(indices, i)
+		}
+		return true
+	})
+	return indices
+}
+
+// NearestGeometries returns the indices of up to k geometries nearest to c in order of increasing planar distance in degrees, where the R-tree was built over geoms. Geometries that contain c have distance zero.
+func (t *RTree) NearestGeometries(geoms []Geometry, c Coord, k int) []int {
+	return t.Nearest(c, k, func(i int) float64 {
+		return geoms[i].distance(c)
+	})
+}
+
+// Bounds returns the bounding box of all coordinates of the geometry. Empty geometries return bounds that do not overlap any other bounds.
+func (g Geometry) Bounds() Bounds {
+	b := ringBounds(g.Points)
+	for _, lineString := range g.LineStrings {
+		b = b.union(ringBounds(lineString))
+	}
+	for _, polygon := range g.Polygons {
+		b = b.union(ringBounds(polygon.Outer))
+	}
+	return b
+}
+
+// Contains returns true if c lies within one of the polygons of the geometry and not within its holes.
+func (g Geometry) Contains(c Coord) bool {
+	for _, polygon := range g.Polygons {
+		if pointInRing(c, polygon.Outer) && !slices.ContainsFunc(polygon.Holes, func(hole []Coord) bool {
+			return pointInRing(c, hole)
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// distance returns the planar distance between c and the geometry, which is zero within its polygons.
+func (g Geometry) distance(c Coord) float64 {
+	dist := math.Inf(1)
+	for _, p := range g.Points {
+		dist = math.Min(dist, math.Hypot(c.X-p.X, c.Y-p.Y))
+	}
+	for _, lineString := range g.LineStrings {
+		if len(lineString) == 1 {
+			dist = math.Min(dist, segmentDistance(c, lineString[0], lineString[0]))
+		}
+		for i := 0; i+1 < len(lineString); i++ {
+			dist = math.Min(dist, segmentDistance(c, lineString[i], lineString[i+1]))
+		}
+	}
+	for _, polygon := range g.Polygons {
+		d := polygonDistance(c, polygon)
+		if 0.0 <= d {
+			return 0.0
+		}
+		dist = math.Min(dist, -d)
+	}
+	return dist
+}
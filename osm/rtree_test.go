@@ -0,0 +1,187 @@
+package osm
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+)
+
+// gridBounds returns nx by ny boxes of size 0.5 on a grid with spacing 1.
+func gridBounds(nx, ny int) []Bounds {
+	bounds := []Bounds{}
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			x, y := float64(i), float64(j)
+			bounds = append(bounds, Bounds{{x, y}, {x + 0.5, y + 0.5}})
+		}
+	}
+	return bounds
+}
+
+func searchAll(tree *RTree, b Bounds) []int {
+	found := []int{}
+	tree.Search(b, func(i int) bool {
+		found = append(found, i)
+		return true
+	})
+	slices.Sort(found)
+	return found
+}
+
+func TestRTreeSearch(t *testing.T) {
+	bounds := gridBounds(50, 40)
+	bounds = append(bounds, Bounds{{170.0, 0.0}, {-170.0, 1.0}})
+	for _, nodeSize := range []int{2, 4, 16} {
+		tree := NewRTreeFromBounds(bounds, nodeSize)
+		if tree.Len() != len(bounds) {
+			t.Errorf("expected %v items, got %v", len(bounds), tree.Len())
+		}
+		for _, query := range []Bounds{
+			{{10.2, 5.2}, {13.7, 8.1}},
+			{{0.6, 0.6}, {0.9, 0.9}},
+			{{-10.0, -10.0}, {100.0, 100.0}},
+			{{49.5, 39.5}, {49.5, 39.5}},
+			{{175.0, 0.0}, {5.0, 0.2}}, // wrapped
+		} {
+			expected := []int{}
+			for i, b := range bounds {
+				if b.IsWrapped() {
+					b[0].X, b[1].X = -180.0, 180.0
+				}
+				if query.IsWrapped() {
+					parts := query.wrappedParts()
+					if b.overlaps(parts[0]) || b.overlaps(parts[1]) {
+						expected = append(expected, i)
+					}
+				} else if b.overlaps(query) {
+					expected = append(expected, i)
+				}
+			}
+			if found := searchAll(tree, query); !slices.Equal(found, expected) {
+				t.Errorf("node size %v, query %v: expected %v, got %v", nodeSize, query, expected, found)
+			}
+		}
+	}
+
+	// stop early
+	tree := NewRTreeFromBounds(bounds, 4)
+	n := 0
+	tree.Search(Bounds{{0.0, 0.0}, {10.0, 10.0}}, func(int) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Errorf("expected search to stop after 3 items, got %v", n)
+	}
+
+	// empty and single trees
+	if found := searchAll(NewRTreeFromBounds(nil, 4), Bounds{{0.0, 0.0}, {1.0, 1.0}}); len(found) != 0 {
+		t.Errorf("expected no items, got %v", found)
+	}
+	if found := searchAll(NewRTreeFromBounds(bounds[:1], 4), Bounds{{0.0, 0.0}, {1.0, 1.0}}); !slices.Equal(found, []int{0}) {
+		t.Errorf("expected item 0, got %v", found)
+	}
+}
+
+func TestRTreeNearest(t *testing.T) {
+	bounds := gridBounds(30, 30)
+	tree := NewRTreeFromBounds(bounds, 4)
+	c := Coord{10.6, 20.25}
+	nearest := tree.Nearest(c, 5, nil)
+	if len(nearest) != 5 {
+		t.Fatalf("expected 5 items, got %v", nearest)
+	}
+	dists := []float64{}
+	for _, i := range nearest {
+		dists = append(dists, boxDistance(c, bounds[i]))
+	}
+	if !slices.IsSorted(dists) {
+		t.Errorf("expected increasing distances, got %v", dists)
+	}
+	if nearest[0] != 20*30+10 || 1e-9 < math.Abs(dists[0]-0.1) || 1e-9 < math.Abs(dists[1]-0.4) {
+		t.Errorf("wrong nearest items %v with distances %v", nearest, dists)
+	}
+
+	// custom distance that pushes away the box containing the coordinate
+	c = Coord{3.2, 3.3}
+	nearest = tree.Nearest(c, 2, func(i int) float64 {
+		if i == 3*30+3 {
+			return 10.0
+		}
+		return boxDistance(c, bounds[i])
+	})
+	if !slices.Equal(nearest, []int{3*30 + 2, 4*30 + 3}) {
+		t.Errorf("expected items %v and %v, got %v", 3*30+2, 4*30+3, nearest)
+	}
+	if nearest := tree.Nearest(c, len(bounds)+10, nil); len(nearest) != len(bounds) {
+		t.Errorf("expected all %v items, got %v", len(bounds), len(nearest))
+	}
+}
+
+func TestRTreeBytes(t *testing.T) {
+	bounds := gridBounds(20, 20)
+	tree := NewRTreeFromBounds(bounds, 8)
+	loaded, err := LoadRTree(slices.Clone(tree.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := Bounds{{3.3, 4.4}, {7.7, 5.5}}
+	if expected, found := searchAll(tree, query), searchAll(loaded, query); !slices.Equal(found, expected) {
+		t.Errorf("expected %v, got %v", expected, found)
+	}
+
+	if _, err := LoadRTree([]byte("RTREE")); err == nil {
+		t.Errorf("expected error for invalid header")
+	}
+	if _, err := LoadRTree(tree.Bytes()[:len(tree.Bytes())-1]); err == nil {
+		t.Errorf("expected error for truncated data")
+	}
+
+	// corrupt an item index and the first child of the root
+	size := loaded.levels[len(loaded.levels)-1]
+	for _, pos := range []int{0, size - 1} {
+		data := slices.Clone(tree.Bytes())
+		binary.LittleEndian.PutUint32(data[rtreeHeaderSize+size*rtreeBoxSize+pos*4:], uint32(size))
+		if _, err := LoadRTree(data); err == nil {
+			t.Errorf("expected error for invalid index at %v", pos)
+		}
+	}
+}
+
+func TestRTreeGeometries(t *testing.T) {
+	geoms := []Geometry{
+		{ID: 1, Points: []Coord{{5.0, 5.0}}},
+		{ID: 2, LineStrings: [][]Coord{{{0.0, 0.0}, {10.0, 0.0}}}},
+		{ID: 3, Polygons: []PolygonWithHoles{{
+			Outer: []Coord{{0.0, 1.0}, {4.0, 1.0}, {4.0, 4.0}, {0.0, 4.0}, {0.0, 1.0}},
+			Holes: [][]Coord{{{1.0, 2.0}, {1.0, 3.0}, {2.0, 3.0}, {2.0, 2.0}, {1.0, 2.0}}},
+		}}},
+		{ID: 4, Polygons: []PolygonWithHoles{{
+			Outer: []Coord{{0.0, 0.5}, {8.0, 0.5}, {8.0, 8.0}, {0.0, 8.0}, {0.0, 0.5}},
+		}}},
+		{ID: 5}, // empty
+	}
+	tree := NewRTree(geoms)
+	if b := geoms[2].Bounds(); b != (Bounds{{0.0, 1.0}, {4.0, 4.0}}) {
+		t.Errorf("wrong bounds %v", b)
+	}
+	if found := tree.Containing(geoms, Coord{3.0, 3.0}); !slices.Equal(sorted(found), []int{2, 3}) {
+		t.Errorf("expected geometries 2 and 3, got %v", found)
+	}
+	if found := tree.Containing(geoms, Coord{1.5, 2.5}); !slices.Equal(found, []int{3}) {
+		t.Errorf("expected geometry 3 for point in hole, got %v", found)
+	}
+	if nearest := tree.NearestGeometries(geoms, Coord{6.0, 0.2}, 2); !slices.Equal(nearest, []int{1, 3}) {
+		t.Errorf("expected geometries 1 and 3, got %v", nearest)
+	}
+	if nearest := tree.NearestGeometries(geoms, Coord{9.0, 5.0}, 1); !slices.Equal(nearest, []int{3}) {
+		t.Errorf("expected geometry 3, got %v", nearest)
+	}
+}
+
+func sorted(s []int) []int {
+	s = slices.Clone(s)
+	slices.Sort(s)
+	return s
+}
@@ -0,0 +1,500 @@
+package osm
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"math"
+	"slices"
+)
+
+// SimplifyMethod is the algorithm used for simplifying line strings and polygons.
+type SimplifyMethod int
+
+const (
+	// DouglasPeucker removes vertices that are within the tolerance distance from the simplified line.
+	DouglasPeucker SimplifyMethod = iota
+	// VisvalingamWhyatt removes vertices whose effective triangle area is smaller than the tolerance, which is in squared units.
+	VisvalingamWhyatt
+)
+
+// simplifyMinFactor is the smallest fraction of the tolerance that is tried when a simplified polygon is invalid, before using the original rings.
+const simplifyMinFactor = 1.0 / 1024.0
+
+// SimplifyLineString simplifies a line string, keeping its first and last coordinate. Closed line strings are simplified as rings and do not collapse.
+func SimplifyLineString(coords []Coord, method SimplifyMethod, tolerance float64) []Coord {
+	if len(coords) < 3 || tolerance <= 0.0 {
+		return coords
+	} else if coords[0] == coords[len(coords)-1] {
+		return simplifyRing(coords, method, tolerance)
+	} else if method == VisvalingamWhyatt {
+		return visvalingamWhyatt(coords, tolerance, false)
+	}
+	return douglasPeucker(coords, tolerance)
+}
+
+// SimplifyPolygon simplifies the outer ring and holes of a polygon. Rings do not collapse and no self-intersections are created: if the simplified polygon is invalid it is simplified again using a smaller tolerance, or otherwise the original polygon is returned.
+func SimplifyPolygon(polygon PolygonWithHoles, method SimplifyMethod, tolerance float64) PolygonWithHoles {
+	return simplifyPolygons([]PolygonWithHoles{polygon}, method, tolerance, false)[0]
+}
+
+// SimplifyPolygons simplifies polygons like SimplifyPolygon, but edges that are shared between polygons (or rings) are simplified identically so that no gaps or overlaps appear between adjacent polygons. Vertices where three or more edges meet are kept.
+func SimplifyPolygons(polygons []PolygonWithHoles, method SimplifyMethod, tolerance float64) []PolygonWithHoles {
+	return simplifyPolygons(polygons, method, tolerance, true)
+}
+
+// SimplifyGeometry simplifies the line strings and polygons of a geometry, where edges shared between its polygons are simplified identically. Points are kept.
+func SimplifyGeometry(geom Geometry, method SimplifyMethod, tolerance float64) Geometry {
+	return SimplifyGeometries([]Geometry{geom}, method, tolerance)[0]
+}
+
+// SimplifyGeometries simplifies the line strings and polygons of geometries, where edges shared between all polygons are simplified identically, such as for adjacent landuse polygons. Points are kept.
+func SimplifyGeometries(geoms []Geometry, method SimplifyMethod, tolerance float64) []Geometry {
+	var polygons []PolygonWithHoles
+	for _, geom := range geoms {
+		polygons = append(polygons, geom.Polygons...)
+	}
+	polygons = simplifyPolygons(polygons, method, tolerance, true)
+
+	simplified := make([]Geometry, len(geoms))
+	for i, geom := range geoms {
+		if 0 < len(geom.LineStrings) {
+			lineStrings := make([][]Coord, len(geom.LineStrings))
+			for j, coords := range geom.LineStrings {
+				lineStrings[j] = SimplifyLineString(coords, method, tolerance)
+			}
+			geom.LineStrings = lineStrings
+		}
+		if 0 < len(geom.Polygons) {
+			geom.Polygons = polygons[:len(geom.Polygons):len(geom.Polygons)]
+			polygons = polygons[len(geom.Polygons):]
+		}
+		simplified[i] = geom
+	}
+	return simplified
+}
+
+// douglasPeucker simplifies an open line string using the Douglas-Peucker algorithm.
+func douglasPeucker(coords []Coord, tolerance float64) []Coord {
+	keep := make([]bool, len(coords))
+	keep[0], keep[len(coords)-1] = true, true
+	stack := [][2]int{{0, len(coords) - 1}}
+	for 0 < len(stack) {
+		i0, i1 := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		k, dist := -1, tolerance
+		for i := i0 + 1; i < i1; i++ {
+			if d := segmentDistance(coords[i], coords[i0], coords[i1]); dist < d {
+				k, dist = i, d
+			}
+		}
+		if k != -1 {
+			keep[k] = true
+			stack = append(stack, [2]int{i0, k}, [2]int{k, i1})
+		}
+	}
+
+	simplified := make([]Coord, 0, len(coords))
+	for i, coord := range coords {
+		if keep[i] {
+			simplified = append(simplified, coord)
+		}
+	}
+	return simplified
+}
+
+// segmentDistance returns the distance between c and the segment from a to b.
+func segmentDistance(c, a, b Coord) float64 {
+	ab, ac := b.Sub(a), c.Sub(a)
+	t := 0.0
+	if l := ab.X*ab.X + ab.Y*ab.Y; l != 0.0 {
+		t = math.Max(0.0, math.Min(1.0, (ac.X*ab.X+ac.Y*ab.Y)/l))
+	}
+	return math.Hypot(ac.X-t*ab.X, ac.Y-t*ab.Y)
+}
+
+// triangleArea returns the unsigned area of the triangle.
+func triangleArea(a, b, c Coord) float64 {
+	ab, ac := b.Sub(a), c.Sub(a)
+	return 0.5 * math.Abs(ab.X*ac.Y-ab.Y*ac.X)
+}
+
+type vwItem struct {
+	area    float64
+	index   int
+	version int
+}
+
+type vwHeap []vwItem
+
+func (h vwHeap) Len() int           { return len(h) }
+func (h vwHeap) Less(i, j int) bool { return h[i].area < h[j].area }
+func (h vwHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *vwHeap) Push(x any)        { *h = append(*h, x.(vwItem)) }
+func (h *vwHeap) Pop() any {
+	item := (*h)[len(*h)-1]
+	*h = (*h)[:len(*h)-1]
+	return item
+}
+
+// visvalingamWhyatt simplifies a line string using the Visvalingam-Whyatt algorithm. For closed line strings the coordinates are cyclic without a duplicate closing coordinate, the first coordinate is kept, and at least three coordinates are kept.
+func visvalingamWhyatt(coords []Coord, tolerance float64, closed bool) []Coord {
+	n := len(coords)
+	prev, next := make([]int, n), make([]int, n)
+	version := make([]int, n)
+	for i := range coords {
+		prev[i], next[i] = i-1, i+1
+	}
+	if closed {
+		prev[0], next[n-1] = n-1, 0
+	}
+
+	h := vwHeap{}
+	push := func(i int) {
+		if closed && i == 0 {
+			return // keep the first coordinate
+		} else if prev[i] != -1 && next[i] != n {
+			version[i]++
+			heap.Push(&h, vwItem{triangleArea(coords[prev[i]], coords[i], coords[next[i]]), i, version[i]})
+		}
+	}
+	for i := range coords {
+		push(i)
+	}
+
+	minimum := 2
+	if closed {
+		minimum = 3
+	}
+	removed := make([]bool, n)
+	remaining, maxArea := n, 0.0
+	for minimum < remaining && 0 < len(h) {
+		item := heap.Pop(&h).(vwItem)
+		if item.version != version[item.index] {
+			continue // stale
+		} else if tolerance <= math.Max(item.area, maxArea) {
+			break
+		}
+		maxArea = math.Max(item.area, maxArea) // effective area never decreases
+		i := item.index
+		removed[i] = true
+		remaining--
+		next[prev[i]], prev[next[i]] = next[i], prev[i]
+		push(prev[i])
+		push(next[i])
+	}
+
+	simplified := make([]Coord, 0, remaining)
+	for i, coord := range coords {
+		if !removed[i] {
+			simplified = append(simplified, coord)
+		}
+	}
+	return simplified
+}
+
+// simplifyRing simplifies a closed ring, keeping its first coordinate and at least three distinct coordinates.
+func simplifyRing(ring []Coord, method SimplifyMethod, tolerance float64) []Coord {
+	if len(ring) < 5 || tolerance <= 0.0 {
+		return ring
+	} else if method == VisvalingamWhyatt {
+		simplified := visvalingamWhyatt(ring[:len(ring)-1], tolerance, true)
+		return append(simplified, simplified[0])
+	}
+
+	// split at the coordinate farthest from the first, and if needed at the coordinate farthest from the line between both
+	k, dist := 0, 0.0
+	for i, c := range ring {
+		if d := math.Hypot(c.X-ring[0].X, c.Y-ring[0].Y); dist < d {
+			k, dist = i, d
+		}
+	}
+	if k == 0 {
+		return ring
+	}
+	splits := []int{0, k, len(ring) - 1}
+	for {
+		simplified := []Coord{ring[0]}
+		for i := 1; i < len(splits); i++ {
+			simplified = append(simplified, douglasPeucker(ring[splits[i-1]:splits[i]+1], tolerance)[1:]...)
+		}
+		if 4 < len(simplified) || 3 < len(splits) {
+			return simplified
+		}
+
+		j, dist := 0, 0.0
+		for i, c := range ring {
+			if d := segmentDistance(c, ring[0], ring[k]); dist < d {
+				j, dist = i, d
+			}
+		}
+		if j == 0 {
+			return ring
+		}
+		splits = append(splits, j)
+		slices.Sort(splits)
+	}
+}
+
+// simplifyArc is a part of a ring between two junctions, or a whole ring if it has no junctions.
+type simplifyArc struct {
+	coords []Coord
+	closed bool
+	factor float64 // fraction of the tolerance
+	result []Coord
+}
+
+// ringArc references an arc of a ring, possibly reversed.
+type ringArc struct {
+	arc      int
+	reversed bool
+}
+
+// arcKey returns a key for the coordinates that is equal for identical sequences.
+func arcKey(coords []Coord) string {
+	b := make([]byte, 0, 16*len(coords))
+	for _, c := range coords {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(c.X))
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(c.Y))
+	}
+	return string(b)
+}
+
+func lessCoord(a, b Coord) bool {
+	return a.X < b.X || a.X == b.X && a.Y < b.Y
+}
+
+// simplifyPolygons simplifies polygons by splitting rings into arcs at junctions, simplifying each unique arc once, and reassembling the rings. Polygons that become invalid are simplified again with a smaller tolerance for their arcs, and all polygons sharing those arcs are validated again so that shared arcs stay identical.
+func simplifyPolygons(polygons []PolygonWithHoles, method SimplifyMethod, tolerance float64, shared bool) []PolygonWithHoles {
+	if tolerance <= 0.0 {
+		return polygons
+	}
+
+	// find junctions, which are coordinates with more than two distinct neighbours
+	junctions := map[Coord]bool{}
+	if shared {
+		neighbours := map[Coord][]Coord{}
+		addNeighbour := func(c, n Coord) {
+			if ns := neighbours[c]; len(ns) < 3 && !slices.Contains(ns, n) {
+				neighbours[c] = append(ns, n)
+			}
+		}
+		for _, polygon := range polygons {
+			for _, ring := range append([][]Coord{polygon.Outer}, polygon.Holes...) {
+				for i := 0; i+1 < len(ring); i++ {
+					addNeighbour(ring[i], ring[i+1])
+					addNeighbour(ring[i+1], ring[i])
+				}
+			}
+		}
+		for c, ns := range neighbours {
+			if len(ns) != 2 {
+				junctions[c] = true
+			}
+		}
+	}
+
+	// split rings into arcs
+	var arcs []simplifyArc
+	arcIndex := map[string]int{}
+	addArc := func(coords []Coord, closed bool) ringArc {
+		reversed := false
+		if closed {
+			// rotate to the smallest coordinate and orient towards its smallest neighbour
+			coords = coords[:len(coords)-1]
+			k := 0
+			for i, c := range coords {
+				if lessCoord(c, coords[k]) {
+					k = i
+				}
+			}
+			rotated := append(slices.Clone(coords[k:]), coords[:k]...)
+			if 2 < len(rotated) && lessCoord(rotated[len(rotated)-1], rotated[1]) {
+				slices.Reverse(rotated[1:])
+				reversed = true
+			}
+			coords = append(rotated, rotated[0])
+		} else if lessCoord(coords[len(coords)-1], coords[0]) || coords[0] == coords[len(coords)-1] && 2 < len(coords) && lessCoord(coords[len(coords)-2], coords[1]) {
+			coords = reverseOrientation(slices.Clone(coords))
+			reversed = true
+		}
+		key := arcKey(coords)
+		index, ok := arcIndex[key]
+		if !ok {
+			index = len(arcs)
+			arcIndex[key] = index
+			arcs = append(arcs, simplifyArc{coords: coords, closed: closed, factor: 1.0})
+		}
+		return ringArc{index, reversed}
+	}
+
+	ringArcs := make([][][]ringArc, len(polygons)) // arcs per ring per polygon
+	for p, polygon := range polygons {
+		for _, ring := range append([][]Coord{polygon.Outer}, polygon.Holes...) {
+			var refs []ringArc
+			k := -1
+			for i := 0; i+1 < len(ring); i++ {
+				if junctions[ring[i]] {
+					k = i
+					break
+				}
+			}
+			if len(ring) < 4 {
+				refs = append(refs, addArc(ring, false)) // keep as is
+			} else if k == -1 {
+				refs = append(refs, addArc(ring, true))
+			} else {
+				rotated := append(slices.Clone(ring[k:len(ring)-1]), ring[:k+1]...)
+				start := 0
+				for i := 1; i < len(rotated); i++ {
+					if junctions[rotated[i]] {
+						refs = append(refs, addArc(rotated[start:i+1], false))
+						start = i
+					}
+				}
+			}
+			ringArcs[p] = append(ringArcs[p], refs)
+		}
+	}
+
+	// simplify arcs and reassemble rings, reducing the tolerance for arcs of invalid polygons
+	simplifyArcs := func() {
+		for i, arc := range arcs {
+			if arc.result != nil {
+				continue
+			} else if arc.factor < simplifyMinFactor {
+				arcs[i].result = arc.coords
+			} else if arc.closed {
+				arcs[i].result = simplifyRing(arc.coords, method, arc.factor*tolerance)
+			} else if 2 < len(arc.coords) {
+				arcs[i].result = SimplifyLineString(arc.coords, method, arc.factor*tolerance)
+			} else {
+				arcs[i].result = arc.coords
+			}
+		}
+	}
+	assemble := func(refs []ringArc) []Coord {
+		var ring []Coord
+		for _, ref := range refs {
+			coords := arcs[ref.arc].result
+			if ref.reversed {
+				coords = reverseOrientation(slices.Clone(coords))
+			}
+			if 0 < len(ring) {
+				coords = coords[1:]
+			}
+			ring = append(ring, coords...)
+		}
+		return ring
+	}
+
+	// polygons using each arc, which must be validated again when the arc changes
+	users := make([][]int, len(arcs))
+	for p := range polygons {
+		for _, refs := range ringArcs[p] {
+			for _, ref := range refs {
+				if users[ref.arc] == nil || users[ref.arc][len(users[ref.arc])-1] != p {
+					users[ref.arc] = append(users[ref.arc], p)
+				}
+			}
+		}
+	}
+
+	simplified := make([]PolygonWithHoles, len(polygons))
+	pending := make([]bool, len(polygons))
+	for p := range polygons {
+		pending[p] = true
+	}
+	for slices.Contains(pending, true) {
+		simplifyArcs()
+		var invalid []int
+		for p := range polygons {
+			if !pending[p] {
+				continue
+			}
+			pending[p] = false
+
+			rings := make([][]Coord, len(ringArcs[p]))
+			for i, refs := range ringArcs[p] {
+				rings[i] = assemble(refs)
+			}
+			original := append([][]Coord{polygons[p].Outer}, polygons[p].Holes...)
+			if validSimplification(original, rings) {
+				simplified[p] = PolygonWithHoles{Outer: rings[0], Holes: rings[1:]}
+			} else {
+				invalid = append(invalid, p)
+			}
+		}
+
+		// halve the tolerance of the arcs of invalid polygons at most once per round, or reset them to their original coordinates if the tolerance is exhausted
+		changed := make([]bool, len(arcs))
+		for _, p := range invalid {
+			exhausted := true
+			for _, refs := range ringArcs[p] {
+				for _, ref := range refs {
+					if simplifyMinFactor <= arcs[ref.arc].factor || changed[ref.arc] {
+						exhausted = false
+					}
+				}
+			}
+			for _, refs := range ringArcs[p] {
+				for _, ref := range refs {
+					arc := &arcs[ref.arc]
+					if exhausted {
+						if !slices.Equal(arc.result, arc.coords) {
+							changed[ref.arc] = true
+						}
+						arc.factor, arc.result = 0.0, arc.coords
+					} else if simplifyMinFactor <= arc.factor && !changed[ref.arc] {
+						arc.factor /= 2.0
+						arc.result = nil
+						changed[ref.arc] = true
+					}
+				}
+			}
+			if exhausted {
+				simplified[p] = polygons[p]
+			}
+		}
+		for i := range arcs {
+			if changed[i] {
+				for _, p := range users[i] {
+					pending[p] = true
+				}
+			}
+		}
+	}
+	return simplified
+}
+
+// validSimplification returns true if the simplified rings are not degenerate, have the same orientation as the original rings, holes remain within the outer ring, and no intersections or duplicate segments are introduced, even if others are removed.
+func validSimplification(original, rings [][]Coord) bool {
+	for i, ring := range rings {
+		if len(ring) < 4 || ring[0] != ring[len(ring)-1] || degenerateRing(ring) || isCCW(ring) != isCCW(original[i]) {
+			return false
+		}
+	}
+	for _, hole := range rings[1:] {
+		if !ringInRing(hole, rings[0]) {
+			return false
+		}
+	}
+	// every problem must exist at the same location in the original rings
+	infinite := Bounds{{math.Inf(-1), math.Inf(-1)}, {math.Inf(1), math.Inf(1)}}
+	type problemKey struct {
+		typ   ProblemType
+		coord Coord
+	}
+	existing := map[problemKey]int{}
+	for _, problem := range ringProblems(original, nil, infinite) {
+		existing[problemKey{problem.Type, problem.Coord}]++
+	}
+	for _, problem := range ringProblems(rings, nil, infinite) {
+		key := problemKey{problem.Type, problem.Coord}
+		if existing[key] == 0 {
+			return false
+		}
+		existing[key]--
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package osm
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestSimplifyLineString(t *testing.T) {
+	coords := []Coord{{0, 0}, {1, 0.1}, {2, -0.1}, {3, 5}, {4, 6}, {5, 7}, {6, 8.1}, {7, 9}, {8, 9}, {9, 9}}
+	simplified := SimplifyLineString(coords, DouglasPeucker, 0.5)
+	if len(simplified) != 5 || simplified[0] != coords[0] || simplified[len(simplified)-1] != coords[len(coords)-1] {
+		t.Errorf("unexpected Douglas-Peucker simplification: %v", simplified)
+	}
+	simplified = SimplifyLineString(coords, VisvalingamWhyatt, 0.5)
+	if len(simplified) != 5 || simplified[0] != coords[0] || simplified[len(simplified)-1] != coords[len(coords)-1] {
+		t.Errorf("unexpected Visvalingam-Whyatt simplification: %v", simplified)
+	}
+}
+
+func TestSimplifyRing(t *testing.T) {
+	// small square does not collapse
+	ring := []Coord{{0, 0}, {1, 0}, {1, 1}, {0.5, 1.01}, {0, 1}, {0, 0}}
+	for _, method := range []SimplifyMethod{DouglasPeucker, VisvalingamWhyatt} {
+		simplified := SimplifyLineString(ring, method, 10.0)
+		if len(simplified) != 4 || simplified[0] != simplified[3] || degenerateRing(simplified) {
+			t.Errorf("expected triangle for method %v: %v", method, simplified)
+		}
+	}
+}
+
+func TestSimplifyPolygon(t *testing.T) {
+	// hole close to an outer vertex that would be removed
+	polygon := PolygonWithHoles{
+		Outer: []Coord{{0, 0}, {10, 0}, {10, 10}, {5, 12}, {0, 10}, {0, 0}},
+		Holes: [][]Coord{{{4, 10.5}, {5, 11.5}, {6, 10.5}, {4, 10.5}}},
+	}
+	for _, method := range []SimplifyMethod{DouglasPeucker, VisvalingamWhyatt} {
+		simplified := SimplifyPolygon(polygon, method, 20.0)
+		if len(simplified.Outer) != 6 {
+			t.Errorf("expected outer vertex to be kept for method %v: %v", method, simplified.Outer)
+		}
+		rings := append([][]Coord{simplified.Outer}, simplified.Holes...)
+		if !validSimplification(append([][]Coord{polygon.Outer}, polygon.Holes...), rings) {
+			t.Errorf("invalid simplification for method %v: %v", method, simplified)
+		}
+	}
+}
+
+func TestSimplifyPolygonsShared(t *testing.T) {
+	// two squares sharing a wiggly edge
+	edge := []Coord{{10, 0}, {10.1, 2}, {9.9, 4}, {10.1, 6}, {9.9, 8}, {10, 10}}
+	left := []Coord{{0, 0}}
+	left = append(left, edge...)
+	left = append(left, Coord{0, 10}, Coord{0, 0})
+	right := []Coord{{20, 0}, {20, 10}}
+	for i := len(edge) - 1; 0 <= i; i-- {
+		right = append(right, edge[i])
+	}
+	right = append(right, Coord{20, 0})
+
+	polygons := SimplifyPolygons([]PolygonWithHoles{{Outer: left}, {Outer: right}}, DouglasPeucker, 0.5)
+	area := 0.0
+	for _, polygon := range polygons {
+		if !isCCW(polygon.Outer) {
+			t.Errorf("outer ring must be CCW: %v", polygon.Outer)
+		}
+		area += ringArea(polygon.Outer)
+	}
+	if math.Abs(area-200.0) > 1e-9 {
+		t.Errorf("expected total area 200 without gaps or overlaps, got %v: %v", area, polygons)
+	}
+	if len(polygons[0].Outer) != 5 || len(polygons[1].Outer) != 5 {
+		t.Errorf("expected shared edge to be simplified: %v", polygons)
+	}
+}
+
+func TestSimplifyPolygonsSharedInvalid(t *testing.T) {
+	// two squares sharing a bulging edge, where the bulge of the right square has a hole that prevents simplifying the shared edge
+	left := []Coord{{-10, 0}, {0, 0}, {-0.4, 4}, {-0.4, 6}, {0, 10}, {-10, 10}, {-10, 0}}
+	right := []Coord{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {-0.4, 6}, {-0.4, 4}, {0, 0}}
+	hole := []Coord{{-0.3, 4.9}, {-0.3, 5.1}, {-0.1, 5.0}, {-0.3, 4.9}}
+
+	polygons := SimplifyPolygons([]PolygonWithHoles{{Outer: left}, {Outer: right, Holes: [][]Coord{hole}}}, DouglasPeucker, 1.0)
+	if len(polygons[1].Holes) != 1 || !ringInRing(polygons[1].Holes[0], polygons[1].Outer) {
+		t.Errorf("hole must remain within the outer ring: %v", polygons[1])
+	}
+	if !slices.Contains(polygons[0].Outer, Coord{-0.4, 4}) || !slices.Contains(polygons[1].Outer, Coord{-0.4, 4}) {
+		t.Errorf("expected shared edge to be kept identically: %v", polygons)
+	}
+	area := ringArea(polygons[0].Outer) + ringArea(polygons[1].Outer)
+	if math.Abs(area-200.0) > 1e-9 {
+		t.Errorf("expected total area 200 without gaps or overlaps, got %v: %v", area, polygons)
+	}
+}
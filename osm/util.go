@@ -20,10 +20,18 @@ func (p Coord) AngleBetween(q Coord) float64 {
 	return math.Atan2(perpdot, dot)
 }
 
-// Bounds is the [min,max] coordinate of a bounding box.
+// Bounds is the [min,max] coordinate of a bounding box. For longitudes and latitudes, the bounds are wrapped when the minimum longitude is larger than the maximum longitude, in which case they cross the antimeridian.
 type Bounds [2]Coord
 
+// IsWrapped returns true if the bounds cross the antimeridian.
+func (b Bounds) IsWrapped() bool {
+	return b[1].X < b[0].X
+}
+
 func (b Bounds) W() float64 {
+	if b.IsWrapped() {
+		return b[1].X + 360.0 - b[0].X
+	}
 	return b[1].X - b[0].X
 }
 
@@ -32,14 +40,30 @@ func (b Bounds) H() float64 {
 }
 
 func (b Bounds) Centre() Coord {
+	if b.IsWrapped() {
+		return Coord{wrapLongitude(b[0].X + b.W()/2.0), (b[0].Y + b[1].Y) / 2.0}
+	}
 	return Coord{(b[0].X + b[1].X) / 2.0, (b[0].Y + b[1].Y) / 2.0}
 }
 
 func (b Bounds) Contains(c Coord) bool {
+	if b.IsWrapped() {
+		return (b[0].X <= c.X || c.X <= b[1].X) && b[0].Y <= c.Y && c.Y <= b[1].Y
+	}
 	return b[0].X <= c.X && c.X <= b[1].X && b[0].Y <= c.Y && c.Y <= b[1].Y
 }
 
+// Expand expands the bounds on each side. Wrapped bounds stay within [-180,180] and cover all longitudes if they are expanded beyond 360 degrees.
 func (b Bounds) Expand(dx, dy float64) Bounds {
+	if b.IsWrapped() {
+		if 360.0 <= b.W()+2.0*dx {
+			return Bounds{{-180.0, b[0].Y - dy}, {180.0, b[1].Y + dy}}
+		}
+		return Bounds{
+			{wrapLongitude(b[0].X - dx), b[0].Y - dy},
+			{wrapLongitude(b[1].X + dx), b[1].Y + dy},
+		}
+	}
 	return Bounds{
 		{b[0].X - dx, b[0].Y - dy},
 		{b[1].X + dx, b[1].Y + dy},
@@ -51,32 +75,25 @@ func (b Bounds) ExpandByFactor(f float64) Bounds {
 	return b.Expand(f*b.W(), f*b.H())
 }
 
+// Project projects the corners of the bounds and returns the bounds of the result. For wrapped bounds, 360 degrees is added to the maximum longitude so that the projected bounds are continuous.
 func (b Bounds) Project(proj func(float64, float64) (float64, float64)) Bounds {
-	ax, ay := proj(b[0].X, b[0].Y)
-	bx, by := proj(b[1].X, b[0].Y)
-	cx, cy := proj(b[1].X, b[1].Y)
-	dx, dy := proj(b[0].X, b[1].Y)
+	x0, x1 := b[0].X, b[1].X
+	if b.IsWrapped() {
+		x1 += 360.0
+	}
+	ax, ay := proj(x0, b[0].Y)
+	bx, by := proj(x1, b[0].Y)
+	cx, cy := proj(x1, b[1].Y)
+	dx, dy := proj(x0, b[1].Y)
 	return Bounds{
 		{math.Min(math.Min(ax, bx), math.Min(cx, dx)), math.Min(math.Min(ay, by), math.Min(cy, dy))},
 		{math.Max(math.Max(ax, bx), math.Max(cx, dx)), math.Max(math.Max(ay, by), math.Max(cy, dy))},
 	}
 }
 
-// IsArea returns true if the way or relation is considered an enclosed area (in contrast to a open path).
+// IsArea returns true if the way or relation is considered an enclosed area (in contrast to a open path), using DefaultAreaRules.
 func (tags Tags) IsArea() bool {
-	area := false
-	for _, tag := range tags {
-		if tag.Key == "area" && tag.Val == "yes" {
-			return true
-		} else if tag.Key == "area" && tag.Val == "no" {
-			return false
-		} else if tag.Key == "building" || tag.Key == "landuse" || tag.Key == "amenity" || tag.Key == "shop" || tag.Key == "building:part" || tag.Key == "boundary" || tag.Key == "historic" || tag.Key == "place" || tag.Key == "area:highway" || tag.Key == "waterway" && tag.Val == "riverbank" || tag.Key == "highway" && (tag.Val == "rest_area" || tag.Val == "services" || tag.Val == "platform") || tag.Key == "railway" && tag.Val == "platform" || tag.Key == "natural" && (tag.Val == "water" || tag.Val == "wood" || tag.Val == "scrub" || tag.Val == "wetland" || tag.Val == "grassland" || tag.Val == "heath" || tag.Val == "rock" || tag.Val == "bare_rock" || tag.Val == "sand" || tag.Val == "beach" || tag.Val == "scree" || tag.Val == "bay" || tag.Val == "glacier" || tag.Val == "shingle" || tag.Val == "fell" || tag.Val == "reef" || tag.Val == "stone" || tag.Val == "mud" || tag.Val == "landslide" || tag.Val == "sinkhole" || tag.Val == "crevasse" || tag.Val == "desert") || tag.Key == "leisure" && (tag.Val != "picnic_table" && tag.Val != "slipway" && tag.Val != "firepit") || tag.Key == "aeroway" && tag.Val == "aerodrome" {
-			area = true
-		} else if tag.Key == "natural" && tag.Val == "coastline" {
-			area = true
-		}
-	}
-	return area
+	return DefaultAreaRules.IsArea(tags)
 }
 
 func isCCW(coords []Coord) bool {
@@ -101,24 +118,7 @@ func reverseOrientation(coords []Coord) []Coord {
 	return coords2
 }
 
-//func fixAreaCoords(bounds Bounds, coords []Coord, filling bool) []Coord {
-//	if len(coords) < 3 {
-//		return coords
-//	}
-//	ccw := isCCW(coords) // TODO: this may be wrong for partial areas
-//	if coords[0] == coords[len(coords)-1] {
-//		// way already closed, remove superfluous last coordinate
-//		coords = coords[:len(coords)-1]
-//	} else {
-//		// add coordinates around bounding box corners to ensure it is properly filled
-//		coords = closeAroundBounds(bounds, coords, ccw)
-//	}
-//	if filling != ccw {
-//		reverseOrientation(coords)
-//	}
-//	return coords
-//}
-
+// cohenSutherlandOutcode returns the outcode of the coordinate relative to the bounds, which must not be wrapped.
 func cohenSutherlandOutcode(bounds Bounds, c Coord) uint8 {
 	code := uint8(0b0000)
 	if c.X <= bounds[0].X {
@@ -178,54 +178,55 @@ func clipCoord(bounds Bounds, inner, outer Coord, outerOutcode uint8) Coord {
 	}
 }
 
-// closeAroundBounds closes a polygon with start and end points outside of the bounds in a CCW direction.
-//func closeAroundBounds(bounds Bounds, coords []Coord, ccw bool) []Coord {
-//	if len(coords) < 2 {
-//		return coords
-//	}
-//	dx, dy := 0.1*(bounds[1].X-bounds[0].X), 0.1*(bounds[1].Y-bounds[0].Y)
-//	start, end := coords[0], coords[len(coords)-1]
-//	startCode, endCode := cohenSutherlandOutcode(bounds, start), cohenSutherlandOutcode(bounds, end)
-//	if startCode == 0 || endCode == 0 {
-//		// no-op
-//		return coords
-//	} else if startCode&endCode != 0 {
-//		// check if endpoints are on the same side and can simply be closed
-//		centre := bounds.Centre()
-//		angle := end.Sub(centre).AngleBetween(start.Sub(centre))
-//		if ccw && 0.0 < angle || !ccw && angle < 0.0 {
-//			return coords
-//		}
-//	}
-//	for {
-//		if !ccw {
-//			// swap left-top and right-bottom
-//			endCode = (endCode & 0b0001 << 3) | (endCode & 0b0010 << 1) | (endCode & 0b0100 >> 1) | (endCode & 0b1000 >> 3)
-//		}
-//		// go around the clipping area to close the coastline
-//		if endCode&0b0001 != 0 && endCode&0b0100 == 0 {
-//			// left or top-left
-//			coords = append(coords, Coord{bounds[0].X - dx, bounds[0].Y - dy})
-//			endCode = 0b0101
-//		} else if endCode&0b0100 != 0 && endCode&0b0010 == 0 {
-//			// bottom or bottom-left
-//			coords = append(coords, Coord{bounds[1].X + dx, bounds[0].Y - dy})
-//			endCode = 0b0110
-//		} else if endCode&0b0010 != 0 && endCode&0b1000 == 0 {
-//			// right or bottom-right
-//			coords = append(coords, Coord{bounds[1].X + dx, bounds[1].Y + dy})
-//			endCode = 0b1010
-//		} else if endCode&0b1000 != 0 && endCode&0b0001 == 0 {
-//			// top or top-right
-//			coords = append(coords, Coord{bounds[0].X - dx, bounds[1].Y + dy})
-//			endCode = 0b0110
-//		}
-//
-//		if startCode&endCode != 0 {
-//			return coords
-//		}
-//	}
-//}
+// clipBounds clips a path to the bounds. Coordinates outside of the bounds are removed, or moved to the corners of the bounds to avoid crossing the inner region, so that the direction and closedness of the path are maintained.
+func clipBounds(bounds Bounds, coords []Coord) []Coord {
+	var clipped []Coord
+	var prevCoord Coord
+	prevOutcode := uint8(0b1111)
+	for _, coord := range coords {
+		outcode := cohenSutherlandOutcode(bounds, coord)
+		if outcode == 0b0000 {
+			if prevOutcode != 0b0000 && prevOutcode != 0b1111 {
+				// cross bounds to inside
+				clipped = append(clipped, clipCoord(bounds, coord, prevCoord, prevOutcode))
+			}
+			clipped = append(clipped, coord)
+		} else if prevOutcode == 0b0000 {
+			// cross bounds to outside
+			clipped = append(clipped, clipCoord(bounds, prevCoord, coord, outcode))
+		} else if outcode != prevOutcode {
+			// both are outside but in different outcode regions
+			// put coordinates at the corners of the bounds to avoid crossing the inner region
+			corner := true
+			if outcode == 0b0101 {
+				// left bottom
+				coord = bounds[0]
+			} else if outcode == 0b1001 {
+				// left top
+				coord = Coord{bounds[0].X, bounds[1].Y}
+			} else if outcode == 0b0110 {
+				// right bottom
+				coord = Coord{bounds[1].X, bounds[0].Y}
+			} else if outcode == 0b1010 {
+				// right top
+				coord = bounds[1]
+			} else {
+				corner = false
+			}
+			if corner && (len(clipped) == 0 || clipped[len(clipped)-1] != coord) {
+				if 1 < len(clipped) && clipped[len(clipped)-2] == coord {
+					// optimise and avoid overlapping segments
+					clipped = clipped[:len(clipped)-1]
+				} else {
+					clipped = append(clipped, coord)
+				}
+			}
+		}
+		prevOutcode = outcode
+		prevCoord = coord
+	}
+	return clipped
+}
 
 // sortRelationWays finds and connects all ways in a relation
 //func sortRelationWays(ways []relationWay) []Coord {
@@ -279,75 +280,3 @@ func clipCoord(bounds Bounds, inner, outer Coord, outerOutcode uint8) Coord {
 //	}
 //	return polygon
 //}
-
-func appendCoords(a, b []Coord) []Coord {
-	for 0 < len(a) && 1 < len(b) {
-		if b[0] == a[len(a)-1] {
-			a = a[:len(a)-1]
-		} else if b[1] == a[len(a)-1] {
-			b = b[2:]
-		}
-	}
-	return append(a, b...)
-}
-
-// connectRelationWays finds and connects all ways in a relation
-func connectRelationWays(ways []relationWay) [][]Coord {
-	var polygons [][]Coord
-	handled := make([]bool, len(ways))
-	wayCoords := make([][]Coord, len(ways))
-	endpoints := make([][2]uint64, len(ways))
-	for j, way := range ways {
-		k := j
-		start, end := way.First, way.Last
-		if start == end {
-			// way closes itself, add directly
-			if 2 < len(way.Coords) {
-				polygons = append(polygons, way.Coords)
-			}
-			handled[j] = true
-			continue
-		}
-
-		wayCoords[j] = way.Coords[:len(way.Coords):len(way.Coords)]
-
-		// loop over remaining ways
-		var startConnected, endConnected bool
-		for i := 0; i < k; i++ {
-			if !handled[i] {
-				if !endConnected && endpoints[i][0] == end {
-					// current comes before other
-					wayCoords[i] = appendCoords(wayCoords[k], wayCoords[i])
-					handled[k] = true
-					if startConnected {
-						break
-					} else {
-						endConnected = true
-						k = i
-						i = 0
-						continue
-					}
-				} else if !startConnected && endpoints[i][1] == start {
-					// current comes after other
-					wayCoords[i] = appendCoords(wayCoords[i], wayCoords[k])
-					handled[k] = true
-					if endConnected {
-						break
-					} else {
-						startConnected = true
-						k = i
-						i = 0
-						continue
-					}
-				}
-			}
-		}
-		endpoints[j] = [2]uint64{start, end}
-	}
-	for i, coords := range wayCoords {
-		if !handled[i] && 2 < len(coords) {
-			polygons = append(polygons, coords)
-		}
-	}
-	return polygons
-}
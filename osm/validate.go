@@ -0,0 +1,439 @@
+package osm
+
+import (
+	"math"
+	"slices"
+)
+
+// validCoord returns true if the coordinate is finite.
+func validCoord(c Coord) bool {
+	return !math.IsNaN(c.X) && !math.IsNaN(c.Y) && !math.IsInf(c.X, 0) && !math.IsInf(c.Y, 0)
+}
+
+// Validate returns the OGC validity errors of the geometry with their locations: invalid coordinates, repeated consecutive coordinates, line strings and rings with too few points, unclosed rings, degenerate rings, self-intersections in and between rings, duplicate segments, holes outside of their outer ring, nested holes, and nested outer rings.
+func (g Geometry) Validate() []Problem {
+	var problems []Problem
+	add := func(typ ProblemType, coord Coord) {
+		problems = append(problems, Problem{Type: typ, ObjectType: g.Type, ID: g.ID, Coord: coord})
+	}
+	checkCoords := func(coords []Coord) {
+		for i, c := range coords {
+			if !validCoord(c) {
+				add(InvalidCoordinate, c)
+			} else if 0 < i && coords[i-1] == c {
+				add(RepeatedPoint, c)
+			}
+		}
+	}
+
+	for _, c := range g.Points {
+		if !validCoord(c) {
+			add(InvalidCoordinate, c)
+		}
+	}
+	for _, coords := range g.LineStrings {
+		checkCoords(coords)
+		if len(coords) < 2 || countDistinct(coords) < 2 {
+			add(TooFewPoints, firstCoord(coords))
+		}
+	}
+
+	var rings [][]Coord
+	for _, polygon := range g.Polygons {
+		for i, ring := range append([][]Coord{polygon.Outer}, polygon.Holes...) {
+			checkCoords(ring)
+			if len(ring) < 4 || countDistinct(ring) < 3 {
+				add(TooFewPoints, firstCoord(ring))
+				continue
+			} else if ring[0] != ring[len(ring)-1] {
+				add(UnclosedRing, ring[0])
+				continue
+			} else if degenerateRing(ring) {
+				add(DegeneratePolygon, ring[0])
+				continue
+			}
+
+			// ring touches itself
+			seen := make(map[Coord]bool, len(ring))
+			for _, c := range ring[:len(ring)-1] {
+				if seen[c] {
+					add(SelfIntersection, c)
+				}
+				seen[c] = true
+			}
+
+			if 0 < i {
+				if !ringInRing(ring, polygon.Outer) {
+					add(HoleOutsideShell, ring[0])
+				}
+				for j, hole := range polygon.Holes {
+					if j+1 != i && ringInRing(ring, hole) {
+						add(NestedHoles, ring[0])
+					}
+				}
+			}
+			rings = append(rings, ring)
+		}
+	}
+	for i, a := range g.Polygons {
+		for j, b := range g.Polygons {
+			if i != j && 3 < len(a.Outer) && 3 < len(b.Outer) && ringInRing(a.Outer, b.Outer) {
+				inHole := false
+				for _, hole := range b.Holes {
+					if 3 < len(hole) && ringInRing(a.Outer, hole) {
+						inHole = true
+						break
+					}
+				}
+				if !inHole {
+					add(NestedShells, a.Outer[0])
+				}
+			}
+		}
+	}
+
+	infinite := Bounds{{math.Inf(-1), math.Inf(-1)}, {math.Inf(1), math.Inf(1)}}
+	for _, problem := range ringProblems(rings, nil, infinite) {
+		add(problem.Type, problem.Coord)
+	}
+	return problems
+}
+
+// IsValid returns true if the geometry has no validity errors.
+func (g Geometry) IsValid() bool {
+	return len(g.Validate()) == 0
+}
+
+func countDistinct(coords []Coord) int {
+	seen := make(map[Coord]bool, len(coords))
+	for _, c := range coords {
+		seen[c] = true
+	}
+	return len(seen)
+}
+
+func firstCoord(coords []Coord) Coord {
+	if len(coords) == 0 {
+		return Coord{}
+	}
+	return coords[0]
+}
+
+// cleanCoords removes invalid and repeated consecutive coordinates.
+func cleanCoords(coords []Coord) []Coord {
+	cleaned := make([]Coord, 0, len(coords))
+	for _, c := range coords {
+		if validCoord(c) && (len(cleaned) == 0 || cleaned[len(cleaned)-1] != c) {
+			cleaned = append(cleaned, c)
+		}
+	}
+	return cleaned
+}
+
+// MakeValid returns a valid geometry. Invalid and repeated coordinates are removed, and line strings with fewer than two distinct coordinates are dropped. Polygons are repaired by noding all rings at their intersections and rebuilding the rings from the resulting planar graph, where an area is filled if it is within more outer rings than holes. Spikes, slivers, and collapsed rings are dropped.
+func (g Geometry) MakeValid() Geometry {
+	valid := g
+	valid.Points = nil
+	for _, c := range g.Points {
+		if validCoord(c) {
+			valid.Points = append(valid.Points, c)
+		}
+	}
+
+	valid.LineStrings = nil
+	for _, coords := range g.LineStrings {
+		if coords = cleanCoords(coords); 1 < len(coords) {
+			valid.LineStrings = append(valid.LineStrings, coords)
+		}
+	}
+
+	var outers, holes [][]Coord
+	for _, polygon := range g.Polygons {
+		for i, ring := range append([][]Coord{polygon.Outer}, polygon.Holes...) {
+			if ring = cleanCoords(ring); 2 < len(ring) {
+				ring = closeRing(ring)
+				if i == 0 {
+					outers = append(outers, ring)
+				} else {
+					holes = append(holes, ring)
+				}
+			}
+		}
+	}
+	valid.Polygons = buildArea(outers, holes)
+	return valid
+}
+
+// planarGraph is a graph of nodes and edges that do not intersect, where each edge consists of two half-edges with opposite directions. Half-edge 2*i is the i-th edge from A to B and half-edge 2*i+1 is from B to A.
+type planarGraph struct {
+	nodes []Coord
+	edges [][2]int // node indices
+	out   [][]int  // outgoing half-edges per node, sorted by angle CCW
+	pos   []int    // position of the half-edge in the outgoing list of its origin
+}
+
+func (g *planarGraph) origin(h int) int {
+	return g.edges[h/2][h%2]
+}
+
+func (g *planarGraph) dest(h int) int {
+	return g.edges[h/2][1-h%2]
+}
+
+// prev returns the preceding outgoing half-edge in CCW order around the origin of h, which is the next one in CW order.
+func (g *planarGraph) prev(h int) int {
+	out := g.out[g.origin(h)]
+	return out[(g.pos[h]+len(out)-1)%len(out)]
+}
+
+// next returns the next half-edge of the face on the left of h.
+func (g *planarGraph) next(h int) int {
+	return g.prev(h ^ 1)
+}
+
+// nodeSegments splits all segments of the rings at their mutual intersections, including touching endpoints and collinear overlaps, and returns the planar graph.
+func nodeSegments(rings [][]Coord) *planarGraph {
+	type segment struct {
+		a, b   Coord
+		splits []Coord
+	}
+	var segments []*segment
+	for _, ring := range rings {
+		for i := 0; i+1 < len(ring); i++ {
+			if ring[i] != ring[i+1] {
+				segments = append(segments, &segment{a: ring[i], b: ring[i+1]})
+			}
+		}
+	}
+
+	// find intersections using a sweep over the X-axis
+	onSegment := func(c, a, b Coord) bool {
+		// c is collinear with a and b
+		ab, ac := b.Sub(a), c.Sub(a)
+		t := (ac.X*ab.X + ac.Y*ab.Y) / (ab.X*ab.X + ab.Y*ab.Y)
+		return 0.0 < t && t < 1.0
+	}
+	sorted := slices.Clone(segments)
+	slices.SortFunc(sorted, func(s, t *segment) int {
+		if x0, x1 := math.Min(s.a.X, s.b.X), math.Min(t.a.X, t.b.X); x0 < x1 {
+			return -1
+		} else if x1 < x0 {
+			return 1
+		}
+		return 0
+	})
+	var active []*segment
+	for _, s := range sorted {
+		minX := math.Min(s.a.X, s.b.X)
+		j := 0
+		for _, t := range active {
+			if minX <= math.Max(t.a.X, t.b.X) {
+				active[j] = t
+				j++
+			}
+		}
+		active = active[:j]
+		for _, t := range active {
+			if math.Max(s.a.Y, s.b.Y) < math.Min(t.a.Y, t.b.Y) || math.Max(t.a.Y, t.b.Y) < math.Min(s.a.Y, s.b.Y) {
+				continue
+			}
+			sd, td := s.b.Sub(s.a), t.b.Sub(t.a)
+			d := t.a.Sub(s.a)
+			denom := sd.X*td.Y - sd.Y*td.X
+			if denom == 0.0 {
+				if d.X*sd.Y-d.Y*sd.X != 0.0 {
+					continue // parallel
+				}
+				// collinear, split at the endpoints of the other segment
+				for _, c := range []Coord{t.a, t.b} {
+					if onSegment(c, s.a, s.b) {
+						s.splits = append(s.splits, c)
+					}
+				}
+				for _, c := range []Coord{s.a, s.b} {
+					if onSegment(c, t.a, t.b) {
+						t.splits = append(t.splits, c)
+					}
+				}
+				continue
+			}
+			u := (d.X*td.Y - d.Y*td.X) / denom
+			v := (d.X*sd.Y - d.Y*sd.X) / denom
+			if u < 0.0 || 1.0 < u || v < 0.0 || 1.0 < v {
+				continue
+			}
+
+			// use existing endpoints for touching segments so that nodes coincide exactly
+			var c Coord
+			if u == 0.0 {
+				c = s.a
+			} else if u == 1.0 {
+				c = s.b
+			} else if v == 0.0 {
+				c = t.a
+			} else if v == 1.0 {
+				c = t.b
+			} else {
+				c = Coord{s.a.X + u*sd.X, s.a.Y + u*sd.Y}
+			}
+			if c != s.a && c != s.b {
+				s.splits = append(s.splits, c)
+			}
+			if c != t.a && c != t.b {
+				t.splits = append(t.splits, c)
+			}
+		}
+		active = append(active, s)
+	}
+
+	// build graph with unique edges
+	g := &planarGraph{}
+	nodes := map[Coord]int{}
+	node := func(c Coord) int {
+		if i, ok := nodes[c]; ok {
+			return i
+		}
+		nodes[c] = len(g.nodes)
+		g.nodes = append(g.nodes, c)
+		return len(g.nodes) - 1
+	}
+	edges := map[[2]int]bool{}
+	for _, s := range segments {
+		d := s.b.Sub(s.a)
+		slices.SortFunc(s.splits, func(p, q Coord) int {
+			tp := (p.X-s.a.X)*d.X + (p.Y-s.a.Y)*d.Y
+			tq := (q.X-s.a.X)*d.X + (q.Y-s.a.Y)*d.Y
+			if tp < tq {
+				return -1
+			} else if tq < tp {
+				return 1
+			}
+			return 0
+		})
+		coords := append(append([]Coord{s.a}, s.splits...), s.b)
+		for i := 0; i+1 < len(coords); i++ {
+			a, b := node(coords[i]), node(coords[i+1])
+			if a == b {
+				continue
+			} else if b < a {
+				a, b = b, a
+			}
+			if !edges[[2]int{a, b}] {
+				edges[[2]int{a, b}] = true
+				g.edges = append(g.edges, [2]int{a, b})
+			}
+		}
+	}
+
+	// sort outgoing half-edges by angle
+	g.out = make([][]int, len(g.nodes))
+	for e := range g.edges {
+		g.out[g.edges[e][0]] = append(g.out[g.edges[e][0]], 2*e)
+		g.out[g.edges[e][1]] = append(g.out[g.edges[e][1]], 2*e+1)
+	}
+	g.pos = make([]int, 2*len(g.edges))
+	for n, out := range g.out {
+		angle := func(h int) float64 {
+			d := g.nodes[g.dest(h)].Sub(g.nodes[n])
+			return math.Atan2(d.Y, d.X)
+		}
+		slices.SortFunc(out, func(h0, h1 int) int {
+			if a0, a1 := angle(h0), angle(h1); a0 < a1 {
+				return -1
+			} else if a1 < a0 {
+				return 1
+			}
+			return 0
+		})
+		for i, h := range out {
+			g.pos[h] = i
+		}
+	}
+	return g
+}
+
+// buildArea nodes the outer rings and holes, and rebuilds the rings of the area that is within more outer rings than holes. It returns valid polygons without spikes and slivers.
+func buildArea(outers, holes [][]Coord) []PolygonWithHoles {
+	if len(outers) == 0 {
+		return nil
+	}
+	g := nodeSegments(append(slices.Clone(outers), holes...))
+	if len(g.edges) == 0 {
+		return nil
+	}
+
+	bounds := ringBounds(g.nodes)
+	epsilon := 1e-9 * math.Max(bounds.W(), bounds.H())
+	inside := func(c Coord) bool {
+		n := 0
+		for _, ring := range outers {
+			if pointInRing(c, ring) {
+				n++
+			}
+		}
+		for _, ring := range holes {
+			if pointInRing(c, ring) {
+				n--
+			}
+		}
+		return 0 < n
+	}
+
+	// find faces on the left of each half-edge and decide whether they are filled, using a point just left of the longest edge of each face
+	face := make([]int, 2*len(g.edges))
+	for h := range face {
+		face[h] = -1
+	}
+	var filled []bool
+	for h := range face {
+		if face[h] != -1 {
+			continue
+		}
+		f := len(filled)
+		longest, length := h, 0.0
+		for k := h; face[k] == -1; k = g.next(k) {
+			face[k] = f
+			a, b := g.nodes[g.origin(k)], g.nodes[g.dest(k)]
+			if l := math.Hypot(b.X-a.X, b.Y-a.Y); length < l {
+				longest, length = k, l
+			}
+		}
+		a, b := g.nodes[g.origin(longest)], g.nodes[g.dest(longest)]
+		offset := math.Min(epsilon, 1e-6*length) / length
+		mid := Coord{(a.X+b.X)/2.0 - (b.Y-a.Y)*offset, (a.Y+b.Y)/2.0 + (b.X-a.X)*offset}
+		filled = append(filled, inside(mid))
+	}
+
+	// follow half-edges between filled and unfilled faces, keeping the filled face on the left
+	boundary := make([]bool, len(face))
+	for h := range face {
+		boundary[h] = filled[face[h]] && !filled[face[h^1]]
+	}
+	var rings [][]Coord
+	used := make([]bool, len(face))
+	for h := range face {
+		if !boundary[h] || used[h] {
+			continue
+		}
+		ring := []Coord{g.nodes[g.origin(h)]}
+		for k := h; !used[k]; {
+			used[k] = true
+			ring = append(ring, g.nodes[g.dest(k)])
+			k = g.next(k)
+			for !boundary[k] {
+				k = g.prev(k)
+			}
+		}
+
+		// drop slivers whose width is smaller than epsilon
+		perimeter := 0.0
+		for i := 0; i+1 < len(ring); i++ {
+			perimeter += math.Hypot(ring[i+1].X-ring[i].X, ring[i+1].Y-ring[i].Y)
+		}
+		if 3 < len(ring) && ring[0] == ring[len(ring)-1] && epsilon*perimeter < 2.0*math.Abs(ringArea(ring)) {
+			rings = append(rings, ring)
+		}
+	}
+	polygons, _ := nestRings(rings)
+	return polygons
+}
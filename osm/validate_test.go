@@ -0,0 +1,76 @@
+package osm
+
+import (
+	"math"
+	"testing"
+)
+
+func hasProblem(problems []Problem, typ ProblemType) bool {
+	for _, problem := range problems {
+		if problem.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidate(t *testing.T) {
+	square := []Coord{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	var tests = []struct {
+		name     string
+		geometry Geometry
+		problem  ProblemType
+	}{
+		{"invalid coordinate", Geometry{Points: []Coord{{math.NaN(), 0}}}, InvalidCoordinate},
+		{"too few points", Geometry{LineStrings: [][]Coord{{{1, 1}, {1, 1}}}}, TooFewPoints},
+		{"repeated point", Geometry{Polygons: []PolygonWithHoles{{Outer: []Coord{{0, 0}, {10, 0}, {10, 0}, {10, 10}, {0, 0}}}}}, RepeatedPoint},
+		{"unclosed ring", Geometry{Polygons: []PolygonWithHoles{{Outer: []Coord{{0, 0}, {10, 0}, {10, 10}, {0, 10}}}}}, UnclosedRing},
+		{"degenerate", Geometry{Polygons: []PolygonWithHoles{{Outer: []Coord{{0, 0}, {5, 0}, {10, 0}, {0, 0}}}}}, DegeneratePolygon},
+		{"bow-tie", Geometry{Polygons: []PolygonWithHoles{{Outer: []Coord{{0, 0}, {10, 10}, {10, 0}, {0, 10}, {0, 0}}}}}, SelfIntersection},
+		{"hole outside shell", Geometry{Polygons: []PolygonWithHoles{{Outer: square, Holes: [][]Coord{{{20, 20}, {20, 21}, {21, 21}, {20, 20}}}}}}, HoleOutsideShell},
+		{"nested holes", Geometry{Polygons: []PolygonWithHoles{{Outer: square, Holes: [][]Coord{{{1, 1}, {1, 9}, {9, 9}, {9, 1}, {1, 1}}, {{2, 2}, {2, 8}, {8, 8}, {8, 2}, {2, 2}}}}}}, NestedHoles},
+		{"nested shells", Geometry{Polygons: []PolygonWithHoles{{Outer: square}, {Outer: []Coord{{2, 2}, {8, 2}, {8, 8}, {2, 8}, {2, 2}}}}}, NestedShells},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := tt.geometry.Validate()
+			if !hasProblem(problems, tt.problem) {
+				t.Errorf("expected %v, got %v", tt.problem, problems)
+			}
+			if valid := tt.geometry.MakeValid(); !valid.IsValid() {
+				t.Errorf("MakeValid returned invalid geometry: %v", valid.Validate())
+			}
+		})
+	}
+
+	valid := Geometry{Polygons: []PolygonWithHoles{{Outer: square, Holes: [][]Coord{{{1, 1}, {1, 9}, {9, 9}, {9, 1}, {1, 1}}}}}}
+	if problems := valid.Validate(); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
+
+func TestMakeValid(t *testing.T) {
+	// bow-tie becomes two triangles
+	g := Geometry{Polygons: []PolygonWithHoles{{Outer: []Coord{{0, 0}, {10, 10}, {10, 0}, {0, 10}, {0, 0}}}}}
+	g = g.MakeValid()
+	if len(g.Polygons) != 2 || polygonsArea(g.Polygons) != 50.0 {
+		t.Errorf("expected two triangles, got %v", g.Polygons)
+	}
+
+	// spike is removed
+	g = Geometry{Polygons: []PolygonWithHoles{{Outer: []Coord{{0, 0}, {10, 0}, {20, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}}}
+	g = g.MakeValid()
+	if len(g.Polygons) != 1 || len(g.Polygons[0].Outer) != 5 || polygonsArea(g.Polygons) != 100.0 {
+		t.Errorf("expected square, got %v", g.Polygons)
+	}
+
+	// overlapping shells are merged and the hole is kept
+	g = Geometry{Polygons: []PolygonWithHoles{
+		{Outer: []Coord{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}, Holes: [][]Coord{{{1, 1}, {1, 2}, {2, 2}, {2, 1}, {1, 1}}}},
+		{Outer: []Coord{{5, 0}, {15, 0}, {15, 10}, {5, 10}, {5, 0}}},
+	}}
+	g = g.MakeValid()
+	if len(g.Polygons) != 1 || len(g.Polygons[0].Holes) != 1 || polygonsArea(g.Polygons) != 149.0 {
+		t.Errorf("expected merged polygon with hole, got %v", g.Polygons)
+	}
+}
@@ -0,0 +1,305 @@
+package osm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/klauspost/compress/zlib"
+)
+
+// maxBlockObjects is the maximum number of objects per PrimitiveBlock, as recommended by the specification.
+const maxBlockObjects = 8000
+
+// Writer writes objects to the OSM PBF file format. Objects must be written in the order of nodes, ways, and then relations, and must be ordered by ascending ID within each type, as announced by the Sort.Type_then_ID feature in the header. Objects are buffered into blocks, so Close must be called to write out the remaining objects.
+type Writer struct {
+	w      io.Writer
+	bounds Bounds
+	err    error
+
+	header bool
+	typ    Type
+	id     uint64 // last written ID of typ
+	n      int
+
+	// block buffers
+	strings map[string]uint32
+	table   []string
+	ids     []uint64
+	lats    []int64
+	lons    []int64
+	keyVals []uint32
+	group   []byte
+
+	buf bytes.Buffer
+	zw  *zlib.Writer
+}
+
+// NewWriter returns a new PBF writer. The bounds are written to the header unless they are zero. Wrapped bounds are written as spanning all longitudes, since the header cannot cross the antimeridian.
+func NewWriter(w io.Writer, bounds Bounds) *Writer {
+	if bounds.IsWrapped() {
+		bounds[0].X, bounds[1].X = -180.0, 180.0
+	}
+	return &Writer{
+		w:       w,
+		bounds:  bounds,
+		strings: map[string]uint32{},
+	}
+}
+
+func appendKey(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = appendKey(b, field, 0)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendSintField(b []byte, field int, v int64) []byte {
+	b = appendKey(b, field, 0)
+	return binary.AppendVarint(b, v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = appendKey(b, field, 2)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendPackedUint32s(b []byte, field int, vs []uint32) []byte {
+	var packed []byte
+	for _, v := range vs {
+		packed = binary.AppendUvarint(packed, uint64(v))
+	}
+	return appendBytesField(b, field, packed)
+}
+
+func appendPackedDeltas(b []byte, field int, vs []int64) []byte {
+	var prev int64
+	var packed []byte
+	for _, v := range vs {
+		packed = binary.AppendVarint(packed, v-prev)
+		prev = v
+	}
+	return appendBytesField(b, field, packed)
+}
+
+func (w *Writer) stringIndex(s string) uint32 {
+	if len(w.table) == 0 {
+		w.table = append(w.table, "") // index zero is reserved as delimiter
+	}
+	if index, ok := w.strings[s]; ok {
+		return index
+	}
+	index := uint32(len(w.table))
+	w.strings[s] = index
+	w.table = append(w.table, s)
+	return index
+}
+
+func (w *Writer) appendTags(b []byte, tags Tags) []byte {
+	if len(tags) == 0 {
+		return b
+	}
+	keys := make([]uint32, len(tags))
+	vals := make([]uint32, len(tags))
+	for i, tag := range tags {
+		keys[i] = w.stringIndex(tag.Key)
+		vals[i] = w.stringIndex(tag.Val)
+	}
+	b = appendPackedUint32s(b, 2, keys)
+	return appendPackedUint32s(b, 3, vals)
+}
+
+// writeBlob writes a zlib compressed blob with its header.
+func (w *Writer) writeBlob(typ string, data []byte) error {
+	w.buf.Reset()
+	if w.zw == nil {
+		w.zw = zlib.NewWriter(&w.buf)
+	} else {
+		w.zw.Reset(&w.buf)
+	}
+	if _, err := w.zw.Write(data); err != nil {
+		return err
+	} else if err := w.zw.Close(); err != nil {
+		return err
+	}
+
+	var blob []byte
+	blob = appendVarintField(blob, 2, uint64(len(data))) // raw_size
+	blob = appendBytesField(blob, 3, w.buf.Bytes())      // zlib_data
+	if maxBlobSize < len(blob) {
+		return fmt.Errorf("blob is too big")
+	}
+
+	var header []byte
+	header = appendBytesField(header, 1, []byte(typ))        // type
+	header = appendVarintField(header, 3, uint64(len(blob))) // datasize
+
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(header)))
+	if _, err := w.w.Write(length[:]); err != nil {
+		return err
+	} else if _, err := w.w.Write(header); err != nil {
+		return err
+	} else if _, err := w.w.Write(blob); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (w *Writer) writeHeader() error {
+	var block []byte
+	if w.bounds != (Bounds{}) {
+		var bbox []byte
+		bbox = appendSintField(bbox, 1, int64(math.Round(w.bounds[0].X*1e9))) // left
+		bbox = appendSintField(bbox, 2, int64(math.Round(w.bounds[1].X*1e9))) // right
+		bbox = appendSintField(bbox, 3, int64(math.Round(w.bounds[1].Y*1e9))) // top
+		bbox = appendSintField(bbox, 4, int64(math.Round(w.bounds[0].Y*1e9))) // bottom
+		block = appendBytesField(block, 1, bbox)
+	}
+	block = appendBytesField(block, 4, []byte("OsmSchema-V0.6")) // required_features
+	block = appendBytesField(block, 4, []byte("DenseNodes"))
+	block = appendBytesField(block, 5, []byte("Sort.Type_then_ID"))        // optional_features
+	block = appendBytesField(block, 16, []byte("github.com/tdewolff/geo")) // writingprogram
+	return w.writeBlob("OSMHeader", block)
+}
+
+// flush writes the buffered objects as a PrimitiveBlock.
+func (w *Writer) flush() error {
+	if w.err != nil {
+		return w.err
+	} else if !w.header {
+		w.header = true
+		if w.err = w.writeHeader(); w.err != nil {
+			return w.err
+		}
+	}
+	if w.n == 0 {
+		return nil
+	}
+
+	var group []byte
+	if w.typ == NodeType {
+		var dense []byte
+		ids := make([]int64, len(w.ids))
+		for i, id := range w.ids {
+			ids[i] = int64(id)
+		}
+		dense = appendPackedDeltas(dense, 1, ids)    // id
+		dense = appendPackedDeltas(dense, 8, w.lats) // lat
+		dense = appendPackedDeltas(dense, 9, w.lons) // lon
+		dense = appendPackedUint32s(dense, 10, w.keyVals)
+		group = appendBytesField(group, 2, dense) // dense
+	} else {
+		group = w.group
+	}
+
+	if len(w.table) == 0 {
+		w.table = append(w.table, "")
+	}
+	var table []byte
+	for _, s := range w.table {
+		table = appendBytesField(table, 1, []byte(s))
+	}
+	var block []byte
+	block = appendBytesField(block, 1, table) // stringtable
+	block = appendBytesField(block, 2, group) // primitivegroup
+	w.err = w.writeBlob("OSMData", block)
+
+	clear(w.strings)
+	w.table = w.table[:0]
+	w.ids = w.ids[:0]
+	w.lats = w.lats[:0]
+	w.lons = w.lons[:0]
+	w.keyVals = w.keyVals[:0]
+	w.group = w.group[:0]
+	w.n = 0
+	return w.err
+}
+
+// next prepares the buffers for an object of the given type and ID, flushing the current block if it is full or of another type.
+func (w *Writer) next(typ Type, id uint64) error {
+	if w.err != nil {
+		return w.err
+	} else if typ < w.typ {
+		return fmt.Errorf("%v written after %v", typ, w.typ)
+	} else if typ == w.typ && (w.header || 0 < w.n) && id <= w.id {
+		return fmt.Errorf("%v %v written after %v %v", typ, id, typ, w.id)
+	} else if 0 < w.n && (typ != w.typ || maxBlockObjects <= w.n) {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	w.typ = typ
+	w.id = id
+	w.n++
+	return nil
+}
+
+// WriteNode writes a node.
+func (w *Writer) WriteNode(node Node) error {
+	if err := w.next(NodeType, node.ID); err != nil {
+		return err
+	}
+	w.ids = append(w.ids, node.ID)
+	w.lats = append(w.lats, int64(math.Round(node.Lat*1e7)))
+	w.lons = append(w.lons, int64(math.Round(node.Lon*1e7)))
+	for _, tag := range node.Tags {
+		w.keyVals = append(w.keyVals, w.stringIndex(tag.Key), w.stringIndex(tag.Val))
+	}
+	w.keyVals = append(w.keyVals, 0)
+	return nil
+}
+
+// WriteWay writes a way.
+func (w *Writer) WriteWay(way Way) error {
+	if err := w.next(WayType, way.ID); err != nil {
+		return err
+	}
+	var msg []byte
+	msg = appendVarintField(msg, 1, way.ID) // id
+	msg = w.appendTags(msg, way.Tags)
+	if 0 < len(way.Refs) {
+		refs := make([]int64, len(way.Refs))
+		for i, ref := range way.Refs {
+			refs[i] = int64(ref)
+		}
+		msg = appendPackedDeltas(msg, 8, refs) // refs
+	}
+	w.group = appendBytesField(w.group, 3, msg) // ways
+	return nil
+}
+
+// WriteRelation writes a relation.
+func (w *Writer) WriteRelation(relation Relation) error {
+	if err := w.next(RelationType, relation.ID); err != nil {
+		return err
+	}
+	var msg []byte
+	msg = appendVarintField(msg, 1, relation.ID) // id
+	msg = w.appendTags(msg, relation.Tags)
+	if 0 < len(relation.Members) {
+		roles := make([]uint32, len(relation.Members))
+		ids := make([]int64, len(relation.Members))
+		types := make([]uint32, len(relation.Members))
+		for i, member := range relation.Members {
+			roles[i] = w.stringIndex(member.Role)
+			ids[i] = int64(member.ID)
+			types[i] = uint32(member.Type)
+		}
+		msg = appendPackedUint32s(msg, 8, roles) // roles_sid
+		msg = appendPackedDeltas(msg, 9, ids)    // memids
+		msg = appendPackedUint32s(msg, 10, types)
+	}
+	w.group = appendBytesField(w.group, 4, msg) // relations
+	return nil
+}
+
+// Close writes out the remaining objects. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	return w.flush()
+}
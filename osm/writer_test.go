@@ -0,0 +1,150 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+)
+
+var testNodes = []Node{
+	{ID: 1, Lon: 5.0, Lat: 5.0, Tags: Tags{{"name", "a"}}},
+	{ID: 2, Lon: 15.0, Lat: 5.0},
+	{ID: 3, Lon: 20.0, Lat: 5.0},
+	{ID: 4, Lon: -5.0, Lat: -5.0},
+}
+
+var testWays = []Way{
+	{ID: 10, Refs: []uint64{1, 2}, Tags: Tags{{"highway", "residential"}}},
+	{ID: 11, Refs: []uint64{2, 3}},
+	{ID: 12, Refs: []uint64{3, 4}},
+}
+
+var testRelations = []Relation{
+	{ID: 20, Members: []Member{{WayType, 10, "outer"}, {WayType, 12, "outer"}}, Tags: Tags{{"type", "multipolygon"}}},
+	{ID: 21, Members: []Member{{WayType, 11, ""}}, Tags: Tags{{"type", "route"}}},
+	{ID: 22, Members: []Member{{RelationType, 20, ""}, {NodeType, 3, "label"}}},
+}
+
+func writeTestPBF(t *testing.T, nodes []Node, ways []Way, relations []Relation) []byte {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Bounds{{-10.0, -10.0}, {30.0, 10.0}})
+	for _, node := range nodes {
+		if err := w.WriteNode(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, way := range ways {
+		if err := w.WriteWay(way); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, relation := range relations {
+		if err := w.WriteRelation(relation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func parseTestPBF(t *testing.T, b []byte) ([]Node, []Way, []Relation) {
+	var mu sync.Mutex
+	var nodes []Node
+	var ways []Way
+	var relations []Relation
+	z := NewParser(bytes.NewReader(b))
+	err := z.Parse(context.Background(), func(node Node) {
+		node.Own()
+		mu.Lock()
+		nodes = append(nodes, node)
+		mu.Unlock()
+	}, func(way Way) {
+		way.Own()
+		mu.Lock()
+		ways = append(ways, way)
+		mu.Unlock()
+	}, func(relation Relation) {
+		relation.Own()
+		mu.Lock()
+		relations = append(relations, relation)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(nodes, func(a, b Node) int { return int(a.ID) - int(b.ID) })
+	slices.SortFunc(ways, func(a, b Way) int { return int(a.ID) - int(b.ID) })
+	slices.SortFunc(relations, func(a, b Relation) int { return int(a.ID) - int(b.ID) })
+	return nodes, ways, relations
+}
+
+func TestWriter(t *testing.T) {
+	nodes, ways, relations := parseTestPBF(t, writeTestPBF(t, testNodes, testWays, testRelations))
+	if len(nodes) != len(testNodes) {
+		t.Fatalf("expected %v nodes, got %v", len(testNodes), len(nodes))
+	}
+	for i, node := range nodes {
+		if node.ID != testNodes[i].ID || 1e-7 < math.Abs(node.Lon-testNodes[i].Lon) || 1e-7 < math.Abs(node.Lat-testNodes[i].Lat) || len(node.Tags) != len(testNodes[i].Tags) {
+			t.Errorf("expected node %v, got %v", testNodes[i], node)
+		} else if 0 < len(node.Tags) && !reflect.DeepEqual(node.Tags, testNodes[i].Tags) {
+			t.Errorf("expected node tags %v, got %v", testNodes[i].Tags, node.Tags)
+		}
+	}
+	for i, way := range ways {
+		if way.ID != testWays[i].ID || !slices.Equal(way.Refs, testWays[i].Refs) || len(way.Tags) != len(testWays[i].Tags) {
+			t.Errorf("expected way %v, got %v", testWays[i], way)
+		}
+	}
+	for i, relation := range relations {
+		if relation.ID != testRelations[i].ID || !slices.Equal(relation.Members, testRelations[i].Members) || len(relation.Tags) != len(testRelations[i].Tags) {
+			t.Errorf("expected relation %v, got %v", testRelations[i], relation)
+		}
+	}
+
+	// nodes written after ways
+	w := NewWriter(&bytes.Buffer{}, Bounds{})
+	if err := w.WriteWay(testWays[0]); err != nil {
+		t.Fatal(err)
+	} else if err := w.WriteNode(testNodes[0]); err == nil {
+		t.Errorf("expected error for node written after way")
+	}
+
+	// nodes not ordered by ID
+	w = NewWriter(&bytes.Buffer{}, Bounds{})
+	if err := w.WriteNode(Node{ID: 2}); err != nil {
+		t.Fatal(err)
+	} else if err := w.WriteNode(Node{ID: 1}); err == nil {
+		t.Errorf("expected error for node with lower ID")
+	}
+	w = NewWriter(&bytes.Buffer{}, Bounds{})
+	if err := w.WriteWay(Way{ID: 2}); err != nil {
+		t.Fatal(err)
+	} else if err := w.WriteWay(Way{ID: 2}); err == nil {
+		t.Errorf("expected error for duplicate way ID")
+	} else if err := w.WriteRelation(Relation{ID: 1}); err != nil {
+		t.Errorf("unexpected error for relation after way: %v", err)
+	}
+}
+
+func TestWriterBlocks(t *testing.T) {
+	nodes := make([]Node, 2*maxBlockObjects+1)
+	for i := range nodes {
+		nodes[i] = Node{ID: uint64(i + 1), Lon: float64(i) * 1e-4, Lat: -float64(i) * 1e-4}
+	}
+	parsed, _, _ := parseTestPBF(t, writeTestPBF(t, nodes, nil, nil))
+	if len(parsed) != len(nodes) {
+		t.Fatalf("expected %v nodes, got %v", len(nodes), len(parsed))
+	}
+	for i, node := range parsed {
+		if node.ID != nodes[i].ID || 1e-7 < math.Abs(node.Lon-nodes[i].Lon) || 1e-7 < math.Abs(node.Lat-nodes[i].Lat) {
+			t.Errorf("expected node %v, got %v", nodes[i], node)
+			break
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package geo contains map projections for geographic coordinates. Longitudes and latitudes are in degrees and projected coordinates are in meters.
+package geo
+
+import (
+	"math"
+)
+
+// Projector converts a longitude and latitude in degrees to projected coordinates.
+type Projector func(float64, float64) (float64, float64)
+
+// Projection is a map projection with forward and inverse transforms. Its Forward method can be passed to osm.Bounds.Project.
+type Projection interface {
+	// Forward converts a longitude and latitude in degrees to projected coordinates in meters.
+	Forward(float64, float64) (float64, float64)
+
+	// Inverse converts projected coordinates in meters to a longitude and latitude in degrees.
+	Inverse(float64, float64) (float64, float64)
+}
+
+// Ellipsoid is a reference ellipsoid of the Earth defined by its semi-major axis in meters and its flattening.
+type Ellipsoid struct {
+	A, F float64
+}
+
+var (
+	// WGS84 is the ellipsoid used by GPS and OpenStreetMap.
+	WGS84 = Ellipsoid{6378137.0, 1.0 / 298.257223563}
+	// GRS80 is the ellipsoid used by ETRS89 and NAD83.
+	GRS80 = Ellipsoid{6378137.0, 1.0 / 298.257222101}
+	// Sphere is a sphere with the mean radius of the Earth.
+	Sphere = Ellipsoid{6371008.8, 0.0}
+)
+
+// B returns the semi-minor axis.
+func (e Ellipsoid) B() float64 {
+	return e.A * (1.0 - e.F)
+}
+
+// E2 returns the square of the first eccentricity.
+func (e Ellipsoid) E2() float64 {
+	return e.F * (2.0 - e.F)
+}
+
+// E returns the first eccentricity.
+func (e Ellipsoid) E() float64 {
+	return math.Sqrt(e.E2())
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180.0
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180.0 / math.Pi
+}
+
+// normalizeLongitude returns the longitude in radians within [-π,π].
+func normalizeLongitude(lon float64) float64 {
+	if lon < -math.Pi || math.Pi < lon {
+		lon = math.Remainder(lon, 2.0*math.Pi)
+	}
+	return lon
+}
+
+// conformalT returns Snyder's t function of the latitude, used by conformal projections.
+func conformalT(lat, e float64) float64 {
+	sin := e * math.Sin(lat)
+	return math.Tan(math.Pi/4.0-lat/2.0) / math.Pow((1.0-sin)/(1.0+sin), e/2.0)
+}
+
+// conformalLatitude returns the latitude for Snyder's t function by iteration.
+func conformalLatitude(t, e float64) float64 {
+	lat := math.Pi/2.0 - 2.0*math.Atan(t)
+	for i := 0; i < 15; i++ {
+		sin := e * math.Sin(lat)
+		next := math.Pi/2.0 - 2.0*math.Atan(t*math.Pow((1.0-sin)/(1.0+sin), e/2.0))
+		if math.Abs(next-lat) < 1e-14 {
+			return next
+		}
+		lat = next
+	}
+	return lat
+}
+
+// scaleM returns Snyder's m function of the latitude.
+func scaleM(lat, e2 float64) float64 {
+	sin := math.Sin(lat)
+	return math.Cos(lat) / math.Sqrt(1.0-e2*sin*sin)
+}
+
+// WebMercator is the spherical Mercator projection used by web maps (EPSG:3857), using the semi-major axis of WGS84 as the radius. Latitudes are clamped to ±85.0511°, which makes the map square.
+type WebMercator struct{}
+
+// WebMercatorMaxLatitude is the maximum latitude of the WebMercator projection.
+const WebMercatorMaxLatitude = 85.05112877980659
+
+func (WebMercator) Forward(lon, lat float64) (float64, float64) {
+	lat = math.Max(-WebMercatorMaxLatitude, math.Min(lat, WebMercatorMaxLatitude))
+	x := WGS84.A * radians(lon)
+	y := WGS84.A * math.Log(math.Tan(math.Pi/4.0+radians(lat)/2.0))
+	return x, y
+}
+
+func (WebMercator) Inverse(x, y float64) (float64, float64) {
+	lon := degrees(x / WGS84.A)
+	lat := degrees(math.Pi/2.0 - 2.0*math.Atan(math.Exp(-y/WGS84.A)))
+	return lon, lat
+}
+
+// Equirectangular is the equirectangular (plate carrée) projection on a sphere with the radius of the semi-major axis, where Lat1 is the standard parallel with true scale.
+type Equirectangular struct {
+	Ellipsoid
+	Lon0, Lat1 float64
+}
+
+// NewEquirectangular returns an equirectangular projection centred at lon0 with true scale at the standard parallel lat1.
+func NewEquirectangular(ellipsoid Ellipsoid, lon0, lat1 float64) *Equirectangular {
+	return &Equirectangular{ellipsoid, lon0, lat1}
+}
+
+func (p *Equirectangular) Forward(lon, lat float64) (float64, float64) {
+	x := p.A * normalizeLongitude(radians(lon-p.Lon0)) * math.Cos(radians(p.Lat1))
+	y := p.A * radians(lat)
+	return x, y
+}
+
+func (p *Equirectangular) Inverse(x, y float64) (float64, float64) {
+	lon := p.Lon0 + degrees(x/(p.A*math.Cos(radians(p.Lat1))))
+	lat := degrees(y / p.A)
+	return lon, lat
+}
@@ -0,0 +1,87 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+// ellipsoids used in the worked examples of Snyder, Map Projections: A Working Manual (1987)
+var (
+	clarke1866    = Ellipsoid{6378206.4, 1.0 - math.Sqrt(1.0-0.00676866)}
+	international = Ellipsoid{6378388.0, 1.0 / 297.0}
+)
+
+func TestProjections(t *testing.T) {
+	tests := []struct {
+		name     string
+		proj     Projection
+		lon, lat float64
+		x, y     float64
+		tol      float64
+	}{
+		{"WebMercator", WebMercator{}, 180.0, WebMercatorMaxLatitude, 20037508.342789244, 20037508.342789244, 1e-6},
+		{"Equirectangular", NewEquirectangular(Sphere, 0.0, 60.0), 90.0, 45.0, Sphere.A * math.Pi / 4.0, Sphere.A * math.Pi / 4.0, 1e-6},
+		{"TransverseMercator", NewTransverseMercator(clarke1866, -75.0, 0.0, 0.9996), -73.5, 40.5, 127106.5, 4484124.4, 0.5},
+		{"UTM", UTM(31, true), 3.0, 0.0, 500000.0, 0.0, 1e-6},
+		{"LambertConformalConic", NewLambertConformalConic(clarke1866, -96.0, 23.0, 33.0, 45.0), -75.0, 35.0, 1894410.9, 1564649.5, 0.1},
+		{"AlbersEqualArea", NewAlbersEqualArea(clarke1866, -96.0, 23.0, 29.5, 45.5), -75.0, 35.0, 1885472.7, 1535925.0, 0.1},
+		{"PolarStereographic", NewPolarStereographic(international, -100.0, -71.0), 150.0, -75.0, -1540033.6, -560526.4, 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := tt.proj.Forward(tt.lon, tt.lat)
+			if tt.tol < math.Abs(x-tt.x) || tt.tol < math.Abs(y-tt.y) {
+				t.Errorf("expected (%v,%v), got (%v,%v)", tt.x, tt.y, x, y)
+			}
+		})
+	}
+}
+
+func TestProjectionsInverse(t *testing.T) {
+	projs := map[string]Projection{
+		"WebMercator":           WebMercator{},
+		"Equirectangular":       NewEquirectangular(WGS84, 5.0, 52.0),
+		"TransverseMercator":    NewTransverseMercator(GRS80, 5.0, 52.0, 0.9996),
+		"Lambert":               TransverseMercatorLambert(5.0, 0.9996),
+		"UTM":                   UTMProjection(6.57, 53.16),
+		"LambertConformalConic": NewLambertConformalConic(GRS80, 3.0, 46.5, 44.0, 49.0),
+		"AlbersEqualArea":       NewAlbersEqualArea(WGS84, -96.0, 23.0, 29.5, 45.5),
+		"AlbersEqualAreaSphere": NewAlbersEqualArea(Sphere, -96.0, 23.0, 29.5, 45.5),
+		"PolarStereographic":    NewPolarStereographic(WGS84, -45.0, 70.0),
+		"UPS":                   UPS(false),
+	}
+	coords := [][2]float64{{6.57, 53.16}, {2.0, 48.0}, {-10.0, 40.0}}
+	for name, proj := range projs {
+		for _, coord := range coords {
+			lon, lat := coord[0], coord[1]
+			if name == "UPS" {
+				lat = -lat
+			}
+			x, y := proj.Forward(lon, lat)
+			lon2, lat2 := proj.Inverse(x, y)
+			if 1e-8 < math.Abs(lon2-lon) || 1e-8 < math.Abs(lat2-lat) {
+				t.Errorf("%v: expected (%v,%v), got (%v,%v)", name, lon, lat, lon2, lat2)
+			}
+		}
+	}
+}
+
+func TestUTMZone(t *testing.T) {
+	tests := []struct {
+		lon, lat float64
+		zone     int
+		north    bool
+	}{
+		{6.57, 53.16, 32, true},
+		{-180.0, 0.0, 1, true},
+		{180.0, -10.0, 1, false},
+		{5.0, 60.0, 32, true},  // Norway
+		{10.0, 78.0, 33, true}, // Svalbard
+		{-43.2, -22.9, 23, false},
+	}
+	for _, tt := range tests {
+		if zone, north := UTMZone(tt.lon, tt.lat); zone != tt.zone || north != tt.north {
+			t.Errorf("UTMZone(%v,%v): expected %v %v, got %v %v", tt.lon, tt.lat, tt.zone, tt.north, zone, north)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package geo
+
+import (
+	"math"
+)
+
+// PolarStereographic is the ellipsoidal Polar Stereographic projection centred on the north pole if LatTs is positive and on the south pole otherwise. LatTs is the latitude of true scale, and if it is at the pole, K0 is the scale factor at the pole. Lon0 is the longitude pointing down from the north pole or up from the south pole, and X0 and Y0 are the false easting and northing.
+type PolarStereographic struct {
+	Ellipsoid
+	Lon0, LatTs float64
+	K0          float64
+	X0, Y0      float64
+
+	e, sign, k float64
+}
+
+// NewPolarStereographic returns a Polar Stereographic projection with true scale at latitude latTs, such as 70 for EPSG:3413 or -71 for EPSG:3031, and central longitude lon0.
+func NewPolarStereographic(ellipsoid Ellipsoid, lon0, latTs float64) *PolarStereographic {
+	return newPolarStereographic(ellipsoid, lon0, latTs, 1.0)
+}
+
+// UPS returns the Universal Polar Stereographic projection on WGS84 for the north or south pole.
+func UPS(north bool) *PolarStereographic {
+	latTs := -90.0
+	if north {
+		latTs = 90.0
+	}
+	p := newPolarStereographic(WGS84, 0.0, latTs, 0.994)
+	p.X0, p.Y0 = 2000000.0, 2000000.0
+	return p
+}
+
+func newPolarStereographic(ellipsoid Ellipsoid, lon0, latTs, k0 float64) *PolarStereographic {
+	p := &PolarStereographic{
+		Ellipsoid: ellipsoid,
+		Lon0:      lon0,
+		LatTs:     latTs,
+		K0:        k0,
+		e:         ellipsoid.E(),
+		sign:      1.0,
+	}
+	if latTs < 0.0 {
+		p.sign = -1.0
+	}
+	if phi := radians(math.Abs(latTs)); phi == math.Pi/2.0 {
+		p.k = 2.0 * k0 / math.Sqrt(math.Pow(1.0+p.e, 1.0+p.e)*math.Pow(1.0-p.e, 1.0-p.e))
+	} else {
+		p.k = scaleM(phi, ellipsoid.E2()) / conformalT(phi, p.e)
+	}
+	return p
+}
+
+func (p *PolarStereographic) Forward(lon, lat float64) (float64, float64) {
+	// the south pole is projected as the north pole with negated coordinates
+	rho := p.A * p.k * conformalT(p.sign*radians(lat), p.e)
+	theta := p.sign * normalizeLongitude(radians(lon-p.Lon0))
+	x := p.sign * rho * math.Sin(theta)
+	y := -p.sign * rho * math.Cos(theta)
+	return p.X0 + x, p.Y0 + y
+}
+
+func (p *PolarStereographic) Inverse(x, y float64) (float64, float64) {
+	x, y = p.sign*(x-p.X0), p.sign*(y-p.Y0)
+	rho := math.Hypot(x, y)
+	lat := conformalLatitude(rho/(p.A*p.k), p.e)
+	lon := p.Lon0
+	if rho != 0.0 {
+		lon += p.sign * degrees(math.Atan2(x, -y))
+	}
+	return lon, p.sign * degrees(lat)
+}
@@ -0,0 +1,121 @@
+package geo
+
+import (
+	"math"
+)
+
+// TransverseMercator is the ellipsoidal Transverse Mercator projection using the Krüger series, which is accurate to within a millimeter up to a few thousand kilometers from the central meridian Lon0. K0 is the scale factor on the central meridian, Lat0 is the latitude of origin, and X0 and Y0 are the false easting and northing.
+type TransverseMercator struct {
+	Ellipsoid
+	Lon0, Lat0 float64
+	K0         float64
+	X0, Y0     float64
+
+	e, a        float64    // eccentricity and rectifying radius
+	alpha, beta [3]float64 // series coefficients
+	delta       [3]float64
+	m0          float64 // northing of the latitude of origin
+}
+
+// NewTransverseMercator returns a Transverse Mercator projection with central meridian lon0, latitude of origin lat0, and scale factor k0.
+func NewTransverseMercator(ellipsoid Ellipsoid, lon0, lat0, k0 float64) *TransverseMercator {
+	p := &TransverseMercator{
+		Ellipsoid: ellipsoid,
+		Lon0:      lon0,
+		Lat0:      lat0,
+		K0:        k0,
+	}
+	n := ellipsoid.F / (2.0 - ellipsoid.F)
+	n2, n3 := n*n, n*n*n
+	p.e = ellipsoid.E()
+	p.a = ellipsoid.A / (1.0 + n) * (1.0 + n2/4.0 + n2*n2/64.0)
+	p.alpha = [3]float64{n/2.0 - 2.0*n2/3.0 + 5.0*n3/16.0, 13.0*n2/48.0 - 3.0*n3/5.0, 61.0 * n3 / 240.0}
+	p.beta = [3]float64{n/2.0 - 2.0*n2/3.0 + 37.0*n3/96.0, n2/48.0 + n3/15.0, 17.0 * n3 / 480.0}
+	p.delta = [3]float64{2.0*n - 2.0*n2/3.0 - 2.0*n3, 7.0*n2/3.0 - 8.0*n3/5.0, 56.0 * n3 / 15.0}
+	_, p.m0 = p.forward(0.0, radians(lat0))
+	return p
+}
+
+// TransverseMercatorLambert returns Lambert's spherical Transverse Mercator projection with central meridian lon0 and scale factor k0, on a sphere with the mean radius of the Earth.
+func TransverseMercatorLambert(lon0, k0 float64) *TransverseMercator {
+	return NewTransverseMercator(Sphere, lon0, 0.0, k0)
+}
+
+// UTM returns the Universal Transverse Mercator projection on WGS84 for the zone (1–60) and hemisphere.
+func UTM(zone int, north bool) *TransverseMercator {
+	p := NewTransverseMercator(WGS84, float64(6*zone-183), 0.0, 0.9996)
+	p.X0 = 500000.0
+	if !north {
+		p.Y0 = 10000000.0
+	}
+	return p
+}
+
+// UTMZone returns the UTM zone and hemisphere of the coordinate, taking into account the exceptions for Norway and Svalbard.
+func UTMZone(lon, lat float64) (int, bool) {
+	lon = degrees(normalizeLongitude(radians(lon)))
+	zone := int(math.Floor((lon+180.0)/6.0)) + 1
+	if 60 < zone {
+		zone = 1
+	}
+	if 56.0 <= lat && lat < 64.0 && 3.0 <= lon && lon < 12.0 {
+		zone = 32 // south-west Norway
+	} else if 72.0 <= lat && lat < 84.0 && 0.0 <= lon && lon < 42.0 {
+		// Svalbard
+		if lon < 9.0 {
+			zone = 31
+		} else if lon < 21.0 {
+			zone = 33
+		} else if lon < 33.0 {
+			zone = 35
+		} else {
+			zone = 37
+		}
+	}
+	return zone, 0.0 <= lat
+}
+
+// UTMProjection returns the UTM projection of the zone that contains the coordinate.
+func UTMProjection(lon, lat float64) *TransverseMercator {
+	return UTM(UTMZone(lon, lat))
+}
+
+// forward returns the unscaled easting and northing for a longitude difference and latitude in radians.
+func (p *TransverseMercator) forward(dlon, lat float64) (float64, float64) {
+	sin := math.Sin(lat)
+	t := math.Sinh(math.Atanh(sin) - p.e*math.Atanh(p.e*sin))
+	xi := math.Atan2(t, math.Cos(dlon))
+	eta := math.Atanh(math.Sin(dlon) / math.Sqrt(1.0+t*t))
+
+	x, y := eta, xi
+	for j, alpha := range p.alpha {
+		k := 2.0 * float64(j+1)
+		x += alpha * math.Cos(k*xi) * math.Sinh(k*eta)
+		y += alpha * math.Sin(k*xi) * math.Cosh(k*eta)
+	}
+	return p.a * x, p.a * y
+}
+
+func (p *TransverseMercator) Forward(lon, lat float64) (float64, float64) {
+	x, y := p.forward(normalizeLongitude(radians(lon-p.Lon0)), radians(lat))
+	return p.X0 + p.K0*x, p.Y0 + p.K0*(y-p.m0)
+}
+
+func (p *TransverseMercator) Inverse(x, y float64) (float64, float64) {
+	xi := ((y-p.Y0)/p.K0 + p.m0) / p.a
+	eta := (x - p.X0) / p.K0 / p.a
+
+	xi2, eta2 := xi, eta
+	for j, beta := range p.beta {
+		k := 2.0 * float64(j+1)
+		xi2 -= beta * math.Sin(k*xi) * math.Cosh(k*eta)
+		eta2 -= beta * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	chi := math.Asin(math.Sin(xi2) / math.Cosh(eta2))
+	lat := chi
+	for j, delta := range p.delta {
+		lat += delta * math.Sin(2.0*float64(j+1)*chi)
+	}
+	lon := p.Lon0 + degrees(math.Atan2(math.Sinh(eta2), math.Cos(xi2)))
+	return lon, degrees(lat)
+}